@@ -0,0 +1,104 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentCodeAction(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.CodeActionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.codeActions(ctx, params.TextDocument.URI, params.Range)
+}
+
+func (h *handler) codeActions(ctx context.Context, uri lsp.DocumentURI, rng lsp.Range) ([]lsp.CodeAction, error) {
+	path := documentURIToURI(uri)
+	rawText, ok := h.project.GetFile(path)
+	if !ok {
+		return nil, nil
+	}
+
+	lints := h.lint(path)
+	result := make([]lsp.CodeAction, 0)
+	for _, d := range lints {
+		diagnostic := h.convertSourceDiagnosticToDiagnostic(d, rawText)
+		if diagnostic.Range.End.Line < rng.Start.Line || diagnostic.Range.Start.Line > rng.End.Line {
+			continue
+		}
+
+		for _, f := range d.Fixes {
+			result = append(result, lsp.CodeAction{
+				Title:       f.Title,
+				Kind:        lsp.CAKQuickFix,
+				Diagnostics: []lsp.Diagnostic{diagnostic},
+				Edit: &lsp.WorkspaceEdit{
+					Changes: map[string][]lsp.TextEdit{
+						string(uri): toLspTextEdits(rawText, f.Edits),
+					},
+				},
+			})
+		}
+	}
+
+	diagnostics := append(h.project.GetErrorDiagnostics(path)[path], lints...)
+	actions, err := h.project.CodeActions(path, toSourceRange(rawText, rng), diagnostics)
+	if err != nil {
+		h.logger.Printf("failed to get code actions: %v", err)
+		return result, nil
+	}
+	for _, a := range actions {
+		diagnostics := make([]lsp.Diagnostic, 0, len(a.Diagnostics))
+		for _, d := range a.Diagnostics {
+			diagnostics = append(diagnostics, h.convertSourceDiagnosticToDiagnostic(d, rawText))
+		}
+		// the action without diagnostics like `=` to `:=` is the refactoring.
+		kind := lsp.CAKQuickFix
+		if len(diagnostics) == 0 {
+			kind = lsp.CAKRefactorRewrite
+		}
+		result = append(result, lsp.CodeAction{
+			Title:       a.Title,
+			Kind:        kind,
+			Diagnostics: diagnostics,
+			Edit: &lsp.WorkspaceEdit{
+				Changes: map[string][]lsp.TextEdit{
+					string(uri): toLspTextEdits(rawText, a.Edits),
+				},
+			},
+		})
+	}
+	return result, nil
+}
+
+// toLspTextEdits converts the edits of rawText.
+func toLspTextEdits(rawText string, edits []source.TextEdit) []lsp.TextEdit {
+	result := make([]lsp.TextEdit, len(edits))
+	for i, e := range edits {
+		result[i] = toLspTextEdit(rawText, e)
+	}
+	return result
+}
+
+func toLspTextEdit(rawText string, textEdit source.TextEdit) lsp.TextEdit {
+	start := toLspPosition(rawText, source.Position{Row: textEdit.Row, Col: textEdit.Col})
+	end := start
+	if textEdit.End != nil {
+		end = toLspPosition(rawText, *textEdit.End)
+	}
+
+	return lsp.TextEdit{
+		Range:   lsp.Range{Start: start, End: end},
+		NewText: textEdit.Text,
+	}
+}
@@ -0,0 +1,85 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentDocumentColor(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.DocumentColorParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.documentColors(ctx, params.TextDocument.URI)
+}
+
+func (h *handler) documentColors(ctx context.Context, uri lsp.DocumentURI) ([]lsp.ColorInformation, error) {
+	if !h.options.DocumentColor {
+		return []lsp.ColorInformation{}, nil
+	}
+
+	colors, err := h.project.DocumentColors(documentURIToURI(uri))
+	if err != nil {
+		h.logger.Printf("failed to get document colors: %v", err)
+		return nil, nil
+	}
+
+	rawText, _ := h.project.GetFile(documentURIToURI(uri))
+	result := make([]lsp.ColorInformation, len(colors))
+	for i, c := range colors {
+		start := toLspPosition(rawText, source.Position{Row: c.Location.Row, Col: c.Location.Col})
+		result[i] = lsp.ColorInformation{
+			Range: lsp.Range{
+				Start: start,
+				End: lsp.Position{
+					Line:      start.Line,
+					Character: start.Character + utf16Count(string(c.Location.Text)),
+				},
+			},
+			Color: lsp.Color{
+				Red:   c.Color.Red,
+				Green: c.Color.Green,
+				Blue:  c.Color.Blue,
+				Alpha: c.Color.Alpha,
+			},
+		}
+	}
+	return result, nil
+}
+
+func (h *handler) handleTextDocumentColorPresentation(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.ColorPresentationParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	color := source.Color{
+		Red:   params.Color.Red,
+		Green: params.Color.Green,
+		Blue:  params.Color.Blue,
+		Alpha: params.Color.Alpha,
+	}
+	label := color.Hex()
+	return []lsp.ColorPresentation{
+		{
+			Label: label,
+			TextEdit: &lsp.TextEdit{
+				Range:   params.Range,
+				NewText: label,
+			},
+		},
+	}, nil
+}
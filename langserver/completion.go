@@ -28,22 +28,28 @@ func (h *handler) handleTextDocumentCompletion(ctx context.Context, conn *jsonrp
 		return nil, err
 	}
 
-	return completionItemToLspCompletionList(items, h.clientSupportSnippets()), nil
+	rawText, _ := h.project.GetFile(documentURIToURI(params.TextDocument.URI))
+	return completionItemToLspCompletionList(items, h.clientSupportSnippets(), rawText), nil
 }
 
 func (h *handler) clientSupportSnippets() bool {
 	return h.initializeParams.Capabilities.TextDocument.Completion.CompletionItem.SnippetSupport
 }
 
-func completionItemToLspCompletionList(items []source.CompletionItem, isSnippetSupport bool) lsp.CompletionList {
+// completionItemToLspCompletionList converts the items whose text edits are in rawText.
+func completionItemToLspCompletionList(items []source.CompletionItem, isSnippetSupport bool, rawText string) lsp.CompletionList {
 	insertTextFormat := lsp.ITFPlainText
 	if isSnippetSupport {
 		insertTextFormat = lsp.ITFSnippet
 	}
 
-	completoinItems := make([]lsp.CompletionItem, len(items))
-	for i, c := range items {
-		completoinItems[i] = createCompletionItem(c, insertTextFormat)
+	completoinItems := make([]lsp.CompletionItem, 0, len(items))
+	for _, c := range items {
+		// the label of the snippet is only for the display, so the client which can't expand the tab stops has nothing to insert.
+		if c.Kind == source.SnippetItem && !isSnippetSupport {
+			continue
+		}
+		completoinItems = append(completoinItems, createCompletionItem(c, insertTextFormat, rawText))
 	}
 
 	return lsp.CompletionList{
@@ -52,29 +58,43 @@ func completionItemToLspCompletionList(items []source.CompletionItem, isSnippetS
 	}
 }
 
-func createCompletionItem(completionItem source.CompletionItem, insertTextFormat lsp.InsertTextFormat) lsp.CompletionItem {
+func createCompletionItem(completionItem source.CompletionItem, insertTextFormat lsp.InsertTextFormat, rawText string) lsp.CompletionItem {
+	sortText, tags := completionItemRank(completionItem)
 	if insertTextFormat == lsp.ITFPlainText {
 		return lsp.CompletionItem{
 			Label:            completionItem.Label,
 			Kind:             kindToLspKind(completionItem.Kind),
 			Detail:           completionItem.Detail,
+			SortText:         sortText,
 			InsertTextFormat: insertTextFormat,
+			Tags:             tags,
 		}
 	}
 
 	additionalTextEdit := make([]lsp.TextEdit, len(completionItem.AdditionalTextEdits))
 	for i, a := range completionItem.AdditionalTextEdits {
-		additionalTextEdit[i] = createAdditionalTextEdit(a)
+		additionalTextEdit[i] = createAdditionalTextEdit(a, rawText)
 	}
 
 	return lsp.CompletionItem{
 		Label:               completionItem.Label,
 		Kind:                kindToLspKind(completionItem.Kind),
 		Detail:              completionItem.Detail,
+		SortText:            sortText,
 		InsertTextFormat:    lsp.ITFSnippet,
-		TextEdit:            createTextEdit(completionItem.TextEdit, completionItem.Kind),
+		TextEdit:            createTextEdit(completionItem.TextEdit, completionItem.Kind, rawText),
 		AdditionalTextEdits: additionalTextEdit,
+		Tags:                tags,
+	}
+}
+
+// completionItemRank returns the sortText and the tags of the item.
+// The clients sort items by the label when sortText is empty, so deprecated items get the sortText which follows any label.
+func completionItemRank(completionItem source.CompletionItem) (string, []lsp.CompletionItemTag) {
+	if !completionItem.Deprecated {
+		return "", nil
 	}
+	return "~" + completionItem.Label, []lsp.CompletionItemTag{lsp.CITDeprecated}
 }
 
 func kindToLspKind(kind source.CompletionKind) lsp.CompletionItemKind {
@@ -85,40 +105,39 @@ func kindToLspKind(kind source.CompletionKind) lsp.CompletionItemKind {
 		return lsp.CIKModule
 	case source.FunctionItem, source.BuiltinFunctionItem:
 		return lsp.CIKFunction
+	case source.KeywordItem:
+		return lsp.CIKKeyword
+	case source.SnippetItem:
+		return lsp.CIKSnippet
+	case source.AnnotationItem:
+		return lsp.CIKProperty
 	default:
 		return lsp.CIKText
 	}
 }
 
-func createAdditionalTextEdit(textEdit source.TextEdit) lsp.TextEdit {
+func createAdditionalTextEdit(textEdit source.TextEdit, rawText string) lsp.TextEdit {
+	start := toLspPosition(rawText, source.Position{Row: textEdit.Row, Col: textEdit.Col})
 	return lsp.TextEdit{
 		Range: lsp.Range{
-			Start: lsp.Position{
-				Line:      textEdit.Row - 1,
-				Character: textEdit.Col - 1,
-			},
-			End: lsp.Position{
-				Line:      textEdit.Row - 1,
-				Character: textEdit.Col - 1,
-			},
+			Start: start,
+			End:   start,
 		},
 		NewText: textEdit.Text,
 	}
 }
 
-func createTextEdit(textEdit *source.TextEdit, kind source.CompletionKind) *lsp.TextEdit {
+func createTextEdit(textEdit *source.TextEdit, kind source.CompletionKind, rawText string) *lsp.TextEdit {
 	if textEdit == nil {
 		return nil
 	}
+	start := toLspPosition(rawText, source.Position{Row: textEdit.Row, Col: textEdit.Col})
 	return &lsp.TextEdit{
 		Range: lsp.Range{
-			Start: lsp.Position{
-				Line:      textEdit.Row - 1,
-				Character: textEdit.Col - 1,
-			},
+			Start: start,
 			End: lsp.Position{
-				Line:      textEdit.Row - 1,
-				Character: textEdit.Col - 1 + len(textEdit.Text),
+				Line:      start.Line,
+				Character: start.Character + len(textEdit.Text),
 			},
 		},
 		NewText: createSnippetText(textEdit.Text, kind),
@@ -12,6 +12,7 @@ func TestCompletionItemToLspCompletionList(t *testing.T) {
 	tests := map[string]struct {
 		items            []source.CompletionItem
 		isSnippetSupport bool
+		rawText          string
 
 		expectCompletionList lsp.CompletionList
 	}{
@@ -176,6 +177,30 @@ func TestCompletionItemToLspCompletionList(t *testing.T) {
 				},
 			},
 		},
+		"deprecated item": {
+			items: []source.CompletionItem{
+				{
+					Label:      "re_match",
+					Kind:       source.BuiltinFunctionItem,
+					Detail:     "detail",
+					Deprecated: true,
+				},
+			},
+			isSnippetSupport: false,
+			expectCompletionList: lsp.CompletionList{
+				IsIncomplete: false,
+				Items: []lsp.CompletionItem{
+					{
+						Label:            "re_match",
+						Kind:             lsp.CIKFunction,
+						Detail:           "detail",
+						SortText:         "~re_match",
+						InsertTextFormat: lsp.ITFPlainText,
+						Tags:             []lsp.CompletionItemTag{lsp.CITDeprecated},
+					},
+				},
+			},
+		},
 		"client doesn't support snippet": {
 			items: []source.CompletionItem{
 				{
@@ -202,11 +227,113 @@ func TestCompletionItemToLspCompletionList(t *testing.T) {
 				},
 			},
 		},
+		"client doesn't support snippet for the rule snippet": {
+			items: []source.CompletionItem{
+				{
+					Label:  "deny",
+					Kind:   source.SnippetItem,
+					Detail: "deny[msg] { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  1,
+						Col:  1,
+						Text: "deny[msg] {\n\t${1:true}\n\tmsg := ${2:\"message\"}\n}",
+					},
+				},
+				{
+					Label: "deny",
+					Kind:  source.FunctionItem,
+				},
+			},
+			isSnippetSupport: false,
+			expectCompletionList: lsp.CompletionList{
+				IsIncomplete: false,
+				Items: []lsp.CompletionItem{
+					{
+						Label:            "deny",
+						Kind:             lsp.CIKFunction,
+						InsertTextFormat: lsp.ITFPlainText,
+					},
+				},
+			},
+		},
+		"client doesn't support snippet for the else snippets": {
+			items: []source.CompletionItem{
+				{
+					Label: "else",
+					Kind:  source.KeywordItem,
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  3,
+						Text: "else",
+					},
+				},
+				{
+					Label:  "else { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  3,
+						Text: "else {\n\t${1:true}\n}",
+					},
+				},
+				{
+					Label:  "else = value { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else = <value> { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  3,
+						Text: "else = ${1:value} {\n\t${2:true}\n}",
+					},
+				},
+			},
+			isSnippetSupport: false,
+			expectCompletionList: lsp.CompletionList{
+				IsIncomplete: false,
+				Items: []lsp.CompletionItem{
+					{
+						Label:            "else",
+						Kind:             lsp.CIKKeyword,
+						InsertTextFormat: lsp.ITFPlainText,
+					},
+				},
+			},
+		},
+		"client doesn't support snippet for the negated membership": {
+			items: []source.CompletionItem{
+				{
+					Label:  "x in coll",
+					Kind:   source.SnippetItem,
+					Detail: "not x in coll",
+					TextEdit: &source.TextEdit{
+						Row:  4,
+						Col:  6,
+						Text: "${1:x} in ${2:coll}",
+					},
+				},
+				{
+					Label:  "name in coll",
+					Kind:   source.SnippetItem,
+					Detail: "not name in coll",
+					TextEdit: &source.TextEdit{
+						Row:  4,
+						Col:  6,
+						Text: "name in ${1:coll}",
+					},
+				},
+			},
+			isSnippetSupport: false,
+			expectCompletionList: lsp.CompletionList{
+				IsIncomplete: false,
+				Items:        []lsp.CompletionItem{},
+			},
+		},
 	}
 
 	for n, tt := range tests {
 		t.Run(n, func(t *testing.T) {
-			got := completionItemToLspCompletionList(tt.items, tt.isSnippetSupport)
+			got := completionItemToLspCompletionList(tt.items, tt.isSnippetSupport, tt.rawText)
 			if diff := cmp.Diff(tt.expectCompletionList, got); diff != "" {
 				t.Errorf("completionItemToLspCompletionList result diff (-expect, +got)\n%s", diff)
 			}
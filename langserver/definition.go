@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/sourcegraph/jsonrpc2"
 )
@@ -30,9 +32,37 @@ func (h *handler) lookupIdent(ctx context.Context, uri lsp.DocumentURI, position
 		h.logger.Printf("failed to get definition: %v", err)
 		return nil, nil
 	}
+	return h.toLspLocations(lookupResults), nil
+}
+
+func (h *handler) handleTextDocumentTypeDefinition(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.TextDocumentPositionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	loc := h.toOPALocation(params.Position, params.TextDocument.URI)
+	lookupResults, err := h.project.TypeDefinition(loc)
+	if err != nil {
+		h.logger.Printf("failed to get type definition: %v", err)
+		return nil, nil
+	}
+	return h.toLspLocations(lookupResults), nil
+}
 
+func (h *handler) toLspLocations(lookupResults []*ast.Location) []lsp.Location {
 	result := make([]lsp.Location, 0, len(lookupResults))
 	for _, r := range lookupResults {
+		// the built-in function is defined in the document.
+		if strings.HasPrefix(r.File, source.BuiltinDocumentURL) {
+			result = append(result, lsp.Location{URI: lsp.DocumentURI(r.File)})
+			continue
+		}
+
 		rawFile, err := h.project.GetRawText(r.File)
 		if err != nil {
 			continue
@@ -41,8 +71,7 @@ func (h *handler) lookupIdent(ctx context.Context, uri lsp.DocumentURI, position
 		location.URI = uriToDocumentURI(r.File)
 		result = append(result, location)
 	}
-
-	return result, nil
+	return result
 }
 
 func (h *handler) toOPALocation(position lsp.Position, uri lsp.DocumentURI) *ast.Location {
@@ -51,40 +80,27 @@ func (h *handler) toOPALocation(position lsp.Position, uri lsp.DocumentURI) *ast
 	if !ok {
 		return nil
 	}
-
-	startInd := 0
-	for i := 0; i < position.Line; i++ {
-		startInd += strings.Index(rawText[startInd:], "\n") + 1
-	}
-	startInd += position.Character
-
-	return &ast.Location{
-		Row:    position.Line + 1,
-		Col:    position.Character + 1,
-		Offset: startInd,
-		File:   path,
+	pos := toSourcePosition(rawText, position)
+	loc, err := h.project.LocationFromPosition(path, pos.Row, pos.Col)
+	if err != nil {
+		return nil
 	}
+	return loc
 }
 
 func toLspLocation(location *ast.Location, rawText string) lsp.Location {
 	if location == nil {
 		return lsp.Location{Range: lsp.Range{Start: lsp.Position{}, End: lsp.Position{}}}
 	}
-	start := lsp.Position{
-		Line:      location.Row - 1,
-		Character: location.Col - 1,
-	}
+	start := toLspPosition(rawText, source.Position{Row: location.Row, Col: location.Col})
 
-	endOffset := location.Offset + len(location.Text) - 1
+	// the end points to the last character of the text.
+	_, lastSize := utf8.DecodeLastRune(location.Text)
+	endOffset := location.Offset + len(location.Text) - lastSize
 	toEndText := rawText[:endOffset]
 	line := strings.Count(toEndText, "\n")
 	newLineInd := strings.LastIndex(toEndText, "\n")
-	var char int
-	if newLineInd == -1 {
-		char = len(toEndText)
-	} else {
-		char = len(toEndText[newLineInd:]) - 1
-	}
+	char := utf16Count(toEndText[newLineInd+1:])
 
 	return lsp.Location{
 		Range: lsp.Range{
@@ -47,6 +47,22 @@ world`,
 				},
 			},
 		},
+		"location is after emoji": {
+			location: &ast.Location{
+				Row:    1,
+				Col:    4,
+				Offset: len("👍, "),
+				Text:   []byte("こんにちは"),
+				File:   "src.rego",
+			},
+			rawText: `👍, こんにちは`,
+			expect: lsp.Location{
+				Range: lsp.Range{
+					Start: lsp.Position{Line: 0, Character: 4},
+					End:   lsp.Position{Line: 0, Character: 8},
+				},
+			},
+		},
 	}
 
 	for n, tt := range tests {
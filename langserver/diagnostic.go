@@ -4,7 +4,7 @@ import (
 	"context"
 
 	"github.com/kitagry/regols/langserver/internal/lsp"
-	"github.com/open-policy-agent/opa/ast"
+	"github.com/kitagry/regols/langserver/internal/source"
 )
 
 func (h *handler) diagnostic() {
@@ -44,36 +44,103 @@ func (h *handler) diagnostic() {
 func (h *handler) diagnose(ctx context.Context, uri lsp.DocumentURI) (map[lsp.DocumentURI][]lsp.Diagnostic, error) {
 	result := make(map[lsp.DocumentURI][]lsp.Diagnostic)
 
-	pathToErrs := h.project.GetErrors(documentURIToURI(uri))
-	for path, errs := range pathToErrs {
-		uri := uriToDocumentURI(path)
-		result[uri] = convertErrorsToDiagnostics(errs)
+	path := documentURIToURI(uri)
+	// the compile errors without location are shown in the file which is diagnosed.
+	for p, errs := range h.project.GetAllErrorDiagnostics(path) {
+		result[uriToDocumentURI(p)] = h.convertErrorsToDiagnostics(errs)
+	}
+
+	rawText, ok := h.project.GetFile(path)
+	if ok {
+		diagnostics := append(h.project.RuleHeadMismatches(path), h.project.UnresolvedRefs(path)...)
+		diagnostics = append(diagnostics, h.lint(path)...)
+		for _, d := range diagnostics {
+			result[uri] = append(result[uri], h.convertSourceDiagnosticToDiagnostic(d, rawText))
+		}
 	}
 
 	return result, nil
 }
 
-func convertErrorsToDiagnostics(errs ast.Errors) []lsp.Diagnostic {
-	result := make([]lsp.Diagnostic, len(errs))
-	for i, e := range errs {
-		result[i] = convertErrorToDiagnostic(e)
+// lint returns diagnostics from the linters enabled by initializationOptions.
+func (h *handler) lint(path string) []source.Diagnostic {
+	result := make([]source.Diagnostic, 0)
+	if h.options.Lint.UnusedVariables {
+		result = append(result, h.project.UnusedVariables(path)...)
+	}
+	if h.options.Lint.UnusedImports {
+		diagnostics, err := h.project.UnusedImports(path)
+		if err != nil {
+			h.logger.Printf("failed to lint unused imports: %v", err)
+		}
+		result = append(result, diagnostics...)
+	}
+	if h.options.Lint.ReservedWords {
+		result = append(result, h.project.ReservedWordBindings(path)...)
+	}
+	if h.options.Lint.DeprecatedBuiltins {
+		result = append(result, h.project.DeprecatedBuiltins(path)...)
 	}
 	return result
 }
 
-func convertErrorToDiagnostic(err *ast.Error) lsp.Diagnostic {
+func (h *handler) convertSourceDiagnosticToDiagnostic(d source.Diagnostic, rawText string) lsp.Diagnostic {
 	return lsp.Diagnostic{
-		Severity: lsp.Error,
+		Severity:           lsp.DiagnosticSeverity(d.Severity),
+		Range:              toLspLocation(d.Location, rawText).Range,
+		Code:               d.Code,
+		Source:             diagnosticSource(d),
+		Message:            d.Message,
+		RelatedInformation: h.convertRelatedInformation(d.Related),
+	}
+}
+
+func (h *handler) convertRelatedInformation(related []source.RelatedInformation) []lsp.DiagnosticRelatedInformation {
+	var result []lsp.DiagnosticRelatedInformation
+	for _, r := range related {
+		relatedText, ok := h.project.GetFile(r.Location.File)
+		if !ok {
+			continue
+		}
+		result = append(result, lsp.DiagnosticRelatedInformation{
+			Location: toLspLocation(r.Location, relatedText),
+			Message:  r.Message,
+		})
+	}
+	return result
+}
+
+// diagnosticSource returns the source which the client shows with the message like `regols/parse`.
+func diagnosticSource(d source.Diagnostic) string {
+	if d.Source == "" {
+		return "regols"
+	}
+	return "regols/" + d.Source
+}
+
+func (h *handler) convertErrorsToDiagnostics(diagnostics []source.Diagnostic) []lsp.Diagnostic {
+	result := make([]lsp.Diagnostic, len(diagnostics))
+	for i, d := range diagnostics {
+		result[i] = h.convertErrorToDiagnostic(d)
+	}
+	return result
+}
+
+func (h *handler) convertErrorToDiagnostic(d source.Diagnostic) lsp.Diagnostic {
+	rawText, _ := h.project.GetFile(d.Location.File)
+	start := toLspPosition(rawText, source.Position{Row: d.Location.Row, Col: d.Location.Col})
+	return lsp.Diagnostic{
+		Severity: lsp.DiagnosticSeverity(d.Severity),
 		Range: lsp.Range{
-			Start: lsp.Position{
-				Line:      err.Location.Row - 1,
-				Character: err.Location.Col - 1,
-			},
+			Start: start,
 			End: lsp.Position{
-				Line:      err.Location.Row - 1,
-				Character: err.Location.Col + err.Location.Offset - 1,
+				Line:      start.Line,
+				Character: start.Character + d.Location.Offset,
 			},
 		},
-		Message: err.Message,
+		Code:               d.Code,
+		Source:             diagnosticSource(d),
+		Message:            d.Message,
+		RelatedInformation: h.convertRelatedInformation(d.Related),
 	}
 }
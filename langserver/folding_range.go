@@ -0,0 +1,53 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentFoldingRange(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.FoldingRangeParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.foldingRange(ctx, params.TextDocument.URI)
+}
+
+func (h *handler) foldingRange(ctx context.Context, uri lsp.DocumentURI) ([]lsp.FoldingRange, error) {
+	ranges, err := h.project.FoldingRanges(documentURIToURI(uri))
+	if err != nil {
+		h.logger.Printf("failed to get folding ranges: %v", err)
+		return nil, nil
+	}
+
+	rawText, _ := h.project.GetFile(documentURIToURI(uri))
+	result := make([]lsp.FoldingRange, 0, len(ranges))
+	for _, r := range ranges {
+		result = append(result, lsp.FoldingRange{
+			StartLine:      r.Range.Start.Row - 1,
+			StartCharacter: toLspPosition(rawText, r.Range.Start).Character,
+			EndLine:        r.Range.End.Row - 1,
+			EndCharacter:   toLspPosition(rawText, r.Range.End).Character,
+			Kind:           foldingRangeKindToLspKind(r.Kind),
+		})
+	}
+	return result, nil
+}
+
+func foldingRangeKindToLspKind(kind source.FoldingRangeKind) lsp.FoldingRangeKind {
+	switch kind {
+	case source.FoldingImports:
+		return lsp.FRKImports
+	default:
+		return lsp.FRKRegion
+	}
+}
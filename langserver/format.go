@@ -3,11 +3,8 @@ package langserver
 import (
 	"context"
 	"encoding/json"
-	"fmt"
-	"strings"
 
 	"github.com/kitagry/regols/langserver/internal/lsp"
-	"github.com/open-policy-agent/opa/format"
 	"github.com/sourcegraph/jsonrpc2"
 )
 
@@ -21,47 +18,40 @@ func (h *handler) handleTextDocumentFormatting(ctx context.Context, conn *jsonrp
 		return nil, err
 	}
 
-	rawText, ok := h.project.GetFile(documentURIToURI(params.TextDocument.URI))
-	if !ok {
-		return nil, fmt.Errorf("failed to find document %s", params.TextDocument.URI)
+	return h.formatting(ctx, params.TextDocument.URI)
+}
+
+func (h *handler) formatting(ctx context.Context, uri lsp.DocumentURI) ([]lsp.TextEdit, error) {
+	path := documentURIToURI(uri)
+	rawText, _ := h.project.GetFile(path)
+	edits, err := h.project.Format(path)
+	if err != nil {
+		return nil, err
 	}
 
-	formatted, err := format.Source(documentURIToURI(params.TextDocument.URI), []byte(rawText))
-	if !ok {
-		return nil, fmt.Errorf("failed to format: %w", err)
+	return toLspTextEdits(rawText, edits), nil
+}
+
+func (h *handler) handleTextDocumentRangeFormatting(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
 	}
 
-	if len(formatted) == 0 {
-		return nil, nil
+	var params lsp.DocumentRangeFormattingParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
 	}
 
-	return ComputeEdits(params.TextDocument.URI, rawText, string(formatted)), nil
+	return h.rangeFormatting(ctx, params.TextDocument.URI, params.Range)
 }
 
-// ComputeEdits computes diff edits from 2 string inputs
-func ComputeEdits(uri lsp.DocumentURI, before, after string) []lsp.TextEdit {
-	ops := operations(splitLines(before), splitLines(after))
-	edits := make([]lsp.TextEdit, 0, len(ops))
-	for _, op := range ops {
-		switch op.Kind {
-		case Delete:
-			// Delete: unformatted[i1:i2] is deleted.
-			edits = append(edits, lsp.TextEdit{Range: lsp.Range{
-				Start: lsp.Position{Line: op.I1, Character: 0},
-				End:   lsp.Position{Line: op.I2, Character: 0},
-			}})
-		case Insert:
-			// Insert: formatted[j1:j2] is inserted at unformatted[i1:i1].
-			if content := strings.Join(op.Content, ""); content != "" {
-				edits = append(edits, lsp.TextEdit{
-					Range: lsp.Range{
-						Start: lsp.Position{Line: op.I1, Character: 0},
-						End:   lsp.Position{Line: op.I2, Character: 0},
-					},
-					NewText: content,
-				})
-			}
-		}
+func (h *handler) rangeFormatting(ctx context.Context, uri lsp.DocumentURI, rng lsp.Range) ([]lsp.TextEdit, error) {
+	path := documentURIToURI(uri)
+	rawText, _ := h.project.GetFile(path)
+	edits, err := h.project.FormatRange(path, toSourceRange(rawText, rng))
+	if err != nil {
+		return nil, err
 	}
-	return edits
+
+	return toLspTextEdits(rawText, edits), nil
 }
@@ -0,0 +1,55 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentDocumentHighlight(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.TextDocumentPositionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.documentHighlight(ctx, params.TextDocument.URI, params.Position)
+}
+
+func (h *handler) documentHighlight(ctx context.Context, uri lsp.DocumentURI, position lsp.Position) ([]lsp.DocumentHighlight, error) {
+	loc := h.toOPALocation(position, uri)
+	highlights, err := h.project.DocumentHighlight(loc)
+	if err != nil {
+		h.logger.Printf("failed to get document highlights: %v", err)
+		return nil, nil
+	}
+
+	rawFile, err := h.project.GetRawText(loc.File)
+	if err != nil {
+		return nil, nil
+	}
+
+	result := make([]lsp.DocumentHighlight, 0, len(highlights))
+	for _, hl := range highlights {
+		result = append(result, lsp.DocumentHighlight{
+			Range: toLspLocation(hl.Location, rawFile).Range,
+			Kind:  highlightKindToLspKind(hl.Kind),
+		})
+	}
+	return result, nil
+}
+
+func highlightKindToLspKind(kind source.HighlightKind) int {
+	switch kind {
+	case source.HighlightWrite:
+		return lsp.Write
+	default:
+		return lsp.Read
+	}
+}
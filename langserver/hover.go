@@ -23,13 +23,16 @@ func (h *handler) handleTextDocumentHover(ctx context.Context, conn *jsonrpc2.Co
 
 func (h *handler) documentIdent(ctx context.Context, uri lsp.DocumentURI, position lsp.Position) (lsp.Hover, error) {
 	loc := h.toOPALocation(position, uri)
-	documentResults, err := h.project.TermDocument(loc)
+	hoverResult, err := h.project.Hover(loc)
 	if err != nil {
 		return lsp.Hover{}, err
 	}
+	if hoverResult == nil {
+		return lsp.Hover{}, nil
+	}
 
-	result := make([]lsp.MarkedString, len(documentResults))
-	for i, d := range documentResults {
+	result := make([]lsp.MarkedString, len(hoverResult.Documents))
+	for i, d := range hoverResult.Documents {
 		result[i] = lsp.MarkedString{Language: d.Language, Value: d.Content}
 	}
 	return lsp.Hover{Contents: result}, nil
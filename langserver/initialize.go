@@ -3,12 +3,72 @@ package langserver
 import (
 	"context"
 	"encoding/json"
+	"path/filepath"
 
 	"github.com/kitagry/regols/langserver/internal/lsp"
 	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/sourcegraph/jsonrpc2"
 )
 
+// initializationOptions is sent from the client as InitializeParams.InitializationOptions.
+type initializationOptions struct {
+	Lint lintOptions `json:"lint"`
+
+	// DocumentColor enables textDocument/documentColor for `#RRGGBB` strings.
+	DocumentColor bool `json:"documentColor"`
+
+	// DefinitionMode is the part of the rule which textDocument/definition jumps to.
+	// It is one of "name" (default), "rule" and "key".
+	DefinitionMode string `json:"definitionMode"`
+
+	// UnqualifiedRefs is how the unqualified reference which matches no rule in the current package is handled.
+	// It is one of "package" (default), "workspace" and "warn".
+	UnqualifiedRefs string `json:"unqualifiedRefs"`
+
+	// MaxDiagnostics caps the number of the diagnostics from the compiler per file. 0 means no limit.
+	MaxDiagnostics int `json:"maxDiagnostics"`
+
+	// ResolveSymlinks treats the symlink path and the real path of the same file as one file.
+	ResolveSymlinks bool `json:"resolveSymlinks"`
+
+	// HideUnderscoreRules hides the rules like `_helper` from the completion and the workspace symbols.
+	HideUnderscoreRules bool `json:"hideUnderscoreRules"`
+
+	// Schemas maps the document like "input" to the JSON schema file, which is relative to the root path.
+	Schemas map[string]string `json:"schemas"`
+}
+
+func (o initializationOptions) definitionMode() source.DefinitionMode {
+	switch o.DefinitionMode {
+	case "rule":
+		return source.DefinitionWholeRule
+	case "key":
+		return source.DefinitionHeadKey
+	default:
+		return source.DefinitionHeadName
+	}
+}
+
+func (o initializationOptions) unqualifiedRefMode() source.UnqualifiedRefMode {
+	switch o.UnqualifiedRefs {
+	case "workspace":
+		return source.UnqualifiedRefWorkspace
+	case "warn":
+		return source.UnqualifiedRefWarn
+	default:
+		return source.UnqualifiedRefPackage
+	}
+}
+
+// lintOptions enables diagnostics which are not reported by the OPA compiler.
+type lintOptions struct {
+	UnusedVariables    bool `json:"unusedVariables"`
+	UnusedImports      bool `json:"unusedImports"`
+	ReservedWords      bool `json:"reservedWords"`
+	DeprecatedBuiltins bool `json:"deprecatedBuiltins"`
+}
+
 func (h *handler) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
 	if req.Params == nil {
 		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
@@ -22,29 +82,87 @@ func (h *handler) handleInitialize(ctx context.Context, conn *jsonrpc2.Conn, req
 	}
 	h.initializeParams = params
 
-	p, err := source.NewProject(params.RootPath)
+	options, err := parseInitializationOptions(params.InitializationOptions)
+	if err != nil {
+		return nil, err
+	}
+	h.options = options
+
+	p, err := source.NewProject(params.RootPath, source.WithMaxDiagnostics(options.MaxDiagnostics))
 	if err != nil {
 		return nil, err
 	}
+	p.SetDefinitionMode(options.definitionMode())
+	p.SetUnqualifiedRefMode(options.unqualifiedRefMode())
+	p.SetResolveSymlinks(options.ResolveSymlinks)
+	p.SetHideUnderscoreRules(options.HideUnderscoreRules)
+	for root, path := range options.Schemas {
+		ref, err := ast.ParseRef(root)
+		if err != nil {
+			h.logger.Printf("failed to parse the document of the schema: %v", err)
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(params.RootPath, path)
+		}
+		if err := p.SetSchemaFile(ref, path); err != nil {
+			h.logger.Println(err)
+		}
+	}
 	h.project = p
 
 	return lsp.InitializeResult{
 		Capabilities: lsp.ServerCapabilities{
 			TextDocumentSync: &lsp.TextDocumentSyncOptionsOrKind{
-				Kind: tdskToPTr(lsp.TDSKFull),
+				Kind: tdskToPTr(lsp.TDSKIncremental),
 			},
-			DocumentFormattingProvider: true,
-			DefinitionProvider:         true,
-			HoverProvider:              true,
-			ReferencesProvider:         true,
+			DocumentFormattingProvider:      true,
+			DocumentRangeFormattingProvider: true,
+			DefinitionProvider:              true,
+			TypeDefinitionProvider:          true,
+			HoverProvider:                   true,
+			ReferencesProvider:              true,
+			DocumentHighlightProvider:       true,
+			CodeActionProvider:              true,
+			RenameProvider:                  true,
+			WorkspaceSymbolProvider:         true,
+			FoldingRangeProvider:            true,
+			InlayHintProvider:               true,
+			ColorProvider:                   options.DocumentColor,
 			CompletionProvider: &lsp.CompletionOptions{
 				TriggerCharacters: []string{"*", "."},
 				ResolveProvider:   true,
 			},
+			SignatureHelpProvider: &lsp.SignatureHelpOptions{
+				TriggerCharacters: []string{"(", ","},
+			},
+			SemanticTokensProvider: &lsp.SemanticTokensOptions{
+				Legend: lsp.SemanticTokensLegend{
+					TokenTypes:     source.SemanticTokenTypes,
+					TokenModifiers: source.SemanticTokenModifiers,
+				},
+				Full: true,
+			},
 		},
 	}, nil
 }
 
+func parseInitializationOptions(v interface{}) (initializationOptions, error) {
+	var options initializationOptions
+	if v == nil {
+		return options, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return options, err
+	}
+	if err := json.Unmarshal(b, &options); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
 func tdskToPTr(s lsp.TextDocumentSyncKind) *lsp.TextDocumentSyncKind {
 	return &s
 }
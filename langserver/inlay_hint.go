@@ -0,0 +1,43 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentInlayHint(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.InlayHintParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.inlayHint(ctx, params.TextDocument.URI, params.Range)
+}
+
+func (h *handler) inlayHint(ctx context.Context, uri lsp.DocumentURI, rng lsp.Range) ([]lsp.InlayHint, error) {
+	path := documentURIToURI(uri)
+	rawText, _ := h.project.GetFile(path)
+	hints, err := h.project.InlayHints(path, toSourceRange(rawText, rng))
+	if err != nil {
+		h.logger.Printf("failed to get inlay hints: %v", err)
+		return nil, nil
+	}
+
+	result := make([]lsp.InlayHint, 0, len(hints))
+	for _, hint := range hints {
+		result = append(result, lsp.InlayHint{
+			Position:     toLspPosition(rawText, hint.Position),
+			Label:        hint.Label,
+			Kind:         lsp.IHKParameter,
+			PaddingRight: true,
+		})
+	}
+	return result, nil
+}
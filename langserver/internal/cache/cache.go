@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -15,11 +16,22 @@ type Policy struct {
 	RawText string
 	Errs    ast.Errors
 	Module  *ast.Module
+	Version int
+
+	// CollectionRules has the names of the rules which produce collections like partial set rules.
+	CollectionRules map[string]struct{}
 }
 
 type GlobalCache struct {
 	mu            sync.RWMutex
 	pathToPlicies map[string]*Policy
+
+	// realPaths maps the path whose symlinks are resolved to the path of pathToPlicies.
+	// It is used only when resolveSymlinks is true.
+	resolveSymlinks bool
+	realPaths       map[string]string
+
+	compileCache compileCache
 }
 
 func NewGlobalCache(rootPath string) (*GlobalCache, error) {
@@ -73,7 +85,70 @@ func loadRegoFiles(rootPath string) ([]string, error) {
 func (g *GlobalCache) Get(path string) *Policy {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
-	return g.pathToPlicies[path]
+	return g.pathToPlicies[g.resolvePath(path)]
+}
+
+// SetResolveSymlinks makes the symlink path and the real path of the same file share the policy.
+// The files which are loaded twice through the symlinks are merged into the real path.
+func (g *GlobalCache) SetResolveSymlinks(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.resolveSymlinks = enabled
+	g.realPaths = make(map[string]string)
+	if !enabled {
+		return
+	}
+
+	paths := make([]string, 0, len(g.pathToPlicies))
+	for path := range g.pathToPlicies {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			continue
+		}
+		key, ok := g.realPaths[real]
+		if !ok {
+			g.realPaths[real] = path
+			continue
+		}
+
+		if path == real {
+			key, path = path, key
+		}
+		g.delete(path)
+		g.realPaths[real] = key
+	}
+}
+
+// ResolvePath returns the path of the cached file which is the same file as the path.
+// It returns the path itself when the file is not cached or the symlinks are not resolved.
+func (g *GlobalCache) ResolvePath(path string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.resolvePath(path)
+}
+
+func (g *GlobalCache) resolvePath(path string) string {
+	if !g.resolveSymlinks {
+		return path
+	}
+	if _, ok := g.pathToPlicies[path]; ok {
+		return path
+	}
+
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	if key, ok := g.realPaths[real]; ok {
+		return key
+	}
+	return path
 }
 
 func (g *GlobalCache) putWithPath(path string) error {
@@ -96,12 +171,35 @@ func (g *GlobalCache) Put(path string, rawText string) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	return g.put(g.resolvePath(path), rawText)
+}
+
+// PutWithVersion puts the text only when the version is not older than the cached one.
+// It returns false when the text is ignored.
+func (g *GlobalCache) PutWithVersion(path string, rawText string, version int) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	path = g.resolvePath(path)
+	if policy, ok := g.pathToPlicies[path]; ok && policy.Version > version {
+		return false, nil
+	}
+
+	if err := g.put(path, rawText); err != nil {
+		return false, err
+	}
+	g.pathToPlicies[path].Version = version
+	return true, nil
+}
+
+func (g *GlobalCache) put(path string, rawText string) error {
 	policy, ok := g.pathToPlicies[path]
 	if !ok {
 		policy = &Policy{}
+		g.addRealPath(path)
 	}
 	policy.RawText = rawText
-	module, err := ast.ParseModule(path, rawText)
+	module, err := ast.ParseModuleWithOpts(path, rawText, ast.ParserOptions{ProcessAnnotation: true})
 	if errs, ok := err.(ast.Errors); ok {
 		policy.Errs = errs
 		g.pathToPlicies[path] = policy
@@ -113,16 +211,75 @@ func (g *GlobalCache) Put(path string, rawText string) error {
 	} else if err != nil {
 		return err
 	}
+	if policy.Module != nil {
+		g.compileCache.invalidate(policy.Module.Package.Path)
+	}
+	g.compileCache.invalidate(module.Package.Path)
 	policy.Module = module
+	policy.CollectionRules = collectionRules(module)
 	policy.Errs = nil
 	g.pathToPlicies[path] = policy
 	return nil
 }
 
+// collectionRules returns the names of the rules which produce sets, arrays or objects.
+//
+//	deny[msg] { ... }
+//	names := {"a", "b"}
+//	ids := [id | id := input.ids[_]]
+func collectionRules(module *ast.Module) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, r := range module.Rules {
+		if len(r.Head.Args) != 0 {
+			continue
+		}
+		if r.Head.Key != nil || (r.Head.Value != nil && isCollection(r.Head.Value.Value)) {
+			result[r.Head.Name.String()] = struct{}{}
+		}
+	}
+	return result
+}
+
+func isCollection(v ast.Value) bool {
+	switch v.(type) {
+	case ast.Set, *ast.Array, ast.Object, *ast.ArrayComprehension, *ast.SetComprehension, *ast.ObjectComprehension:
+		return true
+	}
+	return false
+}
+
+// addRealPath registers the real path of the new file.
+func (g *GlobalCache) addRealPath(path string) {
+	if !g.resolveSymlinks {
+		return
+	}
+	real, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return
+	}
+	if _, ok := g.realPaths[real]; !ok {
+		g.realPaths[real] = path
+	}
+}
+
 func (g *GlobalCache) Delete(path string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	g.delete(g.resolvePath(path))
+}
+
+func (g *GlobalCache) delete(path string) {
+	if p, ok := g.pathToPlicies[path]; ok && p.Module != nil {
+		g.compileCache.invalidate(p.Module.Package.Path)
+	}
+	delete(g.compileCache.errs, path)
+	delete(g.compileCache.ruleTypes, path)
 	delete(g.pathToPlicies, path)
+	for real, key := range g.realPaths {
+		if key == path {
+			delete(g.realPaths, real)
+		}
+	}
 }
 
 func (g *GlobalCache) FindPolicies(packageName ast.Ref) []*ast.Module {
@@ -138,37 +295,6 @@ func (g *GlobalCache) FindPolicies(packageName ast.Ref) []*ast.Module {
 	return result
 }
 
-func (g *GlobalCache) GetErrors(path string) map[string]ast.Errors {
-	// parse error
-	if p := g.Get(path); p != nil && len(p.Errs) != 0 {
-		return map[string]ast.Errors{path: p.Errs}
-	}
-
-	g.mu.RLock()
-	defer g.mu.RUnlock()
-
-	// compile error
-	modules := make(map[string]*ast.Module, len(g.pathToPlicies))
-	errs := make(map[string]ast.Errors, len(g.pathToPlicies))
-	for path, p := range g.pathToPlicies {
-		if p.Module != nil {
-			modules[path] = p.Module
-		}
-		errs[path] = make(ast.Errors, 0)
-	}
-
-	compiler := ast.NewCompiler()
-	compiler.Compile(modules)
-	if !compiler.Failed() {
-		return errs
-	}
-
-	for _, e := range compiler.Errors {
-		errs[e.Location.File] = append(errs[e.Location.File], e)
-	}
-	return errs
-}
-
 func (g *GlobalCache) GetPackages() []ast.Ref {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
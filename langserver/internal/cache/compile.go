@@ -0,0 +1,235 @@
+package cache
+
+import (
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/types"
+)
+
+// compileCache keeps the compile errors and the rule types of each file.
+// Only the modules related to the changed packages are compiled again.
+type compileCache struct {
+	compiled bool
+	// complete is false when the last compile stopped at a stage, because the errors of the later stages are unknown.
+	complete bool
+	errs     map[string]ast.Errors
+
+	// ruleTypes has the types of the rules checked by the compiler, keyed by the path of the file and the rule ref.
+	ruleTypes map[string]map[string]types.Type
+
+	// dirtyPackages has the packages which are changed after the last compile.
+	dirtyPackages map[string]ast.Ref
+}
+
+func (c *compileCache) invalidate(pkg ast.Ref) {
+	if c.dirtyPackages == nil {
+		c.dirtyPackages = make(map[string]ast.Ref)
+	}
+	c.dirtyPackages[pkg.String()] = pkg
+}
+
+// GetErrors returns the errors of all files when the file of the path is changed.
+// The errors of each file are its own parse errors followed by the compile errors attributed to it,
+// so the file which can be parsed still has the compile errors while another file has the parse errors.
+func (g *GlobalCache) GetErrors(path string) map[string]ast.Errors {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.compile()
+
+	errs := make(map[string]ast.Errors, len(g.pathToPlicies))
+	for path, p := range g.pathToPlicies {
+		errs[path] = g.fileErrors(path, p)
+	}
+	return errs
+}
+
+// GetAllErrors returns the errors of all files like GetErrors.
+// The compile errors which have no location are returned with the empty path.
+func (g *GlobalCache) GetAllErrors() map[string]ast.Errors {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.compile()
+
+	errs := make(map[string]ast.Errors, len(g.pathToPlicies))
+	for path, p := range g.pathToPlicies {
+		errs[path] = g.fileErrors(path, p)
+	}
+	if noLocation := g.compileCache.errs[""]; len(noLocation) > 0 {
+		errs[""] = noLocation
+	}
+	return errs
+}
+
+// fileErrors merges the parse errors and the compile errors of the file.
+// The file which has the parse errors is compiled with the module parsed last time.
+func (g *GlobalCache) fileErrors(path string, p *Policy) ast.Errors {
+	errs := append(make(ast.Errors, 0, len(p.Errs)), p.Errs...)
+	return append(errs, g.compileCache.errs[path]...)
+}
+
+// compile updates the compile errors when the modules are changed.
+func (g *GlobalCache) compile() {
+	if !g.compileCache.compiled || (!g.compileCache.complete && len(g.compileCache.dirtyPackages) > 0) {
+		g.compileAll()
+	} else if len(g.compileCache.dirtyPackages) > 0 {
+		g.compileAffected()
+	}
+}
+
+func (g *GlobalCache) compileAll() {
+	modules := make(map[string]*ast.Module, len(g.pathToPlicies))
+	for path, p := range g.pathToPlicies {
+		if p.Module != nil {
+			modules[path] = p.Module
+		}
+	}
+
+	g.compileCache.errs, g.compileCache.ruleTypes, g.compileCache.complete = compileModules(modules)
+	g.compileCache.compiled = true
+	g.compileCache.dirtyPackages = nil
+}
+
+// compileAffected compiles the modules of the changed packages and the packages which refer to them.
+// The errors of the other modules are kept.
+func (g *GlobalCache) compileAffected() {
+	refs := make(map[string][]ast.Ref, len(g.pathToPlicies))
+	for path, p := range g.pathToPlicies {
+		if p.Module != nil {
+			refs[path] = dataRefs(p.Module)
+		}
+	}
+
+	// the changes spread to the packages which refer to the changed packages.
+	affected := make(map[string]struct{})
+	for pkgs := g.compileCache.dirtyPackages; len(pkgs) > 0; {
+		next := make(map[string]ast.Ref)
+		for path, p := range g.pathToPlicies {
+			if p.Module == nil {
+				continue
+			}
+			if _, ok := affected[path]; ok {
+				continue
+			}
+			if _, ok := pkgs[p.Module.Package.Path.String()]; ok || refersAny(refs[path], pkgs) {
+				affected[path] = struct{}{}
+				next[p.Module.Package.Path.String()] = p.Module.Package.Path
+			}
+		}
+		pkgs = next
+	}
+
+	// the affected modules need the modules which they refer to for the compile.
+	modules := make(map[string]*ast.Module)
+	for path := range affected {
+		modules[path] = g.pathToPlicies[path].Module
+	}
+	for added := true; added; {
+		added = false
+		for path, p := range g.pathToPlicies {
+			if _, ok := modules[path]; ok || p.Module == nil {
+				continue
+			}
+			pkg := map[string]ast.Ref{p.Module.Package.Path.String(): p.Module.Package.Path}
+			for requirer := range modules {
+				if refersAny(refs[requirer], pkg) {
+					modules[path] = p.Module
+					added = true
+					break
+				}
+			}
+		}
+	}
+
+	errs, ruleTypes, complete := compileModules(modules)
+	for path := range affected {
+		g.compileCache.errs[path] = errs[path]
+		g.compileCache.ruleTypes[path] = ruleTypes[path]
+	}
+	g.compileCache.errs[""] = errs[""]
+	g.compileCache.complete = complete
+	g.compileCache.dirtyPackages = nil
+}
+
+// compileModules compiles the modules and returns their errors and rule types.
+// The last value is true when the compiler passed all stages without errors.
+func compileModules(modules map[string]*ast.Module) (map[string]ast.Errors, map[string]map[string]types.Type, bool) {
+	errs := make(map[string]ast.Errors, len(modules))
+	compiler := ast.NewCompiler()
+	compiler.Compile(modules)
+
+	for _, e := range compiler.Errors {
+		var path string
+		if e.Location != nil {
+			path = e.Location.File
+		}
+		errs[path] = append(errs[path], e)
+	}
+	return errs, ruleTypes(compiler, modules), len(compiler.Errors) == 0 && compiler.TypeEnv != nil
+}
+
+// ruleTypes returns the types of the rules in the modules.
+// The types are not available when the compiler fails before the type check.
+func ruleTypes(compiler *ast.Compiler, modules map[string]*ast.Module) map[string]map[string]types.Type {
+	result := make(map[string]map[string]types.Type, len(modules))
+	if compiler.TypeEnv == nil {
+		return result
+	}
+
+	for path, m := range modules {
+		result[path] = make(map[string]types.Type)
+		for _, r := range m.Rules {
+			ref := m.Package.Path.Append(ast.StringTerm(r.Head.Name.String()))
+			if tpe := compiler.TypeEnv.Get(ref); tpe != nil {
+				result[path][ref.String()] = tpe
+			}
+		}
+	}
+	return result
+}
+
+// GetRuleType returns the type of the rule which is checked by the compiler.
+// It returns nil when the type is unknown.
+func (g *GlobalCache) GetRuleType(ref ast.Ref) types.Type {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.compile()
+
+	for _, ruleTypes := range g.compileCache.ruleTypes {
+		if tpe, ok := ruleTypes[ref.String()]; ok {
+			return tpe
+		}
+	}
+	return nil
+}
+
+// dataRefs returns the refs to `data` in the imports and the rules of the module.
+func dataRefs(module *ast.Module) []ast.Ref {
+	result := make([]ast.Ref, 0)
+	f := func(r ast.Ref) bool {
+		if r.HasPrefix(ast.DefaultRootRef) {
+			result = append(result, r.ConstantPrefix())
+		}
+		return false
+	}
+	for _, imp := range module.Imports {
+		ast.WalkRefs(imp, f)
+	}
+	for _, r := range module.Rules {
+		ast.WalkRefs(r, f)
+	}
+	return result
+}
+
+// refersAny returns true when one of the refs points to one of the packages or their parent documents.
+func refersAny(refs []ast.Ref, pkgs map[string]ast.Ref) bool {
+	for _, r := range refs {
+		for _, pkg := range pkgs {
+			if r.HasPrefix(pkg) || pkg.HasPrefix(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
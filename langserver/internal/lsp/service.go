@@ -313,6 +313,7 @@ type ServerCapabilities struct {
 	WorkspaceSymbolProvider          bool                             `json:"workspaceSymbolProvider,omitempty"`
 	ImplementationProvider           bool                             `json:"implementationProvider,omitempty"`
 	CodeActionProvider               bool                             `json:"codeActionProvider,omitempty"`
+	ColorProvider                    bool                             `json:"colorProvider,omitempty"`
 	CodeLensProvider                 *CodeLensOptions                 `json:"codeLensProvider,omitempty"`
 	DocumentFormattingProvider       bool                             `json:"documentFormattingProvider,omitempty"`
 	DocumentRangeFormattingProvider  bool                             `json:"documentRangeFormattingProvider,omitempty"`
@@ -320,6 +321,9 @@ type ServerCapabilities struct {
 	RenameProvider                   bool                             `json:"renameProvider,omitempty"`
 	ExecuteCommandProvider           *ExecuteCommandOptions           `json:"executeCommandProvider,omitempty"`
 	SemanticHighlighting             *SemanticHighlightingOptions     `json:"semanticHighlighting,omitempty"`
+	SemanticTokensProvider           *SemanticTokensOptions           `json:"semanticTokensProvider,omitempty"`
+	FoldingRangeProvider             bool                             `json:"foldingRangeProvider,omitempty"`
+	InlayHintProvider                bool                             `json:"inlayHintProvider,omitempty"`
 
 	// XWorkspaceReferencesProvider indicates the server provides support for
 	// xworkspace/references. This is a Sourcegraph extension.
@@ -368,6 +372,63 @@ type SemanticHighlightingOptions struct {
 	Scopes [][]string `json:"scopes,omitempty"`
 }
 
+type SemanticTokensLegend struct {
+	TokenTypes     []string `json:"tokenTypes"`
+	TokenModifiers []string `json:"tokenModifiers"`
+}
+
+type SemanticTokensOptions struct {
+	Legend SemanticTokensLegend `json:"legend"`
+	Full   bool                 `json:"full,omitempty"`
+}
+
+type SemanticTokensParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type SemanticTokens struct {
+	Data []int `json:"data"`
+}
+
+type InlayHintParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Range        Range                  `json:"range"`
+}
+
+type InlayHintKind int
+
+const (
+	IHKType      InlayHintKind = 1
+	IHKParameter InlayHintKind = 2
+)
+
+type InlayHint struct {
+	Position     Position      `json:"position"`
+	Label        string        `json:"label"`
+	Kind         InlayHintKind `json:"kind,omitempty"`
+	PaddingRight bool          `json:"paddingRight,omitempty"`
+}
+
+type FoldingRangeParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type FoldingRangeKind string
+
+const (
+	FRKComment FoldingRangeKind = "comment"
+	FRKImports FoldingRangeKind = "imports"
+	FRKRegion  FoldingRangeKind = "region"
+)
+
+type FoldingRange struct {
+	StartLine      int              `json:"startLine"`
+	StartCharacter int              `json:"startCharacter"`
+	EndLine        int              `json:"endLine"`
+	EndCharacter   int              `json:"endCharacter"`
+	Kind           FoldingRangeKind `json:"kind,omitempty"`
+}
+
 type CompletionItemKind int
 
 const (
@@ -432,18 +493,25 @@ var completionItemKindName = map[CompletionItemKind]string{
 }
 
 type CompletionItem struct {
-	Label               string             `json:"label"`
-	Kind                CompletionItemKind `json:"kind,omitempty"`
-	Detail              string             `json:"detail,omitempty"`
-	Documentation       string             `json:"documentation,omitempty"`
-	SortText            string             `json:"sortText,omitempty"`
-	FilterText          string             `json:"filterText,omitempty"`
-	InsertText          string             `json:"insertText,omitempty"`
-	InsertTextFormat    InsertTextFormat   `json:"insertTextFormat,omitempty"`
-	TextEdit            *TextEdit          `json:"textEdit,omitempty"`
-	AdditionalTextEdits []TextEdit         `json:"additionalTextEdits,omitempty"`
-	Data                interface{}        `json:"data,omitempty"`
-}
+	Label               string              `json:"label"`
+	Kind                CompletionItemKind  `json:"kind,omitempty"`
+	Detail              string              `json:"detail,omitempty"`
+	Documentation       string              `json:"documentation,omitempty"`
+	SortText            string              `json:"sortText,omitempty"`
+	FilterText          string              `json:"filterText,omitempty"`
+	InsertText          string              `json:"insertText,omitempty"`
+	InsertTextFormat    InsertTextFormat    `json:"insertTextFormat,omitempty"`
+	TextEdit            *TextEdit           `json:"textEdit,omitempty"`
+	AdditionalTextEdits []TextEdit          `json:"additionalTextEdits,omitempty"`
+	Data                interface{}         `json:"data,omitempty"`
+	Tags                []CompletionItemTag `json:"tags,omitempty"`
+}
+
+type CompletionItemTag int
+
+const (
+	CITDeprecated CompletionItemTag = 1
+)
 
 type CompletionList struct {
 	IsIncomplete bool             `json:"isIncomplete"`
@@ -692,6 +760,40 @@ type CodeActionParams struct {
 	Context      CodeActionContext      `json:"context"`
 }
 
+type CodeAction struct {
+	Title       string         `json:"title"`
+	Kind        CodeActionKind `json:"kind,omitempty"`
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"`
+	Edit        *WorkspaceEdit `json:"edit,omitempty"`
+}
+
+type DocumentColorParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type Color struct {
+	Red   float64 `json:"red"`
+	Green float64 `json:"green"`
+	Blue  float64 `json:"blue"`
+	Alpha float64 `json:"alpha"`
+}
+
+type ColorInformation struct {
+	Range Range `json:"range"`
+	Color Color `json:"color"`
+}
+
+type ColorPresentationParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Color        Color                  `json:"color"`
+	Range        Range                  `json:"range"`
+}
+
+type ColorPresentation struct {
+	Label    string    `json:"label"`
+	TextEdit *TextEdit `json:"textEdit,omitempty"`
+}
+
 type CodeLensParams struct {
 	TextDocument TextDocumentIdentifier `json:"textDocument"`
 }
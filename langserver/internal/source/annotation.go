@@ -0,0 +1,92 @@
+package source
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// annotationKeys are the keys of the metadata annotation.
+// See https://www.openpolicyagent.org/docs/latest/policy-language/#metadata
+var annotationKeys = []struct {
+	key    string
+	detail string
+}{
+	{key: "title", detail: "the name of the package or the rule"},
+	{key: "description", detail: "the description of the package or the rule"},
+	{key: "scope", detail: "rule, document, package or subpackages"},
+	{key: "schemas", detail: "the schemas of input and data"},
+	{key: "authors", detail: "the authors with the name and the email"},
+	{key: "related_resources", detail: "the links which are related to the policy"},
+	{key: "organizations", detail: "the organizations which are related to the policy"},
+	{key: "entrypoint", detail: "whether the rule is the entrypoint of the policy"},
+	{key: "custom", detail: "the map of the arbitrary data"},
+}
+
+var (
+	annotationKeyLinePattern = regexp.MustCompile(`^\s*#\s*([a-z_]*)$`)
+	annotationKeyPattern     = regexp.MustCompile(`^\s*#\s*([a-z_]+):`)
+)
+
+// listAnnotationCompletionItems lists the keys of the metadata annotation in the comment block which starts with `# METADATA`.
+// The annotations are comments, so the raw text is inspected instead of the AST.
+//
+//	# METADATA
+//	# ti
+//	    ^ title
+func (p *Project) listAnnotationCompletionItems(location *ast.Location) []CompletionItem {
+	policy := p.cache.Get(location.File)
+	if policy == nil || location.Offset > len(policy.RawText) {
+		return nil
+	}
+
+	rawText := policy.RawText
+	lineStart := strings.LastIndex(rawText[:location.Offset], "\n") + 1
+	match := annotationKeyLinePattern.FindStringSubmatch(rawText[lineStart:location.Offset])
+	if match == nil || lineStart == 0 {
+		return nil
+	}
+
+	// the lines above must be the comments up to `# METADATA`.
+	lines := strings.Split(rawText[:lineStart-1], "\n")
+	existKeys := make(map[string]struct{})
+	inBlock := false
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if strings.TrimSpace(strings.TrimPrefix(line, "#")) == "METADATA" {
+			inBlock = true
+			break
+		}
+		if m := annotationKeyPattern.FindStringSubmatch(line); m != nil {
+			existKeys[m[1]] = struct{}{}
+		}
+	}
+	if !inBlock {
+		return nil
+	}
+
+	prefix := match[1]
+	position := offsetToPosition(rawText, location.Offset-len(prefix))
+
+	result := make([]CompletionItem, 0)
+	for _, a := range annotationKeys {
+		if _, ok := existKeys[a.key]; ok || !strings.HasPrefix(a.key, prefix) {
+			continue
+		}
+		result = append(result, CompletionItem{
+			Label:  a.key,
+			Kind:   AnnotationItem,
+			Detail: a.detail,
+			TextEdit: &TextEdit{
+				Row:  position.Row,
+				Col:  position.Col,
+				Text: a.key + ": ",
+			},
+		})
+	}
+	return result
+}
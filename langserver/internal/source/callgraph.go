@@ -0,0 +1,340 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// QualifiedRule identifies the rule by the package path and the rule name.
+type QualifiedRule struct {
+	Package ast.Ref
+	Name    string
+}
+
+func (q QualifiedRule) String() string {
+	return fmt.Sprintf("%s.%s", q.Package.String(), q.Name)
+}
+
+// TransitiveDependencies returns all rules which are reachable from the rule body.
+func (p *Project) TransitiveDependencies(rule QualifiedRule) ([]QualifiedRule, error) {
+	if len(p.findQualifiedRules(rule)) == 0 {
+		return nil, fmt.Errorf("rule %s is not found", rule)
+	}
+
+	visited := map[string]struct{}{rule.String(): {}}
+	result := make([]QualifiedRule, 0)
+	queue := []QualifiedRule{rule}
+	for len(queue) > 0 {
+		q := queue[0]
+		queue = queue[1:]
+
+		for _, callee := range p.callees(q) {
+			if _, ok := visited[callee.String()]; ok {
+				continue
+			}
+			visited[callee.String()] = struct{}{}
+			result = append(result, callee)
+			queue = append(queue, callee)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].String() < result[j].String()
+	})
+	return result, nil
+}
+
+// findQualifiedRules returns all rules which have the rule name in the package.
+// The rule can be defined in multiple files as incremental rules.
+func (p *Project) findQualifiedRules(rule QualifiedRule) []*ast.Rule {
+	result := make([]*ast.Rule, 0)
+	for _, m := range p.cache.FindPolicies(rule.Package) {
+		for _, r := range m.Rules {
+			if ruleHeadName(r) == rule.Name {
+				result = append(result, r)
+			}
+		}
+	}
+	return result
+}
+
+// callees returns the rules which are called from the rule directly.
+func (p *Project) callees(rule QualifiedRule) []QualifiedRule {
+	exists := make(map[string]struct{})
+	result := make([]QualifiedRule, 0)
+	for _, m := range p.cache.FindPolicies(rule.Package) {
+		for _, r := range m.Rules {
+			if ruleHeadName(r) != rule.Name {
+				continue
+			}
+			p.walkCalls(m, r, func(q QualifiedRule, _ *ast.Term) {
+				if _, ok := exists[q.String()]; ok {
+					return
+				}
+				exists[q.String()] = struct{}{}
+				result = append(result, q)
+			})
+		}
+	}
+	return result
+}
+
+// walkCalls calls fn with the rules which are called from the rule and the terms which call them.
+func (p *Project) walkCalls(module *ast.Module, rule *ast.Rule, fn func(QualifiedRule, *ast.Term)) {
+	var vis *ast.GenericVisitor
+	vis = ast.NewGenericVisitor(func(x interface{}) bool {
+		t, ok := x.(*ast.Term)
+		if !ok {
+			return false
+		}
+		switch v := t.Value.(type) {
+		case ast.Ref:
+			if q, ok := p.resolveRuleRef(module, v); ok {
+				fn(q, t)
+			}
+			// the head of ref is already resolved, but the others can have calls like `a[b]`.
+			for _, t := range v[1:] {
+				vis.Walk(t)
+			}
+			return true
+		case ast.Var:
+			if q, ok := p.resolveRuleRef(module, ast.Ref{t}); ok {
+				fn(q, t)
+			}
+		}
+		return false
+	})
+
+	for e := rule; e != nil; e = e.Else {
+		if e.Head.Key != nil {
+			vis.Walk(e.Head.Key)
+		}
+		if e.Head.Value != nil {
+			vis.Walk(e.Head.Value)
+		}
+		vis.Walk(e.Body)
+	}
+}
+
+// CallHierarchyItem is the rule in the call hierarchy with the locations where the call happens.
+type CallHierarchyItem struct {
+	Rule QualifiedRule
+
+	// Location is the name of the first clause of the rule.
+	Location *ast.Location
+
+	// CallSites are the refs which call the rule in the caller, sorted by the file and the offset.
+	// For IncomingCalls they are in the item, and for OutgoingCalls they are in the rule under the cursor.
+	CallSites []*ast.Location
+}
+
+// IncomingCalls returns the rules which call the rule under the location in all packages.
+//
+//	allow { is_admin }
+//	is_admin { ... }
+//	^ allow with the call site of is_admin
+func (p *Project) IncomingCalls(location *ast.Location) ([]CallHierarchyItem, error) {
+	target, err := p.callHierarchyRule(location)
+	if err != nil {
+		return nil, err
+	}
+
+	items := newCallHierarchyItems(p)
+	for _, pkg := range p.cache.GetPackages() {
+		for _, m := range p.cache.FindPolicies(pkg) {
+			for _, r := range m.Rules {
+				caller := QualifiedRule{Package: m.Package.Path, Name: ruleHeadName(r)}
+				p.walkCalls(m, r, func(q QualifiedRule, t *ast.Term) {
+					if q.String() == target.String() {
+						items.add(caller, t.Loc())
+					}
+				})
+			}
+		}
+	}
+	return items.list(), nil
+}
+
+// OutgoingCalls returns the rules which are called from the rule under the location.
+//
+//	allow { is_admin }
+//	^ is_admin with the call site in allow
+func (p *Project) OutgoingCalls(location *ast.Location) ([]CallHierarchyItem, error) {
+	target, err := p.callHierarchyRule(location)
+	if err != nil {
+		return nil, err
+	}
+
+	items := newCallHierarchyItems(p)
+	for _, m := range p.cache.FindPolicies(target.Package) {
+		for _, r := range m.Rules {
+			if ruleHeadName(r) != target.Name {
+				continue
+			}
+			p.walkCalls(m, r, func(q QualifiedRule, t *ast.Term) {
+				items.add(q, t.Loc())
+			})
+		}
+	}
+	return items.list(), nil
+}
+
+// callHierarchyRule returns the rule which is referred at the location, or the rule which contains the location.
+func (p *Project) callHierarchyRule(location *ast.Location) (QualifiedRule, error) {
+	module := p.GetModule(location.File)
+	if module == nil {
+		return QualifiedRule{}, fmt.Errorf("file not found: %s", location.File)
+	}
+
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return QualifiedRule{}, err
+	}
+	if term != nil {
+		ref, ok := term.Value.(ast.Ref)
+		if v, isVar := term.Value.(ast.Var); isVar {
+			ref, ok = ast.Ref{ast.NewTerm(v)}, true
+		}
+		if ok {
+			if q, ok := p.resolveRuleRef(module, ref); ok {
+				return q, nil
+			}
+		}
+	}
+
+	rule := p.findRuleForTerm(location)
+	if rule == nil {
+		return QualifiedRule{}, fmt.Errorf("no rule is found at %d:%d", location.Row, location.Col)
+	}
+	return QualifiedRule{Package: module.Package.Path, Name: ruleHeadName(rule)}, nil
+}
+
+// callHierarchyItems groups the call sites by the rule.
+type callHierarchyItems struct {
+	project *Project
+	items   map[string]*CallHierarchyItem
+}
+
+func newCallHierarchyItems(p *Project) *callHierarchyItems {
+	return &callHierarchyItems{project: p, items: make(map[string]*CallHierarchyItem)}
+}
+
+func (c *callHierarchyItems) add(rule QualifiedRule, callSite *ast.Location) {
+	item, ok := c.items[rule.String()]
+	if !ok {
+		item = &CallHierarchyItem{Rule: rule}
+		rules := c.project.findQualifiedRules(rule)
+		sort.Slice(rules, func(i, j int) bool {
+			if rules[i].Location.File != rules[j].Location.File {
+				return rules[i].Location.File < rules[j].Location.File
+			}
+			return rules[i].Location.Offset < rules[j].Location.Offset
+		})
+		if len(rules) > 0 {
+			item.Location = ruleNameLocation(rules[0])
+		}
+		c.items[rule.String()] = item
+	}
+	item.CallSites = append(item.CallSites, callSite)
+}
+
+// list returns the items sorted by the rule.
+func (c *callHierarchyItems) list() []CallHierarchyItem {
+	result := make([]CallHierarchyItem, 0, len(c.items))
+	for _, item := range c.items {
+		sort.Slice(item.CallSites, func(i, j int) bool {
+			if item.CallSites[i].File != item.CallSites[j].File {
+				return item.CallSites[i].File < item.CallSites[j].File
+			}
+			return item.CallSites[i].Offset < item.CallSites[j].Offset
+		})
+		result = append(result, *item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Rule.String() < result[j].Rule.String()
+	})
+	return result
+}
+
+// resolveRuleRef returns the rule which the ref in the module refers.
+//
+//	import data.lib
+//	lib.method(msg) -> data.lib.method
+//	method(msg)     -> data.<module package>.method
+func (p *Project) resolveRuleRef(module *ast.Module, ref ast.Ref) (QualifiedRule, bool) {
+	if len(ref) == 0 {
+		return QualifiedRule{}, false
+	}
+
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok || head.Equal(ast.InputRootDocument.Value) {
+		return QualifiedRule{}, false
+	}
+
+	var absolute ast.Ref
+	imp := findImportByName(head, module.Imports)
+	switch {
+	case head.Equal(ast.DefaultRootDocument.Value):
+		absolute = ref
+	case imp != nil:
+		path, ok := imp.Path.Value.(ast.Ref)
+		if !ok {
+			return QualifiedRule{}, false
+		}
+		absolute = path.Concat(ref[1:])
+	default:
+		absolute = module.Package.Path.Append(ast.StringTerm(string(head))).Concat(ref[1:])
+	}
+
+	// the longest package is the most specific one.
+	var pkg ast.Ref
+	for _, path := range p.cache.GetPackages() {
+		if len(path) < len(absolute) && absolute.HasPrefix(path) && len(path) > len(pkg) {
+			pkg = path
+		}
+	}
+	if pkg == nil {
+		return QualifiedRule{}, false
+	}
+
+	name, ok := absolute[len(pkg)].Value.(ast.String)
+	if !ok {
+		return QualifiedRule{}, false
+	}
+
+	q := QualifiedRule{Package: pkg, Name: string(name)}
+	if len(p.findQualifiedRules(q)) == 0 {
+		return QualifiedRule{}, false
+	}
+	return q, true
+}
+
+// findImportByName returns the import which is referred by the name.
+// The alias wins over the last segment of the other import, because the alias is what the policy author chose.
+//
+//	import data.lib          -> lib
+//	import data.lib as alias -> alias
+func findImportByName(name ast.Var, imports []*ast.Import) *ast.Import {
+	for _, imp := range imports {
+		if imp.Alias != "" && imp.Alias.Equal(name) {
+			return imp
+		}
+	}
+
+	for _, imp := range imports {
+		if imp.Alias != "" {
+			continue
+		}
+
+		path, ok := imp.Path.Value.(ast.Ref)
+		if !ok || len(path) == 0 {
+			continue
+		}
+		if s, ok := path[len(path)-1].Value.(ast.String); ok && string(s) == string(name) {
+			return imp
+		}
+	}
+	return nil
+}
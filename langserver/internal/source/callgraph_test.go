@@ -0,0 +1,354 @@
+package source_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_TransitiveDependencies(t *testing.T) {
+	tests := map[string]struct {
+		files        map[string]source.File
+		rule         source.QualifiedRule
+		expectResult []source.QualifiedRule
+	}{
+		"Should list multi-hop dependencies across packages": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib.a as alib
+
+allow {
+	alib.check(input.user)
+	is_admin
+}
+
+is_admin {
+	input.role == "admin"
+}`,
+				},
+				"a.rego": {
+					RawText: `package lib.a
+
+check(user) {
+	data.lib.b.helper(user)
+}`,
+				},
+				"b.rego": {
+					RawText: `package lib.b
+
+helper(user) {
+	count(user) > 0
+	base
+}
+
+base := true`,
+				},
+			},
+			rule: source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "allow"},
+			expectResult: []source.QualifiedRule{
+				{Package: ast.MustParseRef("data.lib.a"), Name: "check"},
+				{Package: ast.MustParseRef("data.lib.b"), Name: "base"},
+				{Package: ast.MustParseRef("data.lib.b"), Name: "helper"},
+				{Package: ast.MustParseRef("data.src"), Name: "is_admin"},
+			},
+		},
+		"Should stop at recursive dependencies": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+allow {
+	lib.ping
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+ping {
+	pong
+}
+
+pong {
+	ping
+}`,
+				},
+			},
+			rule: source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "allow"},
+			expectResult: []source.QualifiedRule{
+				{Package: ast.MustParseRef("data.lib"), Name: "ping"},
+				{Package: ast.MustParseRef("data.lib"), Name: "pong"},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.TransitiveDependencies(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got, cmp.Comparer(func(x, y source.QualifiedRule) bool {
+				return x.String() == y.String()
+			})); diff != "" {
+				t.Errorf("TransitiveDependencies result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_CallHierarchy(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+import data.lib
+
+allow {
+	lib.check(input.user)
+	is_admin
+}
+
+deny {
+	not is_admin
+}
+
+is_admin {
+	input.role == "admin"
+}`,
+		},
+		"lib.rego": {
+			RawText: `package lib
+
+check(user) {
+	user.name != ""
+}`,
+		},
+	}
+
+	srcLocation := func(prefix, text string) *ast.Location {
+		return &ast.Location{
+			Row:    strings.Count(prefix, "\n") + 1,
+			Col:    len(prefix) - strings.LastIndex(prefix, "\n"),
+			Offset: len(prefix),
+			Text:   []byte(text),
+			File:   "src.rego",
+		}
+	}
+	allow := srcLocation("package src\n\nimport data.lib\n\n", "allow")
+	callCheck := srcLocation("package src\n\nimport data.lib\n\nallow {\n\t", "lib.check")
+	callIsAdminInAllow := srcLocation("package src\n\nimport data.lib\n\nallow {\n\tlib.check(input.user)\n\t", "is_admin")
+	deny := srcLocation("package src\n\nimport data.lib\n\nallow {\n\tlib.check(input.user)\n\tis_admin\n}\n\n", "deny")
+	callIsAdminInDeny := srcLocation("package src\n\nimport data.lib\n\nallow {\n\tlib.check(input.user)\n\tis_admin\n}\n\ndeny {\n\tnot ", "is_admin")
+	isAdmin := srcLocation("package src\n\nimport data.lib\n\nallow {\n\tlib.check(input.user)\n\tis_admin\n}\n\ndeny {\n\tnot is_admin\n}\n\n", "is_admin")
+	check := &ast.Location{Row: 3, Col: 1, Offset: len("package lib\n\n"), Text: []byte("check"), File: "lib.rego"}
+
+	tests := map[string]struct {
+		outgoing       bool
+		createLocation createLocationFunc
+		expectResult   []source.CallHierarchyItem
+	}{
+		"Should return the callers of the rule with the call sites": {
+			createLocation: createLocation(14, 8, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "allow"},
+					Location:  allow,
+					CallSites: []*ast.Location{callIsAdminInAllow},
+				},
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "deny"},
+					Location:  deny,
+					CallSites: []*ast.Location{callIsAdminInDeny},
+				},
+			},
+		},
+		"Should return the callers in the other package of the rule which is called at the location": {
+			createLocation: createLocation(6, 10, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "allow"},
+					Location:  allow,
+					CallSites: []*ast.Location{callCheck},
+				},
+			},
+		},
+		"Should return the callees of the rule with the call sites": {
+			outgoing:       true,
+			createLocation: createLocation(5, 5, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.lib"), Name: "check"},
+					Location:  check,
+					CallSites: []*ast.Location{callCheck},
+				},
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "is_admin"},
+					Location:  isAdmin,
+					CallSites: []*ast.Location{callIsAdminInAllow},
+				},
+			},
+		},
+		"Should return the callees of the rule which contains the location": {
+			outgoing:       true,
+			createLocation: createLocation(6, 16, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.lib"), Name: "check"},
+					Location:  check,
+					CallSites: []*ast.Location{callCheck},
+				},
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "is_admin"},
+					Location:  isAdmin,
+					CallSites: []*ast.Location{callIsAdminInAllow},
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			calls := project.IncomingCalls
+			if tt.outgoing {
+				calls = project.OutgoingCalls
+			}
+			got, err := calls(tt.createLocation(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got, cmp.Comparer(func(x, y source.QualifiedRule) bool {
+				return x.String() == y.String()
+			})); diff != "" {
+				t.Errorf("call hierarchy result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_CallHierarchyWithRefHead(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+roles.admin[user] {
+	user := input.users[_]
+	is_admin(user)
+}
+
+is_admin(user) {
+	user.role == "admin"
+}
+
+allow {
+	roles.admin[input.user]
+}`,
+		},
+	}
+
+	srcLocation := func(prefix, text string) *ast.Location {
+		return &ast.Location{
+			Row:    strings.Count(prefix, "\n") + 1,
+			Col:    len(prefix) - strings.LastIndex(prefix, "\n"),
+			Offset: len(prefix),
+			Text:   []byte(text),
+			File:   "src.rego",
+		}
+	}
+	roles := srcLocation("package src\n\n", "roles")
+	callIsAdmin := srcLocation("package src\n\nroles.admin[user] {\n\tuser := input.users[_]\n\t", "is_admin")
+	isAdmin := srcLocation("package src\n\nroles.admin[user] {\n\tuser := input.users[_]\n\tis_admin(user)\n}\n\n", "is_admin")
+	allow := srcLocation("package src\n\nroles.admin[user] {\n\tuser := input.users[_]\n\tis_admin(user)\n}\n\nis_admin(user) {\n\tuser.role == \"admin\"\n}\n\n", "allow")
+	callRoles := srcLocation("package src\n\nroles.admin[user] {\n\tuser := input.users[_]\n\tis_admin(user)\n}\n\nis_admin(user) {\n\tuser.role == \"admin\"\n}\n\nallow {\n\t", "roles.admin[input.user]")
+
+	tests := map[string]struct {
+		outgoing       bool
+		createLocation createLocationFunc
+		expectResult   []source.CallHierarchyItem
+	}{
+		"Should return the ref head rule as the caller": {
+			createLocation: createLocation(8, 3, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "roles"},
+					Location:  roles,
+					CallSites: []*ast.Location{callIsAdmin},
+				},
+			},
+		},
+		"Should return the callers of the ref head rule": {
+			createLocation: createLocation(13, 3, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "allow"},
+					Location:  allow,
+					CallSites: []*ast.Location{callRoles},
+				},
+			},
+		},
+		"Should return the callees of the ref head rule": {
+			outgoing:       true,
+			createLocation: createLocation(4, 3, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "is_admin"},
+					Location:  isAdmin,
+					CallSites: []*ast.Location{callIsAdmin},
+				},
+			},
+		},
+		"Should return the ref head rule as the callee": {
+			outgoing:       true,
+			createLocation: createLocation(12, 3, "src.rego"),
+			expectResult: []source.CallHierarchyItem{
+				{
+					Rule:      source.QualifiedRule{Package: ast.MustParseRef("data.src"), Name: "roles"},
+					Location:  roles,
+					CallSites: []*ast.Location{callRoles},
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			calls := project.IncomingCalls
+			if tt.outgoing {
+				calls = project.OutgoingCalls
+			}
+			got, err := calls(tt.createLocation(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got, cmp.Comparer(func(x, y source.QualifiedRule) bool {
+				return x.String() == y.String()
+			})); diff != "" {
+				t.Errorf("call hierarchy result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// CodeActions returns the quick fixes for the diagnostics in the range.
+// The diagnostics are the ones which the client has, like the compile errors of the file.
+func (p *Project) CodeActions(path string, rng Range, diagnostics []Diagnostic) ([]CodeAction, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if policy.Module == nil {
+		return []CodeAction{}, nil
+	}
+
+	result := make([]CodeAction, 0)
+	for _, d := range diagnostics {
+		if d.Location == nil || !inRows(d.Location, rng) {
+			continue
+		}
+
+		var actions []CodeAction
+		switch d.Code {
+		case ast.TypeErr, ast.UnsafeVarErr:
+			actions = p.missingImportActions(policy.Module, d)
+		case UnusedImportCode:
+			actions = removeImportActions(policy.RawText, d)
+		}
+		for _, a := range actions {
+			a.Diagnostics = []Diagnostic{d}
+			result = append(result, a)
+		}
+	}
+
+	result = append(result, p.assignActions(policy.Module, rng)...)
+	return result, nil
+}
+
+// inRows returns true when the location and the range share a row.
+func inRows(location *ast.Location, rng Range) bool {
+	endRow := location.Row + strings.Count(string(location.Text), "\n")
+	return location.Row <= rng.End.Row && rng.Start.Row <= endRow
+}
+
+// missingImportActions returns the actions which import the package whose last segment is referred without the import.
+//
+//	lib.f(1) -> undefined function lib.f
+//	lib.r    -> var lib is unsafe
+func (p *Project) missingImportActions(module *ast.Module, d Diagnostic) []CodeAction {
+	var name string
+	switch {
+	case strings.HasPrefix(d.Message, "undefined function "):
+		fn := strings.TrimPrefix(d.Message, "undefined function ")
+		i := strings.Index(fn, ".")
+		if i < 0 {
+			return nil
+		}
+		name = fn[:i]
+	case strings.HasPrefix(d.Message, "var ") && strings.HasSuffix(d.Message, " is unsafe"):
+		name = strings.TrimSuffix(strings.TrimPrefix(d.Message, "var "), " is unsafe")
+	default:
+		return nil
+	}
+
+	for _, imp := range module.Imports {
+		if importToLabel(imp) == name {
+			return nil
+		}
+	}
+
+	result := make([]CodeAction, 0)
+	for _, pkg := range p.cache.GetPackages() {
+		if pkg.Equal(module.Package.Path) {
+			continue
+		}
+		if s, ok := pkg[len(pkg)-1].Value.(ast.String); !ok || string(s) != name {
+			continue
+		}
+		result = append(result, CodeAction{
+			Title: fmt.Sprintf("Import %s", pkg.String()),
+			Edits: []TextEdit{importTextEdit(module, pkg)},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Title < result[j].Title
+	})
+	return result
+}
+
+// removeImportActions returns the action which deletes the line of the unused import.
+// The blank line after the import is also deleted when the line before it is blank,
+// so deleting the only import doesn't leave the doubled blank lines.
+//
+//	package src
+//
+//	import data.lib <- deleted with the next blank line
+//
+//	allow { ... }
+func removeImportActions(rawText string, d Diagnostic) []CodeAction {
+	loc := d.Location
+	if loc.Offset+len(loc.Text) > len(rawText) {
+		return nil
+	}
+
+	start := strings.LastIndex(rawText[:loc.Offset], "\n") + 1
+	end := len(rawText)
+	if i := strings.Index(rawText[loc.Offset+len(loc.Text):], "\n"); i >= 0 {
+		end = loc.Offset + len(loc.Text) + i + 1
+	}
+	if prevBlank := start == 0 || strings.HasSuffix(rawText[:start], "\n\n"); prevBlank && strings.HasPrefix(rawText[end:], "\n") {
+		end++
+	}
+
+	endPosition := offsetToPosition(rawText, end)
+	return []CodeAction{
+		{
+			Title: fmt.Sprintf("Remove %s", strings.TrimSpace(string(loc.Text))),
+			Edits: []TextEdit{
+				{
+					Row:  loc.Row,
+					Col:  1,
+					End:  &endPosition,
+					Text: "",
+				},
+			},
+		},
+	}
+}
+
+// assignActions returns the actions which rewrite `x = expr` to `x := expr` in the range,
+// when x is not bound before it, so `=` is the assignment rather than the comparison.
+func (p *Project) assignActions(module *ast.Module, rng Range) []CodeAction {
+	result := make([]CodeAction, 0)
+	for _, rule := range module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			for _, expr := range r.Body {
+				if expr.Location == nil || !inRows(expr.Location, rng) {
+					continue
+				}
+				if !expr.IsCall() || !ast.Equality.Ref().Equal(expr.Operator()) {
+					continue
+				}
+
+				lhs := expr.Operand(0)
+				if !p.isUnboundVar(lhs, r) {
+					continue
+				}
+
+				op := expr.Terms.([]*ast.Term)[0]
+				if op.Location == nil || string(op.Location.Text) != "=" {
+					continue
+				}
+				result = append(result, CodeAction{
+					Title: fmt.Sprintf("Use := to assign %s", lhs.Value),
+					Edits: []TextEdit{
+						{
+							Row:  op.Location.Row,
+							Col:  op.Location.Col,
+							Text: ":=",
+							End:  &Position{Row: op.Location.Row, Col: op.Location.Col + 1},
+						},
+					},
+				})
+			}
+		}
+	}
+	return result
+}
+
+// isUnboundVar returns true when the term is the variable which is bound first at the term.
+func (p *Project) isUnboundVar(term *ast.Term, rule *ast.Rule) bool {
+	v, ok := term.Value.(ast.Var)
+	if !ok || v.IsGenerated() || v.IsWildcard() || term.Location == nil {
+		return false
+	}
+	if ast.RootDocumentNames.Contains(term) {
+		return false
+	}
+
+	// the arguments are bound by the caller.
+	if p.findDefinitionInTerms(term, rule.Head.Args) != nil || p.findDefinitionInBody(term, rule.Body) != nil {
+		return false
+	}
+	// the rule names and the imports
+	return len(p.findDefinitionOutOfRule(term)) == 0
+}
@@ -0,0 +1,226 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_CodeActions(t *testing.T) {
+	tests := map[string]struct {
+		files         map[string]source.File
+		path          string
+		rng           source.Range
+		expectActions []source.CodeAction
+		// expectQuickFix is true when the actions resolve the diagnostics.
+		expectQuickFix bool
+	}{
+		"Should import the package of the undefined function": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	lib.is_admin(input.user)
+}`,
+				},
+				"lib.rego": {
+					RawText: `package x.lib
+
+is_admin(user) {
+	user == "admin"
+}`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 4, Col: 2}, End: source.Position{Row: 4, Col: 2}},
+			expectActions: []source.CodeAction{
+				{
+					Title: "Import data.x.lib",
+					Edits: []source.TextEdit{{Row: 2, Col: 1, Text: "\nimport data.x.lib\n"}},
+				},
+			},
+			expectQuickFix: true,
+		},
+		"Should import the package of the unsafe var after the last import": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.in
+
+allow {
+	lib.admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+admin := true`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 6, Col: 2}, End: source.Position{Row: 6, Col: 2}},
+			expectActions: []source.CodeAction{
+				{
+					Title: "Import data.lib",
+					Edits: []source.TextEdit{{Row: 4, Col: 1, Text: "import data.lib\n"}},
+				},
+			},
+			expectQuickFix: true,
+		},
+		"Should remove the unused import line": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+import data.util
+
+allow {
+	lib.admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+admin := true`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 4, Col: 1}, End: source.Position{Row: 4, Col: 1}},
+			expectActions: []source.CodeAction{
+				{
+					Title: "Remove import data.util",
+					Edits: []source.TextEdit{{Row: 4, Col: 1, End: &source.Position{Row: 5, Col: 1}}},
+				},
+			},
+			expectQuickFix: true,
+		},
+		"Should remove the only import with the following blank line": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.util as u
+
+allow {
+	input.admin
+}`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 3, Col: 1}, End: source.Position{Row: 3, Col: 1}},
+			expectActions: []source.CodeAction{
+				{
+					Title: "Remove import data.util as u",
+					Edits: []source.TextEdit{{Row: 3, Col: 1, End: &source.Position{Row: 5, Col: 1}}},
+				},
+			},
+			expectQuickFix: true,
+		},
+		"Should rewrite the unification of the fresh variable to the assignment": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	name = input.user.name
+	name == "admin"
+}`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 4, Col: 2}, End: source.Position{Row: 4, Col: 2}},
+			expectActions: []source.CodeAction{
+				{
+					Title: "Use := to assign name",
+					Edits: []source.TextEdit{{Row: 4, Col: 7, Text: ":=", End: &source.Position{Row: 4, Col: 8}}},
+				},
+			},
+		},
+		"Should not rewrite the comparison of the bound variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+is_admin(user) {
+	user = "admin"
+}
+
+allow {
+	x := 1
+	x = 1
+	allow_all = true
+	input.user = "admin"
+}
+
+allow_all := false`,
+				},
+			},
+			path:          "src.rego",
+			rng:           source.Range{Start: source.Position{Row: 1, Col: 1}, End: source.Position{Row: 14, Col: 1}},
+			expectActions: []source.CodeAction{},
+		},
+		"Should not return the action out of the range": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	lib.admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+admin := true`,
+				},
+			},
+			path:          "src.rego",
+			rng:           source.Range{Start: source.Position{Row: 1, Col: 1}, End: source.Position{Row: 2, Col: 1}},
+			expectActions: []source.CodeAction{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diagnostics := make([]source.Diagnostic, 0)
+			for _, e := range project.GetErrors(tt.path)[tt.path] {
+				diagnostics = append(diagnostics, source.Diagnostic{
+					Location: e.Location,
+					Severity: source.SeverityError,
+					Code:     e.Code,
+					Message:  e.Message,
+				})
+			}
+
+			unusedImports, err := project.UnusedImports(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diagnostics = append(diagnostics, unusedImports...)
+
+			got, err := project.CodeActions(tt.path, tt.rng, diagnostics)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, a := range got {
+				if tt.expectQuickFix != (len(a.Diagnostics) == 1) {
+					t.Errorf("CodeActions resolved diagnostics of %s should be set only for the quick fix, but got %v", a.Title, a.Diagnostics)
+				}
+				got[i].Diagnostics = nil
+			}
+			if diff := cmp.Diff(tt.expectActions, got); diff != "" {
+				t.Errorf("CodeActions result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
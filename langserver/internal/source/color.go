@@ -0,0 +1,96 @@
+package source
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// Color is RGBA color which each component is in the range [0, 1].
+type Color struct {
+	Red   float64
+	Green float64
+	Blue  float64
+	Alpha float64
+}
+
+// Hex returns the color as `#RRGGBB`.
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", colorComponentToByte(c.Red), colorComponentToByte(c.Green), colorComponentToByte(c.Blue))
+}
+
+func colorComponentToByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return uint8(v*255 + 0.5)
+}
+
+type ColorInformation struct {
+	// Location is the location of the color text without the quotes.
+	Location *ast.Location
+	Color    Color
+}
+
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// DocumentColors returns string literals which are `#RRGGBB` colors.
+func (p *Project) DocumentColors(path string) ([]ColorInformation, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if policy.Module == nil {
+		return nil, nil
+	}
+
+	result := make([]ColorInformation, 0)
+	ast.WalkTerms(policy.Module, func(t *ast.Term) bool {
+		s, ok := t.Value.(ast.String)
+		if !ok || t.Location == nil || !hexColorPattern.MatchString(string(s)) {
+			return false
+		}
+		// skip the escaped string like "\u0023ff0000", because the location doesn't match the value.
+		if len(t.Location.Text) != len(s)+2 {
+			return false
+		}
+
+		color, err := parseHexColor(string(s))
+		if err != nil {
+			return false
+		}
+
+		// skip the opening quote
+		result = append(result, ColorInformation{
+			Location: &ast.Location{
+				Row:    t.Location.Row,
+				Col:    t.Location.Col + 1,
+				Offset: t.Location.Offset + 1,
+				Text:   []byte(s),
+				File:   t.Location.File,
+			},
+			Color: color,
+		})
+		return false
+	})
+	return result, nil
+}
+
+func parseHexColor(s string) (Color, error) {
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return Color{}, err
+	}
+
+	return Color{
+		Red:   float64((v>>16)&0xff) / 255,
+		Green: float64((v>>8)&0xff) / 255,
+		Blue:  float64(v&0xff) / 255,
+		Alpha: 1,
+	}, nil
+}
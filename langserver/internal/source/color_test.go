@@ -0,0 +1,74 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_DocumentColors(t *testing.T) {
+	tests := map[string]struct {
+		files        map[string]source.File
+		path         string
+		expectColors []source.ColorInformation
+	}{
+		"Should return hex color string": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+color := "#ff8000"`,
+				},
+			},
+			path: "src.rego",
+			expectColors: []source.ColorInformation{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    11,
+						Offset: len("package src\n\ncolor := \""),
+						Text:   []byte("#ff8000"),
+						File:   "src.rego",
+					},
+					Color: source.Color{Red: 1, Green: 128.0 / 255, Blue: 0, Alpha: 1},
+				},
+			},
+		},
+		"Should not return non-color strings": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.name == "hello"
+	input.short == "#fff"
+	input.invalid == "#gggggg"
+	input.tag == "#ff8000 "
+}`,
+				},
+			},
+			path:         "src.rego",
+			expectColors: []source.ColorInformation{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.DocumentColors(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectColors, got); diff != "" {
+				t.Errorf("DocumentColors result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
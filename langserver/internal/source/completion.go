@@ -3,23 +3,46 @@ package source
 import (
 	"fmt"
 	"path"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/types"
 )
 
 type CompletionItem struct {
-	Label               string
-	Kind                CompletionKind
-	Detail              string
+	Label string
+	Kind  CompletionKind
+
+	// Detail is the source of the rule like `is_hello(msg) { ... }` or the signature of the built-in function.
+	Detail string
+
+	// TextEdit has the text which is inserted, like the call signature `is_hello(msg)` of the function.
 	TextEdit            *TextEdit
 	AdditionalTextEdits []TextEdit
+
+	// Deprecated is true for the deprecated built-in functions, which are listed after the other items.
+	Deprecated bool
 }
 
 type TextEdit struct {
 	Row  int
 	Col  int
 	Text string
+
+	// End is the end of the replaced text.
+	// When End is nil, Text is inserted at Row and Col.
+	End *Position
+}
+
+type Position struct {
+	Row int
+	Col int
+}
+
+type Range struct {
+	Start Position
+	End   Position
 }
 
 type CompletionKind int
@@ -31,9 +54,22 @@ const (
 	FunctionItem
 	BuiltinFunctionItem
 	ImportItem
+	KeywordItem
+	// SnippetItem has the text which includes tab stops like `${1:coll}`.
+	SnippetItem
+	// AnnotationItem is the key of the metadata annotation.
+	AnnotationItem
 )
 
 func (p *Project) ListCompletionItems(location *ast.Location) ([]CompletionItem, error) {
+	if items := p.listAnnotationCompletionItems(location); items != nil {
+		return items, nil
+	}
+
+	if items := p.listSchemaKeyCompletionItems(location); len(items) > 0 {
+		return items, nil
+	}
+
 	term, err := p.SearchTargetTerm(location)
 	if err != nil {
 		return nil, err
@@ -50,7 +86,7 @@ func (p *Project) ListCompletionItems(location *ast.Location) ([]CompletionItem,
 	}
 
 	// list candidates
-	list := p.listCompletionCandidates(location, term)
+	list := p.listCachedCompletionCandidates(location, term)
 
 	// filter items
 	list = filterCompletionItems(term, list)
@@ -73,70 +109,212 @@ func (p *Project) listCompletionCandidates(location *ast.Location, target *ast.T
 		}
 	}
 
+	// `not ` cannot be parsed until the expression is typed.
+	if len(policy.Errs) > 0 && p.isAfterNot(location) {
+		return p.listNotCompletionItems(location, target)
+	}
+
 	if policy.Module == nil {
 		return nil
 	}
 
 	for _, r := range policy.Module.Rules {
 		if in(location, r.Loc()) {
+			if isInRuleName(location, r) {
+				// the items are filtered by the target term later.
+				return p.listRuleNameItems(location, "")
+			}
 			return p.listCompletionItemsForTerms(location, target)
 		}
 	}
 
-	return p.listImportCompletionItems(location)
+	result := p.listImportCompletionItems(location)
+	result = append(result, p.listElseCompletionItems(location)...)
+	result = append(result, p.listDefaultCompletionItems(location)...)
+	result = append(result, p.listRuleSnippetItems(location)...)
+	if word, ok := p.topLevelWord(location); ok {
+		_, wordOffset := currentWord(policy.RawText, location.Offset)
+		position := offsetToPosition(policy.RawText, wordOffset)
+		wordLocation := &ast.Location{Row: position.Row, Col: position.Col, Offset: wordOffset, Text: []byte(word), File: location.File}
+		result = append(result, p.listRuleNameItems(wordLocation, word)...)
+	}
+	return result
+}
+
+// isInRuleName returns true when the location is in the name of the rule head.
+// The default rule is false, because its location starts with `default`.
+func isInRuleName(location *ast.Location, rule *ast.Rule) bool {
+	if !strings.HasPrefix(string(rule.Location.Text), rule.Head.Name.String()) {
+		return false
+	}
+	return in(location, ruleNameLocation(rule))
+}
+
+// listRuleNameItems lists the names of the rules in the package to add the definition of the incremental rule.
+// The rule whose name is being typed is not listed, because it is not defined yet.
+//
+//	allow { ... }
+//
+//	allo
+//	    ^ allow
+func (p *Project) listRuleNameItems(location *ast.Location, prefix string) []CompletionItem {
+	module := p.GetModule(location.File)
+	if module == nil {
+		return nil
+	}
+
+	rules := make([]*ast.Rule, 0)
+	for _, m := range p.cache.FindPolicies(module.Package.Path) {
+		for _, r := range m.Rules {
+			if (r.Location.File == location.File && isInRuleName(location, r)) || p.isHiddenRule(r) {
+				continue
+			}
+			rules = append(rules, r)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Head.Name.Compare(rules[j].Head.Name) < 0
+	})
+
+	result := make([]CompletionItem, 0)
+	exists := make(map[string]struct{})
+	for _, r := range rules {
+		name := r.Head.Name.String()
+		if _, ok := exists[name]; ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		exists[name] = struct{}{}
+
+		item := createRuleCompletionItem(location, r)
+		item.TextEdit = createTextEdit(location, name)
+		result = append(result, item)
+	}
+	return result
 }
 
 func (p *Project) listPackageCompletionItems(location *ast.Location) []CompletionItem {
 	fileNames := make([]string, 0)
 	file := path.Base(location.File)
 	if ind := strings.LastIndex(file, ".rego"); ind > 0 {
-		fileName := file[:ind]
+		fileName := strings.TrimSuffix(file[:ind], "_test")
 
-		if strings.HasSuffix(fileName, "_test") {
-			packageName := fileName[:len(fileName)-len("_test")]
-			fileNames = append(fileNames, packageName)
-		} else {
-			fileNames = append(fileNames, fileName)
+		// core.v1.rego -> core.v1
+		if name, ok := toPackageName(fileName); ok {
+			fileNames = append(fileNames, name)
 		}
 	}
 
 	dirNames := make([]string, 0)
 	dir := path.Dir(location.File)
-	if dir != "." {
-		ind := strings.LastIndex(dir, "/")
-		dirNames = append(dirNames, dir[ind+1:])
+	// the file at the root of the workspace has no directory for the package.
+	if dir != "." && dir != "/" && dir != path.Clean(p.rootPath) {
+		if name, ok := toPackageName(path.Base(dir)); ok && !strings.Contains(name, ".") {
+			dirNames = append(dirNames, name)
+		}
 	}
 
-	for i, fileName := range fileNames {
-		fileNames[i] = strings.ReplaceAll(fileName, "-", "_")
+	result := make([]CompletionItem, 0)
+	if strings.HasSuffix(file, "_test.rego") {
+		result = append(result, p.listSiblingTestPackageItems(location)...)
 	}
-	for i, dirName := range dirNames {
-		dirNames[i] = strings.ReplaceAll(dirName, "-", "_")
+	if len(dirNames) == 0 {
+		for _, f := range fileNames {
+			result = append(result, createPackageCompletionItem(location, f))
+		}
 	}
-
-	result := make([]CompletionItem, 0)
 	for _, d := range dirNames {
-		result = append(result, CompletionItem{
-			Label:    fmt.Sprintf("package %s", d),
-			Kind:     PackageItem,
-			TextEdit: createTextEdit(location, fmt.Sprintf("package %s", d)),
-		})
+		result = append(result, createPackageCompletionItem(location, d))
 		for _, f := range fileNames {
-			result = append(result, CompletionItem{
-				Label:    fmt.Sprintf("package %s", f),
-				Kind:     PackageItem,
-				TextEdit: createTextEdit(location, fmt.Sprintf("package %s", f)),
-			}, CompletionItem{
-				Label:    fmt.Sprintf("package %s.%s", d, f),
-				Kind:     PackageItem,
-				TextEdit: createTextEdit(location, fmt.Sprintf("package %s.%s", d, f)),
-			})
+			result = append(result,
+				createPackageCompletionItem(location, f),
+				createPackageCompletionItem(location, fmt.Sprintf("%s.%s", d, f)),
+			)
 		}
 	}
 
 	return result
 }
 
+func createPackageCompletionItem(location *ast.Location, name string) CompletionItem {
+	return CompletionItem{
+		Label:    fmt.Sprintf("package %s", name),
+		Kind:     PackageItem,
+		TextEdit: createTextEdit(location, fmt.Sprintf("package %s", name)),
+	}
+}
+
+// InsertPackageEdit returns the edit which inserts `package <name>` and a blank line at the top of the file without the package.
+// The whitespaces of the empty file are replaced, so the file starts with the package.
+func (p *Project) InsertPackageEdit(path string, pkgName string) TextEdit {
+	edit := TextEdit{
+		Row:  1,
+		Col:  1,
+		Text: fmt.Sprintf("package %s\n\n", pkgName),
+	}
+
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return edit
+	}
+	if strings.TrimSpace(policy.RawText) == "" {
+		if policy.RawText != "" {
+			end := offsetToPosition(policy.RawText, len(policy.RawText))
+			edit.End = &end
+		}
+		return edit
+	}
+	if strings.HasPrefix(policy.RawText, "\n") {
+		edit.Text = fmt.Sprintf("package %s\n", pkgName)
+	}
+	return edit
+}
+
+// toPackageName converts the file or directory name into the package name.
+// It returns false when a part of the name cannot be a package name like `v1.2`.
+func toPackageName(name string) (string, bool) {
+	parts := strings.Split(strings.ReplaceAll(name, "-", "_"), ".")
+	for _, p := range parts {
+		if !varNamePattern.MatchString(p) {
+			return "", false
+		}
+	}
+	return strings.Join(parts, "."), true
+}
+
+// listSiblingTestPackageItems lists the packages of the other test files in the same directory,
+// so that the test file can share the helper rules with them.
+func (p *Project) listSiblingTestPackageItems(location *ast.Location) []CompletionItem {
+	dir := path.Dir(location.File)
+	packages := make([]string, 0)
+	exists := make(map[string]struct{})
+	for _, pkg := range p.cache.GetPackages() {
+		for _, m := range p.cache.FindPolicies(pkg) {
+			file := m.Package.Location.File
+			if file == location.File || path.Dir(file) != dir || !strings.HasSuffix(file, "_test.rego") {
+				continue
+			}
+
+			name := strings.TrimPrefix(pkg.String(), "data.")
+			if _, ok := exists[name]; ok {
+				continue
+			}
+			exists[name] = struct{}{}
+			packages = append(packages, name)
+		}
+	}
+	sort.Strings(packages)
+
+	result := make([]CompletionItem, len(packages))
+	for i, name := range packages {
+		result[i] = CompletionItem{
+			Label:    fmt.Sprintf("package %s", name),
+			Kind:     PackageItem,
+			TextEdit: createTextEdit(location, fmt.Sprintf("package %s", name)),
+		}
+	}
+	return result
+}
+
 func (p *Project) listCompletionItemsForTerms(location *ast.Location, target *ast.Term) []CompletionItem {
 	result := make([]CompletionItem, 0)
 
@@ -145,6 +323,11 @@ func (p *Project) listCompletionItemsForTerms(location *ast.Location, target *as
 		return nil
 	}
 
+	// input.config. has only the properties of the schema.
+	if items := p.listSchemaPropertyItems(location, target); len(items) > 0 {
+		return items
+	}
+
 	if !isLibraryTerm(target) {
 		result = append(result, p.listLibraryVariables(location, module)...)
 
@@ -152,9 +335,21 @@ func (p *Project) listCompletionItemsForTerms(location *ast.Location, target *as
 		if rule != nil {
 			list := p.listCompletionItemsInRule(location, rule)
 			result = append(result, list...)
+
+			if isInWithValue(location, rule) {
+				result = append(result, listRootDocumentItems()...)
+			}
+
+			if p.isAfterNot(location) {
+				result = append(result, p.listNotCompletionItems(location, target)...)
+			}
 		}
 	}
 
+	if rule := p.findRuleForTerm(location); rule != nil {
+		result = append(result, listObjectKeys(location, target, rule)...)
+		result = append(result, p.listResultFields(location, target, rule)...)
+	}
 	result = append(result, p.listRules(location, target)...)
 	result = append(result, p.listBuiltinFunctions(location, target)...)
 
@@ -176,27 +371,6 @@ func (p *Project) listLibraryVariables(loc *ast.Location, module *ast.Module) []
 	for _, p := range pkgs {
 		if !isImported(p, module.Imports) && !p.Equal(module.Package.Path) {
 			label := string(p[len(p)-1].Value.(ast.String))
-
-			var importTextEdit TextEdit
-			if len(module.Imports) != 0 {
-				lastImportedRow := 0
-				for _, imp := range module.Imports {
-					if lastImportedRow < imp.Location.Row {
-						lastImportedRow = imp.Location.Row
-					}
-					importTextEdit = TextEdit{
-						Row:  lastImportedRow + 1,
-						Col:  1,
-						Text: fmt.Sprintf("import %s\n", p.String()),
-					}
-				}
-			} else {
-				importTextEdit = TextEdit{
-					Row:  module.Package.Location.Row + 1,
-					Col:  1,
-					Text: fmt.Sprintf("\nimport %s\n", p.String()),
-				}
-			}
 			result = append(result, CompletionItem{
 				Label: label,
 				Kind:  PackageItem,
@@ -206,7 +380,7 @@ func (p *Project) listLibraryVariables(loc *ast.Location, module *ast.Module) []
 					Text: label,
 				},
 				AdditionalTextEdits: []TextEdit{
-					importTextEdit,
+					importTextEdit(module, p),
 				},
 			})
 		}
@@ -214,6 +388,29 @@ func (p *Project) listLibraryVariables(loc *ast.Location, module *ast.Module) []
 	return result
 }
 
+// importTextEdit returns the edit which inserts the import of the package after the last import or the package declaration.
+func importTextEdit(module *ast.Module, pkg ast.Ref) TextEdit {
+	if len(module.Imports) == 0 {
+		return TextEdit{
+			Row:  module.Package.Location.Row + 1,
+			Col:  1,
+			Text: fmt.Sprintf("\nimport %s\n", pkg.String()),
+		}
+	}
+
+	lastImportedRow := 0
+	for _, imp := range module.Imports {
+		if lastImportedRow < imp.Location.Row {
+			lastImportedRow = imp.Location.Row
+		}
+	}
+	return TextEdit{
+		Row:  lastImportedRow + 1,
+		Col:  1,
+		Text: fmt.Sprintf("import %s\n", pkg.String()),
+	}
+}
+
 func isImported(p ast.Ref, imports []*ast.Import) bool {
 	for _, imp := range imports {
 		if p.Equal(imp.Path.Value) {
@@ -241,8 +438,32 @@ func (p *Project) listCompletionItemsInRule(loc *ast.Location, rule *ast.Rule) [
 		}
 	}
 
-	for _, b := range rule.Body {
-		if b.Loc().Row >= loc.Row {
+	if in(loc, rule.Head.Loc()) {
+		result = append(result, p.listCompletionItemsInComprehension(loc, rule.Head)...)
+	}
+
+	result = append(result, p.listCompletionItemsInBody(loc, rule.Body)...)
+	return result
+}
+
+func (p *Project) listCompletionItemsInBody(loc *ast.Location, body ast.Body) []CompletionItem {
+	// the with value can refer to the variables declared before it in the same line.
+	//
+	//	mock := {"user": "admin"}; allow with input as m
+	return p.listCompletionItemsInExprs(loc, body, isInWithValueOfBody(loc, body))
+}
+
+// listCompletionItemsInExprs lists the variables which are bound before the location.
+// When sameRow is true, the expressions before the location in the same row are also listed.
+func (p *Project) listCompletionItemsInExprs(loc *ast.Location, body ast.Body, sameRow bool) []CompletionItem {
+	result := make([]CompletionItem, 0)
+	for _, b := range body {
+		if _, ok := b.Terms.(*ast.Every); !ok && in(loc, b.Loc()) {
+			result = append(result, p.listCompletionItemsInComprehension(loc, b)...)
+			break
+		}
+
+		if b.Loc().Row >= loc.Row && (!sameRow || b.Loc().Offset+len(b.Loc().Text) >= loc.Offset) {
 			break
 		}
 
@@ -255,12 +476,88 @@ func (p *Project) listCompletionItemsInRule(loc *ast.Location, rule *ast.Rule) [
 				list := p.listCompletionItemsInTerm(loc, t[1])
 				result = append(result, list...)
 			}
+		case *ast.SomeDecl:
+			for _, s := range t.Symbols {
+				call, ok := s.Value.(ast.Call)
+				if !ok {
+					result = append(result, p.listCompletionItemsInTerm(loc, s)...)
+					continue
+				}
+				// some k, v in xs -> internal.member_3(k, v, xs)
+				for _, arg := range call[1 : len(call)-1] {
+					result = append(result, p.listCompletionItemsInTerm(loc, arg)...)
+				}
+			}
+		case *ast.Every:
+			// the variables of every are visible only in its body.
+			if !in(loc, b.Loc()) {
+				continue
+			}
+			if t.Key != nil {
+				result = append(result, p.listCompletionItemsInTerm(loc, t.Key)...)
+			}
+			result = append(result, p.listCompletionItemsInTerm(loc, t.Value)...)
+			result = append(result, p.listCompletionItemsInBody(loc, t.Body)...)
 		}
 	}
 
 	return result
 }
 
+// listCompletionItemsInComprehension lists the variables which are bound before the location in the body of the comprehension in x.
+//
+//	ids := [id | u := input.users[_]; id := u.]
+//	                                         ^ u
+func (p *Project) listCompletionItemsInComprehension(loc *ast.Location, x interface{}) []CompletionItem {
+	c := findComprehensionTerm(&ast.Term{Location: loc}, x)
+	if c == nil {
+		return nil
+	}
+
+	var body ast.Body
+	switch v := c.Value.(type) {
+	case *ast.ArrayComprehension:
+		body = v.Body
+	case *ast.SetComprehension:
+		body = v.Body
+	case *ast.ObjectComprehension:
+		body = v.Body
+	}
+	// the body of the comprehension is often written in one line.
+	return p.listCompletionItemsInExprs(loc, body, true)
+}
+
+// isInWithValue returns true when the location is in the value of with keyword.
+//
+//	allow with input as i
+//	                    ^ location
+func isInWithValue(loc *ast.Location, rule *ast.Rule) bool {
+	for ; rule != nil; rule = rule.Else {
+		if isInWithValueOfBody(loc, rule.Body) {
+			return true
+		}
+	}
+	return false
+}
+
+func isInWithValueOfBody(loc *ast.Location, body ast.Body) bool {
+	for _, b := range body {
+		for _, w := range b.With {
+			if w.Value != nil && in(loc, w.Value.Loc()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func listRootDocumentItems() []CompletionItem {
+	return []CompletionItem{
+		{Label: "input", Kind: VariableItem},
+		{Label: "data", Kind: VariableItem},
+	}
+}
+
 func (p *Project) listCompletionItemsInTerm(loc *ast.Location, term *ast.Term) []CompletionItem {
 	result := make([]CompletionItem, 0)
 	switch v := term.Value.(type) {
@@ -268,6 +565,15 @@ func (p *Project) listCompletionItemsInTerm(loc *ast.Location, term *ast.Term) [
 		for i := 0; i < v.Len(); i++ {
 			result = append(result, p.listCompletionItemsInTerm(loc, v.Elem(i))...)
 		}
+	case ast.Object:
+		v.Foreach(func(key, value *ast.Term) {
+			result = append(result, p.listCompletionItemsInTerm(loc, key)...)
+			result = append(result, p.listCompletionItemsInTerm(loc, value)...)
+		})
+	case ast.Set:
+		for _, elem := range v.Slice() {
+			result = append(result, p.listCompletionItemsInTerm(loc, elem)...)
+		}
 	case ast.Ref:
 		// skip library name
 		// ```
@@ -286,9 +592,225 @@ func (p *Project) listCompletionItemsInTerm(loc *ast.Location, term *ast.Term) [
 	return result
 }
 
+// listObjectKeys lists the keys of the object literal which is bound to the head of the ref.
+//
+//	x := {"a": 1, "b": 2}
+//	x.
+//	  ^ a, b
+func listObjectKeys(location *ast.Location, target *ast.Term, rule *ast.Rule) []CompletionItem {
+	if target == nil {
+		return nil
+	}
+	ref, ok := target.Value.(ast.Ref)
+	if !ok || len(ref) < 2 {
+		return nil
+	}
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok {
+		return nil
+	}
+
+	result := make([]CompletionItem, 0)
+	for _, b := range rule.Body {
+		if b.Loc().Offset >= target.Loc().Offset {
+			break
+		}
+		if !b.IsAssignment() && !b.IsEquality() {
+			continue
+		}
+
+		terms := b.Operands()
+		var obj ast.Object
+		for i, t := range terms {
+			if !t.Equal(ast.VarTerm(string(head))) {
+				continue
+			}
+			if o, ok := terms[1-i].Value.(ast.Object); ok {
+				obj = o
+			}
+		}
+
+		// x.a.
+		//     ^ lists the keys of x.a
+		for _, r := range ref[1 : len(ref)-1] {
+			if obj == nil {
+				break
+			}
+			v := obj.Get(r)
+			if v == nil {
+				obj = nil
+				break
+			}
+			obj, _ = v.Value.(ast.Object)
+		}
+		if obj == nil {
+			continue
+		}
+
+		obj.Foreach(func(key, value *ast.Term) {
+			k, ok := key.Value.(ast.String)
+			if !ok {
+				return
+			}
+			result = append(result, CompletionItem{
+				Label:    string(k),
+				Kind:     VariableItem,
+				Detail:   valueTypeName(value.Value),
+				TextEdit: createTextEdit(location, string(k)),
+			})
+		})
+	}
+	return result
+}
+
+// listResultFields lists the fields of the object which is returned by the function, when the type of the result is known.
+//
+//	x := parse_config(input)
+//	x.
+//	  ^ the fields of the result of parse_config
+func (p *Project) listResultFields(location *ast.Location, target *ast.Term, rule *ast.Rule) []CompletionItem {
+	if target == nil {
+		return nil
+	}
+	ref, ok := target.Value.(ast.Ref)
+	if !ok || len(ref) < 2 {
+		return nil
+	}
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok {
+		return nil
+	}
+
+	result := make([]CompletionItem, 0)
+	for _, b := range rule.Body {
+		if b.Loc().Offset >= target.Loc().Offset {
+			break
+		}
+		if !b.IsAssignment() && !b.IsEquality() {
+			continue
+		}
+
+		terms := b.Operands()
+		var tpe types.Type
+		for i, t := range terms {
+			call, ok := terms[1-i].Value.(ast.Call)
+			if !ok {
+				continue
+			}
+			if t.Equal(ast.VarTerm(string(head))) {
+				tpe = p.callResultType(call)
+			}
+
+			// [valid, errs] := json.match_schema(x, schema)
+			//         ^ the second element of the result
+			if arr, ok := t.Value.(*ast.Array); ok {
+				for j := 0; j < arr.Len(); j++ {
+					if arr.Elem(j).Equal(ast.VarTerm(string(head))) {
+						tpe = selectType(p.callResultType(call), ast.IntNumberTerm(j))
+					}
+				}
+			}
+		}
+
+		// x.a.
+		//     ^ lists the fields of x.a
+		for _, r := range ref[1 : len(ref)-1] {
+			tpe = selectType(tpe, r)
+		}
+		obj, ok := tpe.(*types.Object)
+		if !ok {
+			continue
+		}
+
+		for _, prop := range obj.StaticProperties() {
+			key, ok := prop.Key.(string)
+			if !ok {
+				continue
+			}
+			result = append(result, CompletionItem{
+				Label:    key,
+				Kind:     VariableItem,
+				Detail:   types.Sprint(prop.Value),
+				TextEdit: createTextEdit(location, key),
+			})
+		}
+	}
+	return result
+}
+
+// selectType returns the type of the element which is selected by the key from the type of the collection.
+//
+//	x.a  -> the property a of the object
+//	x[1] -> the second element of the array
+//	x[_] -> any element of the array
+func selectType(tpe types.Type, key *ast.Term) types.Type {
+	switch t := tpe.(type) {
+	case *types.Object:
+		if s, ok := key.Value.(ast.String); ok {
+			return t.Select(string(s))
+		}
+	case *types.Array:
+		switch k := key.Value.(type) {
+		case ast.Number:
+			if i, ok := k.Int(); ok {
+				return t.Select(i)
+			}
+		case ast.Var:
+			return types.Values(t)
+		}
+	}
+	return nil
+}
+
+// callResultType returns the type of the result of the built-in function or the function which is checked by the compiler.
+func (p *Project) callResultType(call ast.Call) types.Type {
+	op, ok := call[0].Value.(ast.Ref)
+	if !ok {
+		return nil
+	}
+	if b, ok := ast.BuiltinMap[op.String()]; ok {
+		return b.Decl.Result()
+	}
+
+	pkg := p.findPolicyRef(call[0])
+	if pkg == nil {
+		return nil
+	}
+	// is_admin(x) -> is_admin is var, lib.is_admin(x) -> is_admin is string
+	var name string
+	switch v := op[len(op)-1].Value.(type) {
+	case ast.Var:
+		name = string(v)
+	case ast.String:
+		name = string(v)
+	default:
+		return nil
+	}
+	f, ok := p.cache.GetRuleType(pkg.Append(ast.StringTerm(name))).(*types.Function)
+	if !ok {
+		return nil
+	}
+	return f.Result()
+}
+
+// valueTypeName returns the type name of the value like `string` or `object`.
+// The types of the values which are determined at evaluation time are `any`.
+func valueTypeName(v ast.Value) string {
+	switch v.(type) {
+	case ast.Null, ast.Boolean, ast.Number, ast.String, *ast.Array, ast.Object, ast.Set:
+		return ast.TypeName(v)
+	default:
+		return "any"
+	}
+}
+
 func (p *Project) listRules(location *ast.Location, term *ast.Term) []CompletionItem {
 	searchPackageName := p.findPolicyRef(term)
 	if searchPackageName == nil {
+		// x.is has no rules of the current package, because x is not the imported package.
+		if isLibraryTerm(term) {
+			return nil
+		}
 		module := p.GetModule(location.File)
 		if module == nil {
 			return nil
@@ -296,26 +818,91 @@ func (p *Project) listRules(location *ast.Location, term *ast.Term) []Completion
 		searchPackageName = module.Package.Path
 	}
 	searchModules := p.cache.FindPolicies(searchPackageName)
-	if len(searchModules) == 0 {
+	if len(searchModules) == 0 && !isLibraryTerm(term) {
 		return nil
 	}
 
-	return p.listRulesFromModules(location, searchModules)
+	rule := p.findRuleForTerm(location)
+	collectionOnly := rule != nil && isInMembershipCollection(location, rule)
+	result := p.listRulesFromModules(location, searchModules, collectionOnly)
+	// data. has no rules but the top level packages.
+	if isLibraryTerm(term) {
+		result = append(result, p.listChildPackages(location, searchPackageName)...)
+	}
+	return result
 }
 
-func (p *Project) listRulesFromModules(location *ast.Location, modules []*ast.Module) []CompletionItem {
-	exists := make(map[string]CompletionItem)
+// listChildPackages lists the next names of the packages under the parent.
+//
+//	package lib.nested
+//
+//	lib.n
+//	    ^ nested
+func (p *Project) listChildPackages(location *ast.Location, parent ast.Ref) []CompletionItem {
+	exists := make(map[string]struct{})
+	result := make([]CompletionItem, 0)
+	for _, pkg := range p.cache.GetPackages() {
+		if len(pkg) <= len(parent) || !pkg.HasPrefix(parent) {
+			continue
+		}
+		name, ok := pkg[len(parent)].Value.(ast.String)
+		if !ok {
+			continue
+		}
+		if _, ok := exists[string(name)]; ok {
+			continue
+		}
+		exists[string(name)] = struct{}{}
+
+		result = append(result, CompletionItem{
+			Label:    string(name),
+			Kind:     PackageItem,
+			TextEdit: createTextEdit(location, string(name)),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
+// listRulesFromModules lists the rules of the modules.
+// When collectionOnly is true, only the rules which produce collections are listed and they are inserted without their keys.
+func (p *Project) listRulesFromModules(location *ast.Location, modules []*ast.Module, collectionOnly bool) []CompletionItem {
+	// Incremental rules can be defined across files, so sort them to merge their details in a stable order.
+	// The arguments of the first clause like `mem_multiple("E")` are the template of the inserted text.
+	rules := make([]*ast.Rule, 0)
 	for _, m := range modules {
-		for _, r := range m.Rules {
-			item := createRuleCompletionItem(location, r)
-			alreadyItem, ok := exists[item.Label]
-			if !ok {
-				exists[item.Label] = item
+		rules = append(rules, m.Rules...)
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Location.File != rules[j].Location.File {
+			return rules[i].Location.File < rules[j].Location.File
+		}
+		return rules[i].Location.Row < rules[j].Location.Row
+	})
+
+	exists := make(map[string]CompletionItem)
+	for _, r := range rules {
+		if p.isHiddenRule(r) {
+			continue
+		}
+
+		item := createRuleCompletionItem(location, r)
+		if collectionOnly {
+			if !p.isCollectionRule(r) {
 				continue
 			}
-			alreadyItem.Detail += "\n\n" + item.Detail
-			exists[alreadyItem.Label] = alreadyItem
+			item.Kind = VariableItem
+			item.TextEdit = createTextEdit(location, item.Label)
 		}
+		alreadyItem, ok := exists[item.Label]
+		if !ok {
+			exists[item.Label] = item
+			continue
+		}
+		alreadyItem.Detail += "\n\n" + item.Detail
+		exists[alreadyItem.Label] = alreadyItem
 	}
 
 	result := make([]CompletionItem, 0)
@@ -325,6 +912,51 @@ func (p *Project) listRulesFromModules(location *ast.Location, modules []*ast.Mo
 	return result
 }
 
+// isCollectionRule returns true when the rule is tagged as a collection producer by the cache.
+func (p *Project) isCollectionRule(rule *ast.Rule) bool {
+	policy := p.cache.Get(rule.Location.File)
+	if policy == nil {
+		return false
+	}
+	_, ok := policy.CollectionRules[rule.Head.Name.String()]
+	return ok
+}
+
+// isInMembershipCollection returns true when the location is in the collection of `in` operator.
+//
+//	x in co
+//	      ^ location
+func isInMembershipCollection(loc *ast.Location, rule *ast.Rule) bool {
+	found := false
+	check := func(terms []*ast.Term) {
+		if len(terms) == 0 {
+			return
+		}
+		op, ok := terms[0].Value.(ast.Ref)
+		if !ok || (!op.Equal(ast.Member.Ref()) && !op.Equal(ast.MemberWithKey.Ref())) {
+			return
+		}
+		coll := terms[len(terms)-1]
+		if coll.Loc() != nil && in(loc, coll.Loc()) {
+			found = true
+		}
+	}
+
+	ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.Expr:
+			if terms, ok := v.Terms.([]*ast.Term); ok {
+				check(terms)
+			}
+		case ast.Call:
+			// some x in xs -> internal.member_2(x, xs)
+			check(v)
+		}
+		return found
+	}).Walk(rule.Body)
+	return found
+}
+
 func (p *Project) listBuiltinFunctions(location *ast.Location, term *ast.Term) []CompletionItem {
 	if term == nil {
 		return nil
@@ -338,30 +970,83 @@ func (p *Project) listBuiltinFunctions(location *ast.Location, term *ast.Term) [
 				continue
 			}
 			result = append(result, CompletionItem{
-				Label:    b.Name,
+				Label:      b.Name,
+				Kind:       BuiltinFunctionItem,
+				Detail:     createDocForBuiltinFunction(b),
+				TextEdit:   createTextEdit(location, fmt.Sprintf("%s%s", b.Name, b.Decl.FuncArgs().String())),
+				Deprecated: b.IsDeprecated(),
+			})
+		}
+
+		for _, category := range builtinCategories() {
+			result = append(result, CompletionItem{
+				Label:    category,
 				Kind:     BuiltinFunctionItem,
-				Detail:   createDocForBuiltinFunction(b),
-				TextEdit: createTextEdit(location, fmt.Sprintf("%s%s", b.Name, b.Decl.FuncArgs().String())),
+				Detail:   fmt.Sprintf("%s namespace\n\n%s", category, BuiltinDetail),
+				TextEdit: createTextEdit(location, category),
 			})
 		}
 		return result
 	}
 
-	val := ref[0]
+	// crypto.x509.parse_certificates
+	// ^ namespace ^ nested namespace
+	namespace := ref[:len(ref)-1].String()
+	nested := make(map[string]struct{})
 	for _, b := range ast.DefaultBuiltins {
 		if b.Infix != "" {
 			continue
 		}
-		if strings.HasPrefix(b.Name, fmt.Sprintf("%s.", val.Value.String())) {
-			name := strings.TrimLeft(b.Name, fmt.Sprintf("%s.", val.Value.String()))
+		if strings.HasPrefix(b.Name, fmt.Sprintf("%s.", namespace)) {
+			name := strings.TrimPrefix(b.Name, fmt.Sprintf("%s.", namespace))
+			if i := strings.Index(name, "."); i >= 0 {
+				nested[name[:i]] = struct{}{}
+				continue
+			}
 			result = append(result, CompletionItem{
-				Label:    name,
-				Kind:     BuiltinFunctionItem,
-				Detail:   createDocForBuiltinFunction(b),
-				TextEdit: createTextEdit(location, fmt.Sprintf("%s%s", name, b.Decl.FuncArgs().String())),
+				Label:      name,
+				Kind:       BuiltinFunctionItem,
+				Detail:     createDocForBuiltinFunction(b),
+				TextEdit:   createTextEdit(location, fmt.Sprintf("%s%s", name, b.Decl.FuncArgs().String())),
+				Deprecated: b.IsDeprecated(),
 			})
 		}
 	}
+
+	names := make([]string, 0, len(nested))
+	for name := range nested {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, CompletionItem{
+			Label:    name,
+			Kind:     BuiltinFunctionItem,
+			Detail:   fmt.Sprintf("%s.%s namespace\n\n%s", namespace, name, BuiltinDetail),
+			TextEdit: createTextEdit(location, name),
+		})
+	}
+	return result
+}
+
+// builtinCategories returns the namespaces of built-in functions like `crypto` and `time`.
+func builtinCategories() []string {
+	exists := make(map[string]struct{})
+	result := make([]string, 0)
+	for _, b := range ast.DefaultBuiltins {
+		i := strings.Index(b.Name, ".")
+		if b.Infix != "" || i < 0 {
+			continue
+		}
+
+		category := b.Name[:i]
+		if _, ok := exists[category]; ok {
+			continue
+		}
+		exists[category] = struct{}{}
+		result = append(result, category)
+	}
+	sort.Strings(result)
 	return result
 }
 
@@ -412,6 +1097,8 @@ func isLibraryTerm(target *ast.Term) bool {
 	return ok
 }
 
+// createRuleCompletionItem inserts the rule with the arguments of its head like `is_hello(msg)`.
+// The detail is the source of the rule in its own file, so the rules in the other files of the package are listed the same way.
 func createRuleCompletionItem(location *ast.Location, rule *ast.Rule) CompletionItem {
 	head := rule.Head
 	var insertText strings.Builder
@@ -467,20 +1154,44 @@ func importToLabel(imp *ast.Import) string {
 func filterCompletionItems(target *ast.Term, list []CompletionItem) []CompletionItem {
 	termPrefix := getTermPrefix(target)
 
+	// the same rule or variable can be listed more than once, so the items are unique by the label and the kind.
+	type itemKey struct {
+		label string
+		kind  CompletionKind
+	}
 	result := make([]CompletionItem, 0)
-	exist := make(map[string]struct{})
+	exist := make(map[itemKey]int)
 	for _, item := range list {
-		if strings.HasPrefix(item.Label, termPrefix) {
-			if _, ok := exist[item.Label]; !ok {
-				result = append(result, item)
-				exist[item.Label] = struct{}{}
+		if !strings.HasPrefix(item.Label, termPrefix) {
+			continue
+		}
+		key := itemKey{label: item.Label, kind: item.Kind}
+		if i, ok := exist[key]; ok {
+			if hasMoreDetail(item, result[i]) {
+				result[i] = item
 			}
+			continue
 		}
+		exist[key] = len(result)
+		result = append(result, item)
 	}
 
+	// the deprecated built-ins rank below their replacements.
+	sort.SliceStable(result, func(i, j int) bool {
+		return !result[i].Deprecated && result[j].Deprecated
+	})
+
 	return result
 }
 
+// hasMoreDetail returns true when the item has the detail or the text edit which the other doesn't have.
+func hasMoreDetail(item, other CompletionItem) bool {
+	if (item.Detail != "") != (other.Detail != "") {
+		return item.Detail != ""
+	}
+	return item.TextEdit != nil && other.TextEdit == nil
+}
+
 func getTermPrefix(target *ast.Term) string {
 	if target == nil {
 		return ""
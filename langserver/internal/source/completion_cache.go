@@ -0,0 +1,106 @@
+package source
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// completionCache keeps the candidates of the last completion, so the completion requests for the growing word
+// only filter the candidates again instead of walking the AST.
+//
+//	allow {
+//		c
+//		co  -> the candidates of `c` are reused
+//	}
+type completionCache struct {
+	mu sync.Mutex
+
+	path string
+	// start is the offset where the word to be completed starts, which does not move while the word grows.
+	start int
+	// before and after are the text around the word when the candidates are listed.
+	before, after string
+
+	candidates []CompletionItem
+
+	// hits is the number of the lookups which reuse the candidates.
+	hits int
+}
+
+// get returns the candidates which have the word as the prefix, when only the word at the start offset is changed after they are listed.
+func (c *completionCache) get(path string, start int, rawText, word string) ([]CompletionItem, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.candidates == nil || c.path != path || c.start != start {
+		return nil, false
+	}
+	if start+len(word) > len(rawText) || rawText[:start] != c.before || rawText[start+len(word):] != c.after {
+		return nil, false
+	}
+	c.hits++
+
+	result := make([]CompletionItem, 0, len(c.candidates))
+	for _, item := range c.candidates {
+		if strings.HasPrefix(item.Label, word) {
+			result = append(result, item)
+		}
+	}
+	return result, true
+}
+
+func (c *completionCache) put(path string, start int, rawText, word string, candidates []CompletionItem) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if start+len(word) > len(rawText) {
+		c.candidates = nil
+		return
+	}
+	c.path = path
+	c.start = start
+	c.before = rawText[:start]
+	c.after = rawText[start+len(word):]
+	c.candidates = candidates
+}
+
+// invalidate drops the candidates when the other file is changed, because the candidates include the rules of the other files.
+// The change of the same file is checked by the text around the word.
+func (c *completionCache) invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path != path {
+		c.candidates = nil
+	}
+}
+
+// clear drops the candidates regardless of the file, e.g. when the file is deleted or the schema is changed.
+func (c *completionCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.candidates = nil
+}
+
+// listCachedCompletionCandidates returns the candidates from the cache or lists them.
+func (p *Project) listCachedCompletionCandidates(location *ast.Location, target *ast.Term) []CompletionItem {
+	policy := p.cache.Get(location.File)
+	// the candidates at the parse error depend on the broken text, so they are not cached.
+	if policy == nil || len(policy.Errs) > 0 {
+		return p.listCompletionCandidates(location, target)
+	}
+
+	// the location is the start of the word instead of the cursor, so the growing word hits the same candidates.
+	word := getTermPrefix(target)
+	start := location.Offset
+	if list, ok := p.completionCache.get(location.File, start, policy.RawText, word); ok {
+		return list
+	}
+
+	list := p.listCompletionCandidates(location, target)
+	p.completionCache.put(location.File, start, policy.RawText, word, list)
+	return list
+}
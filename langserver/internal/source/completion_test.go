@@ -6,6 +6,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
 )
 
 type completionTestCase struct {
@@ -111,6 +112,35 @@ authorize = "allow" {
 				},
 			},
 		},
+		"Should list variables only in the second else clause": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+authorize(ms_arg) = "allow" {
+	ms_allow := "allow"
+	trace(ms_allow)
+} else = "deny" {
+	ms_deny := "deny"
+	trace(ms_deny)
+} else = "out" {
+	ms_out := ms_arg
+	ms
+}`,
+				},
+			},
+			createLocation: createLocation(11, 3, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "ms_arg",
+					Kind:  source.VariableItem,
+				},
+				{
+					Label: "ms_out",
+					Kind:  source.VariableItem,
+				},
+			},
+		},
 		"Should list rule as single item though the rule args are different": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -141,6 +171,170 @@ mem_multiple("P") = 1000000000000000000`,
 				},
 			},
 		},
+		"Should use the arguments of the first clause across files as the template": {
+			files: map[string]source.File{
+				"b.rego": {
+					RawText: `package src
+
+func() {
+	me
+}
+
+mem_multiple(unit) = 1 {
+	unit == "B"
+}`,
+				},
+				"a.rego": {
+					RawText: `package src
+
+mem_multiple("E") = 1000000000000000000000`,
+				},
+			},
+			createLocation: createLocation(4, 3, "b.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "mem_multiple",
+					Kind:  source.FunctionItem,
+					TextEdit: &source.TextEdit{
+						Row:  4,
+						Col:  2,
+						Text: `mem_multiple("E")`,
+					},
+					Detail: `mem_multiple("E") = 1000000000000000000000
+
+mem_multiple(unit) = 1 {
+	unit == "B"
+}`,
+				},
+			},
+		},
+		"Should list the function in the other file with its arguments and source": {
+			files: map[string]source.File{
+				"b.rego": {
+					RawText: `package src
+
+func() {
+	can
+}`,
+				},
+				"a.rego": {
+					RawText: `package src
+
+# 管理者だけが削除できる
+can_access(user, action) {
+	user.role == "admin"
+	action != "delete"
+}`,
+				},
+			},
+			createLocation: createLocation(4, 4, "b.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "can_access",
+					Kind:  source.FunctionItem,
+					TextEdit: &source.TextEdit{
+						Row:  4,
+						Col:  2,
+						Text: "can_access(user, action)",
+					},
+					Detail: `can_access(user, action) {
+	user.role == "admin"
+	action != "delete"
+}`,
+				},
+			},
+		},
+		"Should list incremental rule defined across files with all details": {
+			files: map[string]source.File{
+				"b.rego": {
+					RawText: `package src
+
+func() {
+	de
+}
+
+deny = "b"`,
+				},
+				"a.rego": {
+					RawText: `package src
+
+deny = "a"`,
+				},
+			},
+			createLocation: createLocation(4, 3, "b.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "deny",
+					Kind:  source.VariableItem,
+					TextEdit: &source.TextEdit{
+						Row:  4,
+						Col:  2,
+						Text: "deny",
+					},
+					Detail: `deny = "a"
+
+deny = "b"`,
+				},
+			},
+		},
+		"Should list self-referential incremental rule with each detail once": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+paths[p] {
+	p := input.path
+}
+
+paths[p] {
+	paths[q]
+	p := concat("/", [q, "child"])
+}
+
+func() {
+	pa
+}`,
+				},
+			},
+			createLocation: createLocation(13, 3, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "paths",
+					Kind:  source.FunctionItem,
+					TextEdit: &source.TextEdit{
+						Row:  13,
+						Col:  2,
+						Text: "paths[p]",
+					},
+					Detail: `paths[p] {
+	p := input.path
+}
+
+paths[p] {
+	paths[q]
+	p := concat("/", [q, "child"])
+}`,
+				},
+			},
+		},
+		"Should not list variables declared by every out of its body": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+import future.keywords.every
+
+allow {
+	every key, value in input.list {
+		value > 0
+	}
+	ke
+}`,
+				},
+			},
+			createLocation: createLocation(9, 3, "main.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
 		"Should not list duplicated variables": {
 			files: map[string]source.File{
 				"main.rego": {
@@ -157,383 +351,1904 @@ violation[msg] {
 				{Label: "msg", Kind: source.VariableItem},
 			},
 		},
-		"Should list package items when the file is empty and location from client is something wrong": {
+		"Should list variables in the set literal of the unification": {
 			files: map[string]source.File{
-				"test-test/core.rego": {
-					RawText: ``,
+				"main.rego": {
+					RawText: `package main
+
+allow {
+	{"admin", role_name} = {"admin", "viewer"}
+	role_
+}`,
 				},
 			},
-			createLocation: createLocation(1, 0, "test-test/core.rego"),
+			createLocation: createLocation(5, 6, "main.rego"),
 			expectItems: []source.CompletionItem{
-				{Label: "package test_test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test_test"}},
-				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
-				{Label: "package test_test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test_test.core"}},
+				{Label: "role_name", Kind: source.VariableItem},
 			},
 		},
-		"Should list package items when the file is empty": {
+		"Should list variables in the object literal of the assignment": {
 			files: map[string]source.File{
-				"test/core.rego": {
-					RawText: ``,
+				"main.rego": {
+					RawText: `package main
+
+allow {
+	{"user": user_name, "roles": [user_role]} := input
+	user_
+}`,
 				},
 			},
-			createLocation: createLocation(1, 1, "test/core.rego"),
+			createLocation: createLocation(5, 6, "main.rego"),
 			expectItems: []source.CompletionItem{
-				{Label: "package test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test"}},
-				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
-				{Label: "package test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test.core"}},
+				{Label: "user_role", Kind: source.VariableItem},
+				{Label: "user_name", Kind: source.VariableItem},
 			},
 		},
-		"Should list package items when the file has no package": {
+		"Should list else keyword after the rule body": {
 			files: map[string]source.File{
-				"test/core.rego": {
-					RawText: `p`,
+				"src.rego": {
+					RawText: `package src
+
+allow = true {
+	input.admin
+}`,
 				},
 			},
-			createLocation: createLocation(1, 1, "test/core.rego"),
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow = true {
+	input.admin
+} el`,
+				},
+			},
+			createLocation: createLocation(5, 4, "src.rego"),
 			expectItems: []source.CompletionItem{
-				{Label: "package test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test"}},
-				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
-				{Label: "package test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test.core"}},
+				{
+					Label: "else",
+					Kind:  source.KeywordItem,
+					TextEdit: &source.TextEdit{
+						Row:  5,
+						Col:  3,
+						Text: "else",
+					},
+				},
+				{
+					Label:  "else { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  5,
+						Col:  3,
+						Text: "else {\n\t${1:true}\n}",
+					},
+				},
+				{
+					Label:  "else = value { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else = <value> { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  5,
+						Col:  3,
+						Text: "else = ${1:value} {\n\t${2:true}\n}",
+					},
+				},
 			},
 		},
-		`Should list package items which remove "_test"`: {
+		"Should list the other items instead of else keyword which does not match the word after the rule body": {
 			files: map[string]source.File{
-				"aaa/bbb_test.rego": {
-					RawText: `p`,
+				"src.rego": {
+					RawText: `package src
+
+allow = true {
+	input.admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib`,
 				},
 			},
-			createLocation: createLocation(1, 1, "aaa/bbb_test.rego"),
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow = true {
+	input.admin
+} i`,
+				},
+			},
+			createLocation: createLocation(5, 3, "src.rego"),
 			expectItems: []source.CompletionItem{
-				{Label: "package aaa", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa"}},
-				{Label: "package bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package bbb"}},
-				{Label: "package aaa.bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa.bbb"}},
+				{
+					Label: "import data.lib",
+					Kind:  source.ImportItem,
+					TextEdit: &source.TextEdit{
+						Row:  5,
+						Col:  1,
+						Text: "import data.lib",
+					},
+				},
 			},
 		},
-	}
-
-	for n, tt := range tests {
-		t.Run(n, func(t *testing.T) {
-			project, err := source.NewProjectWithFiles(tt.files)
-			if err != nil {
-				t.Fatal(err)
-			}
+		"Should list else keyword and snippets after the else clause": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
 
-			for path, file := range tt.updateFile {
-				err := project.UpdateFile(path, file.RawText, file.Version)
-				if err != nil {
-					t.Fatal(err)
-				}
-			}
+allow = true {
+	input.admin
+} else = false {
+	input.guest
+}`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
 
-			location := tt.createLocation(tt.files)
-			got, err := project.ListCompletionItems(location)
-			if err != nil {
-				t.Fatal(err)
-			}
+allow = true {
+	input.admin
+} else = false {
+	input.guest
+} el`,
+				},
+			},
+			createLocation: createLocation(7, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "else",
+					Kind:  source.KeywordItem,
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  3,
+						Text: "else",
+					},
+				},
+				{
+					Label:  "else { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  3,
+						Text: "else {\n\t${1:true}\n}",
+					},
+				},
+				{
+					Label:  "else = value { ... }",
+					Kind:   source.SnippetItem,
+					Detail: "else = <value> { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  3,
+						Text: "else = ${1:value} {\n\t${2:true}\n}",
+					},
+				},
+			},
+		},
+		"Should not list else keyword in the rule body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
 
-			if diff := cmp.Diff(tt.expectItems, got, cmp.Comparer(func(x, y source.CompletionItem) bool {
-				return reflect.DeepEqual(x, y)
-			})); diff != "" {
-				t.Errorf("ListCompletionItems result diff (-expect, +got)\n%s", diff)
+allow = true {
+	input.admin
+	el
+}`,
+				},
+			},
+			createLocation: createLocation(5, 3, "src.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
+		"Should list the partial set rule without its key as the collection of in": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.in
+
+deny[msg] {
+	msg := "denied"
+}
+
+default_name := "a"
+
+allow {
+	"a" in de
+}`,
+				},
+			},
+			createLocation: createLocation(12, 10, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "deny",
+					Kind:   source.VariableItem,
+					Detail: "deny[msg] {\n\tmsg := \"denied\"\n}",
+					TextEdit: &source.TextEdit{
+						Row:  12,
+						Col:  9,
+						Text: "deny",
+					},
+				},
+			},
+		},
+		"Should list rules of the nested package of the imported package": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.nested.i
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+				"nested.rego": {
+					RawText: `package lib.nested
+
+is_nested(msg) {
+	msg == "nested"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 13, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "is_nested",
+					Kind:  source.FunctionItem,
+					TextEdit: &source.TextEdit{
+						Row:  6,
+						Col:  13,
+						Text: "is_nested(msg)",
+					},
+					Detail: "is_nested(msg) {\n\tmsg == \"nested\"\n}",
+				},
+			},
+		},
+		"Should list rules and nested packages after the imported package": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.i
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+				"nested.rego": {
+					RawText: `package lib.nested`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.
+}`,
+				},
+			},
+			createLocation: createLocation(6, 5, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label: "is_hello",
+					Kind:  source.FunctionItem,
+					TextEdit: &source.TextEdit{
+						Row:  6,
+						Col:  6,
+						Text: "is_hello(msg)",
+					},
+					Detail: "is_hello(msg) {\n\tmsg == \"hello\"\n}",
+				},
+				{
+					Label: "nested",
+					Kind:  source.PackageItem,
+					TextEdit: &source.TextEdit{
+						Row:  6,
+						Col:  6,
+						Text: "nested",
+					},
+				},
+			},
+		},
+		"Should not list fields of the built-in result whose type is any": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	y := json.unmarshal(input.body)
+	y.zz
+}`,
+				},
+			},
+			createLocation: createLocation(5, 5, "src.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
+		"Should list only the rules of the imported package after the dotted prefix": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+import data.lib
+
+is_admin {
+	input.admin
+}
+
+violation[msg] {
+	lib.is
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello {
+	input.msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(10, 7, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "is_hello",
+					Kind:     source.VariableItem,
+					TextEdit: &source.TextEdit{Row: 10, Col: 6, Text: "is_hello"},
+					Detail:   "is_hello {\n\tinput.msg == \"hello\"\n}",
+				},
+			},
+		},
+		"Should not list the rules of the current package after the ref which is not the package": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+is_admin {
+	input.admin
+}
+
+violation[msg] {
+	user := input.user
+	user.is
+}`,
+				},
+			},
+			createLocation: createLocation(9, 8, "main.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
+		"Should list the function which has multiple clauses once": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+mem_multiple("E") = 1000000000000000000000
+
+mem_multiple("P") = 1000000000000000000
+
+allow {
+	mem
+}`,
+				},
+			},
+			createLocation: createLocation(8, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "mem_multiple",
+					Kind:     source.FunctionItem,
+					TextEdit: &source.TextEdit{Row: 8, Col: 2, Text: "mem_multiple(\"E\")"},
+					Detail:   "mem_multiple(\"E\") = 1000000000000000000000\n\nmem_multiple(\"P\") = 1000000000000000000",
+				},
+			},
+		},
+		"Should prefer the item which has the detail when the items have the same label and kind": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+name := "admin"
+
+allow {
+	name := input.name
+	nam
+}`,
+				},
+			},
+			createLocation: createLocation(7, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "name",
+					Kind:     source.VariableItem,
+					TextEdit: &source.TextEdit{Row: 7, Col: 2, Text: "name"},
+					Detail:   `name := "admin"`,
+				},
+			},
+		},
+		"Should list the top level packages after data": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+violation[msg] {
+	data
+}`,
+				},
+				"auth.rego": {
+					RawText: `package lib.auth`,
+				},
+				"util.rego": {
+					RawText: `package lib.util`,
+				},
+				"other.rego": {
+					RawText: `package other`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+violation[msg] {
+	data.
+}`,
+				},
+			},
+			createLocation: createLocation(4, 6, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "lib", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 4, Col: 7, Text: "lib"}},
+				{Label: "main", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 4, Col: 7, Text: "main"}},
+				{Label: "other", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 4, Col: 7, Text: "other"}},
+			},
+		},
+		"Should list the packages under the path from data": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+violation[msg] {
+	data.lib.a
+}`,
+				},
+				"auth.rego": {
+					RawText: `package lib.auth`,
+				},
+				"util.rego": {
+					RawText: `package lib.util`,
+				},
+			},
+			createLocation: createLocation(4, 11, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "auth", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 4, Col: 11, Text: "auth"}},
+			},
+		},
+		"Should list the rules of the package from data": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+violation[msg] {
+	data.lib.auth.i
+}`,
+				},
+				"auth.rego": {
+					RawText: `package lib.auth
+
+is_admin {
+	input.admin
+}`,
+				},
+			},
+			createLocation: createLocation(4, 16, "main.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "is_admin",
+					Kind:     source.VariableItem,
+					TextEdit: &source.TextEdit{Row: 4, Col: 16, Text: "is_admin"},
+					Detail:   "is_admin {\n\tinput.admin\n}",
+				},
+			},
+		},
+		"Should list the rule names at the top level to add the incremental definition": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	msg := "bad"
+}
+
+`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	msg := "bad"
+}
+
+viol`,
+				},
+			},
+			createLocation: createLocation(7, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "violation",
+					Kind:   source.FunctionItem,
+					Detail: "violation[msg] {\n\tmsg := \"bad\"\n}",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "violation",
+					},
+				},
+			},
+		},
+		"Should not list the rule whose name is being typed": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+
+allo {
+	true
+}`,
+				},
+			},
+			createLocation: createLocation(7, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "allow",
+					Kind:   source.VariableItem,
+					Detail: "allow {\n\ttrue\n}",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "allow",
+					},
+				},
+			},
+		},
+		"Should list the rule of the same name which is defined in the other file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allo {
+	true
+}`,
+				},
+				"other.rego": {
+					RawText: `package src
+
+allo {
+	false
+}`,
+				},
+			},
+			createLocation: createLocation(3, 4, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "allo",
+					Kind:   source.VariableItem,
+					Detail: "allo {\n\tfalse\n}",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  1,
+						Text: "allo",
+					},
+				},
+			},
+		},
+		"Should list default keyword at the top level": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+
+de`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "default",
+					Kind:   source.KeywordItem,
+					Detail: "default <name> = <value>",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "default ${1:name} = ${2:value}",
+					},
+				},
+				{
+					Label:  "deny",
+					Kind:   source.SnippetItem,
+					Detail: "deny[msg] { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "deny[msg] {\n\t${1:true}\n\tmsg := ${2:\"message\"}\n}",
+					},
+				},
+				{
+					Label:  "default allow",
+					Kind:   source.SnippetItem,
+					Detail: "default allow = false",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "default allow = ${1:false}",
+					},
+				},
+			},
+		},
+		"Should list only the default snippets which match the longer word at the top level": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+
+`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+
+def`,
+				},
+			},
+			createLocation: createLocation(7, 3, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "default",
+					Kind:   source.KeywordItem,
+					Detail: "default <name> = <value>",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "default ${1:name} = ${2:value}",
+					},
+				},
+				{
+					Label:  "default allow",
+					Kind:   source.SnippetItem,
+					Detail: "default allow = false",
+					TextEdit: &source.TextEdit{
+						Row:  7,
+						Col:  1,
+						Text: "default allow = ${1:false}",
+					},
+				},
+			},
+		},
+		"Should list rule snippets which match the word at the top level": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+d`,
+				},
+			},
+			createLocation: createLocation(3, 1, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "default",
+					Kind:   source.KeywordItem,
+					Detail: "default <name> = <value>",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  1,
+						Text: "default ${1:name} = ${2:value}",
+					},
+				},
+				{
+					Label:  "deny",
+					Kind:   source.SnippetItem,
+					Detail: "deny[msg] { ... }",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  1,
+						Text: "deny[msg] {\n\t${1:true}\n\tmsg := ${2:\"message\"}\n}",
+					},
+				},
+				{
+					Label:  "default allow",
+					Kind:   source.SnippetItem,
+					Detail: "default allow = false",
+					TextEdit: &source.TextEdit{
+						Row:  3,
+						Col:  1,
+						Text: "default allow = ${1:false}",
+					},
+				},
+			},
+		},
+		"Should not list default keyword in the rule body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	de
+}`,
+				},
+			},
+			createLocation: createLocation(4, 3, "src.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
+		"Should list metadata annotation keys which are not written yet": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+# METADATA
+# title: Allow
+# d
+allow {
+	true
+}`,
+				},
+			},
+			createLocation: createLocation(5, 3, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:  "description",
+					Kind:   source.AnnotationItem,
+					Detail: "the description of the package or the rule",
+					TextEdit: &source.TextEdit{
+						Row:  5,
+						Col:  3,
+						Text: "description: ",
+					},
+				},
+			},
+		},
+		"Should list all metadata annotation keys after METADATA": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+# METADATA
+# 
+allow {
+	true
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "title", Kind: source.AnnotationItem, Detail: "the name of the package or the rule", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "title: "}},
+				{Label: "description", Kind: source.AnnotationItem, Detail: "the description of the package or the rule", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "description: "}},
+				{Label: "scope", Kind: source.AnnotationItem, Detail: "rule, document, package or subpackages", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "scope: "}},
+				{Label: "schemas", Kind: source.AnnotationItem, Detail: "the schemas of input and data", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "schemas: "}},
+				{Label: "authors", Kind: source.AnnotationItem, Detail: "the authors with the name and the email", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "authors: "}},
+				{Label: "related_resources", Kind: source.AnnotationItem, Detail: "the links which are related to the policy", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "related_resources: "}},
+				{Label: "organizations", Kind: source.AnnotationItem, Detail: "the organizations which are related to the policy", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "organizations: "}},
+				{Label: "entrypoint", Kind: source.AnnotationItem, Detail: "whether the rule is the entrypoint of the policy", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "entrypoint: "}},
+				{Label: "custom", Kind: source.AnnotationItem, Detail: "the map of the arbitrary data", TextEdit: &source.TextEdit{Row: 4, Col: 3, Text: "custom: "}},
+			},
+		},
+		"Should list package items when the file is empty and location from client is something wrong": {
+			files: map[string]source.File{
+				"test-test/core.rego": {
+					RawText: ``,
+				},
+			},
+			createLocation: createLocation(1, 0, "test-test/core.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package test_test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test_test"}},
+				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
+				{Label: "package test_test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test_test.core"}},
+			},
+		},
+		"Should list package items when the file is empty": {
+			files: map[string]source.File{
+				"test/core.rego": {
+					RawText: ``,
+				},
+			},
+			createLocation: createLocation(1, 1, "test/core.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test"}},
+				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
+				{Label: "package test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test.core"}},
+			},
+		},
+		"Should list package items when the file has no package": {
+			files: map[string]source.File{
+				"test/core.rego": {
+					RawText: `p`,
+				},
+			},
+			createLocation: createLocation(1, 1, "test/core.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test"}},
+				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
+				{Label: "package test.core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package test.core"}},
+			},
+		},
+		"Should list package items when the file is at the root": {
+			files: map[string]source.File{
+				"core.rego": {
+					RawText: ``,
+				},
+			},
+			createLocation: createLocation(1, 1, "core.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package core", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core"}},
+			},
+		},
+		"Should list package items when the file name has dots": {
+			files: map[string]source.File{
+				"authz/core.v1.rego": {
+					RawText: ``,
+				},
+			},
+			createLocation: createLocation(1, 1, "authz/core.v1.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package authz", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package authz"}},
+				{Label: "package core.v1", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package core.v1"}},
+				{Label: "package authz.core.v1", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package authz.core.v1"}},
+			},
+		},
+		"Should not list package items when the file name cannot be the package name": {
+			files: map[string]source.File{
+				"v1.2.rego": {
+					RawText: ``,
+				},
+			},
+			createLocation: createLocation(1, 1, "v1.2.rego"),
+			expectItems:    []source.CompletionItem{},
+		},
+		"Should list package items of the sibling test files first": {
+			files: map[string]source.File{
+				"aaa/bbb_test.rego": {
+					RawText: `p`,
+				},
+				"aaa/helper_test.rego": {
+					RawText: `package aaa.bbb_test`,
+				},
+			},
+			createLocation: createLocation(1, 1, "aaa/bbb_test.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package aaa.bbb_test", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa.bbb_test"}},
+				{Label: "package aaa", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa"}},
+				{Label: "package bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package bbb"}},
+				{Label: "package aaa.bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa.bbb"}},
+			},
+		},
+		`Should list package items which remove "_test"`: {
+			files: map[string]source.File{
+				"aaa/bbb_test.rego": {
+					RawText: `p`,
+				},
+			},
+			createLocation: createLocation(1, 1, "aaa/bbb_test.rego"),
+			expectItems: []source.CompletionItem{
+				{Label: "package aaa", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa"}},
+				{Label: "package bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package bbb"}},
+				{Label: "package aaa.bbb", Kind: source.PackageItem, TextEdit: &source.TextEdit{Row: 1, Col: 1, Text: "package aaa.bbb"}},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for path, file := range tt.updateFile {
+				err := project.UpdateFile(path, file.RawText, file.Version)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.ListCompletionItems(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectItems, got, cmp.Comparer(func(x, y source.CompletionItem) bool {
+				return reflect.DeepEqual(x, y)
+			})); diff != "" {
+				t.Errorf("ListCompletionItems result diff (-expect, +got)\n%s", diff)
 			}
 		})
 	}
 }
 
-func TestProject_ListCompletionItemsExist(t *testing.T) {
-	tests := map[string]map[string]completionTestCase{
-		"List variables": {
-			"Should list variables in the same rule": {
+func TestProject_ListCompletionItemsExist(t *testing.T) {
+	tests := map[string]map[string]completionTestCase{
+		"List variables": {
+			"Should list variables in the same rule": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	ms := hoge(fuga)
+	messages[message]
+	m
+}`,
+					},
+				},
+				createLocation: createLocation(6, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "msg", Kind: source.VariableItem},
+					{Label: "ms", Kind: source.VariableItem},
+					{Label: "message", Kind: source.VariableItem},
+				},
+			},
+			"Should list variables bound before the location in the comprehension": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	ids := [id | role := input.roles[_]; id := ro]
+	msg := ids[0]
+}`,
+					},
+				},
+				createLocation: createLocation(4, 46, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "role", Kind: source.VariableItem},
+				},
+			},
+			"Should list variables of the rule in the comprehension": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	user := input.user
+	ids := [id |
+		id := u
+	]
+	msg := ids[0]
+}`,
+					},
+				},
+				createLocation: createLocation(6, 9, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "user", Kind: source.VariableItem},
+				},
+			},
+			"Should list variables bound before the location in the comprehension of the rule head": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+names := {name | user := input.users[_]; name := us}`,
+					},
+				},
+				createLocation: createLocation(3, 51, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "user", Kind: source.VariableItem},
+				},
+			},
+			"Should list variables declared by some": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	some user
+	user == input.user
+	msg := u
+}`,
+					},
+				},
+				createLocation: createLocation(6, 9, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "user", Kind: source.VariableItem},
+				},
+			},
+			"Should list both key and value declared by some in": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import future.keywords.in
+
+violation[msg] {
+	some index, value in input.list
+	msg := sprintf("%d: %s", [i, v])
+}`,
+					},
+				},
+				createLocation: createLocation(7, 28, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "index", Kind: source.VariableItem},
+				},
+			},
+			"Should list value declared by some in": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import future.keywords.in
+
+violation[msg] {
+	some index, value in input.list
+	msg := sprintf("%d: %s", [i, v])
+}`,
+					},
+				},
+				createLocation: createLocation(7, 31, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "value", Kind: source.VariableItem},
+				},
+			},
+			"Should list key and value declared by every in its body": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import future.keywords.every
+
+allow {
+	every key, value in input.list {
+		name := key
+		value == n
+	}
+}`,
+					},
+				},
+				createLocation: createLocation(8, 12, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "name", Kind: source.VariableItem},
+				},
+			},
+			"Should list key declared by every in its body": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import future.keywords.every
+
+allow {
+	every key, value in input.list {
+		value == k
+	}
+}`,
+					},
+				},
+				createLocation: createLocation(7, 12, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "key", Kind: source.VariableItem},
+				},
+			},
+			"Should list imported variables": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	l
+}`,
+					},
+				},
+				createLocation: createLocation(6, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "lib", Kind: source.PackageItem},
+				},
+			},
+			"Should list imported variables without import statement": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	l
+}`,
+					},
+					"lib.rego": {
+						RawText: `package lib`,
+					},
+				},
+				createLocation: createLocation(4, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "lib",
+						Kind:  source.PackageItem,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "lib",
+						},
+						AdditionalTextEdits: []source.TextEdit{
+							{
+								Row:  2,
+								Col:  1,
+								Text: "\nimport data.lib\n",
+							},
+						},
+					},
+				},
+			},
+			"Should list imported variables without import statement with other imported statement": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import data.hoge
+
+violation[msg] {
+	l
+}`,
+					},
+					"lib.rego": {
+						RawText: `package lib`,
+					},
+				},
+				createLocation: createLocation(6, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "lib",
+						Kind:  source.PackageItem,
+						TextEdit: &source.TextEdit{
+							Row:  6,
+							Col:  2,
+							Text: "lib",
+						},
+						AdditionalTextEdits: []source.TextEdit{
+							{
+								Row:  4,
+								Col:  1,
+								Text: "import data.lib\n",
+							},
+						},
+					},
+				},
+			},
+			"Should list variables when the prefix text is none": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	msg = "hello"
+
+}`,
+					},
+				},
+				createLocation: createLocation(5, 1, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "msg", Kind: source.VariableItem},
+				},
+			},
+		},
+		"List rules": {
+			"Should list rules in the same file": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation [msg] {
+	i
+}
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+				createLocation: createLocation(4, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "is_hello",
+						Kind:  source.FunctionItem,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "is_hello(msg)",
+						},
+						Detail: `is_hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+			},
+			"Should list rules in the same package but other file": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation [msg] {
+	he
+}`,
+					},
+					"other.rego": {
+						RawText: `package main
+
+hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+				createLocation: createLocation(4, 3, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "hello",
+						Kind:  source.FunctionItem,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "hello(msg)",
+						},
+						Detail: `hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+			},
+			"Should list rules in the other packages": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+import data.lib
+
+violation [msg] {
+	lib.i
+}`,
+					},
+					"lib.rego": {
+						RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+				createLocation: createLocation(6, 6, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "is_hello",
+						Kind:  source.FunctionItem,
+						TextEdit: &source.TextEdit{
+							Row:  6,
+							Col:  6,
+							Text: "is_hello(msg)",
+						},
+						Detail: `is_hello(msg) {
+	msg == "hello"
+}`,
+					},
+				},
+			},
+			"Should list built-in functions": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	j
+}`,
+					},
+				},
+				createLocation: createLocation(4, 2, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:  "json.patch",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "json.patch(any, array[object<op: string, path: any>[any: any]])\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "json.patch(any, array[object<op: string, path: any>[any: any]])",
+						},
+					},
+				},
+			},
+			"Should list built-in functions when prefix include `.` character": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	json.p
+}`,
+					},
+				},
+				createLocation: createLocation(4, 7, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:  "patch",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "json.patch(any, array[object<op: string, path: any>[any: any]])\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  7,
+							Text: "patch(any, array[object<op: string, path: any>[any: any]])",
+						},
+					},
+				},
+			},
+			"Should list built-in namespace": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	cry
+}`,
+					},
+				},
+				createLocation: createLocation(4, 4, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:  "crypto",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "crypto namespace\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "crypto",
+						},
+					},
+				},
+			},
+			"Should list built-in functions in crypto namespace": {
+				files: map[string]source.File{
+					"main.rego": {
+						RawText: `package main
+
+violation[msg] {
+	crypto.sha
+}`,
+					},
+				},
+				createLocation: createLocation(4, 11, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:  "sha256",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "crypto.sha256(string)\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  9,
+							Text: "sha256(string)",
+						},
+					},
+				},
+			},
+			"Should list nested built-in namespace": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
 violation[msg] {
-	ms := hoge(fuga)
-	messages[message]
-	m
+	crypto.x
 }`,
 					},
 				},
-				createLocation: createLocation(6, 2, "main.rego"),
+				createLocation: createLocation(4, 9, "main.rego"),
 				expectItems: []source.CompletionItem{
-					{Label: "msg", Kind: source.VariableItem},
-					{Label: "ms", Kind: source.VariableItem},
-					{Label: "message", Kind: source.VariableItem},
+					{
+						Label:  "x509",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "crypto.x509 namespace\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  9,
+							Text: "x509",
+						},
+					},
 				},
 			},
-			"Should list imported variables": {
+			"Should list built-in functions in nested namespace": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
-import data.lib
-
 violation[msg] {
-	l
+	crypto.x509.parse_rsa
 }`,
 					},
 				},
-				createLocation: createLocation(6, 2, "main.rego"),
+				createLocation: createLocation(4, 22, "main.rego"),
 				expectItems: []source.CompletionItem{
-					{Label: "lib", Kind: source.PackageItem},
+					{
+						Label:  "parse_rsa_private_key",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "crypto.x509.parse_rsa_private_key(string)\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  14,
+							Text: "parse_rsa_private_key(string)",
+						},
+					},
 				},
 			},
-			"Should list imported variables without import statement": {
+			"Should list built-in functions in time namespace": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
 violation[msg] {
-	l
+	time.n
 }`,
 					},
-					"lib.rego": {
-						RawText: `package lib`,
-					},
 				},
-				createLocation: createLocation(4, 2, "main.rego"),
+				createLocation: createLocation(4, 7, "main.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "lib",
-						Kind:  source.PackageItem,
+						Label:  "now_ns",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "time.now_ns()\n\n" + source.BuiltinDetail,
 						TextEdit: &source.TextEdit{
 							Row:  4,
-							Col:  2,
-							Text: "lib",
-						},
-						AdditionalTextEdits: []source.TextEdit{
-							{
-								Row:  2,
-								Col:  1,
-								Text: "\nimport data.lib\n",
-							},
+							Col:  7,
+							Text: "now_ns()",
 						},
 					},
 				},
 			},
-			"Should list imported variables without import statement with other imported statement": {
+			"Should list built-in functions whose name starts with the namespace characters": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
-import data.hoge
+violation[msg] {
+	strings.r
+}`,
+					},
+				},
+				createLocation: createLocation(4, 10, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:  "reverse",
+						Kind:   source.BuiltinFunctionItem,
+						Detail: "strings.reverse(string)\n\n" + source.BuiltinDetail,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  10,
+							Text: "reverse(string)",
+						},
+					},
+				},
+			},
+			"Should list rule which is variable": {
+				files: map[string]source.File{
+					"src.rego": {
+						RawText: `package src
 
 violation[msg] {
-	l
+	is
+}
+
+default is_test = true`,
+					},
+				},
+				createLocation: createLocation(4, 3, "src.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "is_test",
+						Kind:  source.VariableItem,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "is_test",
+						},
+						Detail: "default is_test = true",
+					},
+				},
+			},
+		},
+		"List test helpers": {
+			"Should list helper rule defined in the sibling test file": {
+				files: map[string]source.File{
+					"src/main_test.rego": {
+						RawText: `package main_test
+
+test_allow {
+	mock_
 }`,
 					},
-					"lib.rego": {
-						RawText: `package lib`,
+					"src/helper_test.rego": {
+						RawText: `package main_test
+
+mock_input := {"user": "admin"}`,
 					},
 				},
-				createLocation: createLocation(6, 2, "main.rego"),
+				createLocation: createLocation(4, 6, "src/main_test.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "lib",
-						Kind:  source.PackageItem,
+						Label: "mock_input",
+						Kind:  source.VariableItem,
 						TextEdit: &source.TextEdit{
-							Row:  6,
+							Row:  4,
 							Col:  2,
-							Text: "lib",
+							Text: "mock_input",
 						},
-						AdditionalTextEdits: []source.TextEdit{
-							{
-								Row:  4,
-								Col:  1,
-								Text: "import data.lib\n",
-							},
+						Detail: `mock_input := {"user": "admin"}`,
+					},
+				},
+			},
+			"Should list helper rule defined in the sibling test file of the same package": {
+				files: map[string]source.File{
+					"src/main_test.rego": {
+						RawText: `package main
+
+test_allow {
+	mock_
+}`,
+					},
+					"src/helper_test.rego": {
+						RawText: `package main
+
+mock_input := {"user": "admin"}`,
+					},
+				},
+				createLocation: createLocation(4, 6, "src/main_test.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label: "mock_input",
+						Kind:  source.VariableItem,
+						TextEdit: &source.TextEdit{
+							Row:  4,
+							Col:  2,
+							Text: "mock_input",
 						},
+						Detail: `mock_input := {"user": "admin"}`,
 					},
 				},
 			},
-			"Should list variables when the prefix text is none": {
+		},
+		"List negated membership": {
+			"Should list membership snippet after not": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
-violation[msg] {
-	msg = "hello"
+import future.keywords.in
 
+allow {
+	mock := ["admin"]
+	not m
 }`,
 					},
 				},
-				createLocation: createLocation(5, 1, "main.rego"),
+				createLocation: createLocation(7, 6, "main.rego"),
 				expectItems: []source.CompletionItem{
-					{Label: "msg", Kind: source.VariableItem},
+					{Label: "mock", Kind: source.VariableItem},
+					{
+						Label:  "m in coll",
+						Kind:   source.SnippetItem,
+						Detail: "not m in coll",
+						TextEdit: &source.TextEdit{
+							Row:  7,
+							Col:  6,
+							Text: "m in ${1:coll}",
+						},
+					},
 				},
 			},
-		},
-		"List rules": {
-			"Should list rules in the same file": {
+			"Should list membership snippet just after not": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
-violation [msg] {
-	i
-}
-
-is_hello(msg) {
-	msg == "hello"
+allow {
+	input.user == "admin"
+	not 
 }`,
 					},
 				},
-				createLocation: createLocation(4, 2, "main.rego"),
+				createLocation: createLocation(5, 6, "main.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "is_hello",
-						Kind:  source.FunctionItem,
+						Label:  "x in coll",
+						Kind:   source.SnippetItem,
+						Detail: "not x in coll",
 						TextEdit: &source.TextEdit{
-							Row:  4,
-							Col:  2,
-							Text: "is_hello(msg)",
+							Row:  5,
+							Col:  6,
+							Text: "${1:x} in ${2:coll}",
 						},
-						Detail: `is_hello(msg) {
-	msg == "hello"
+					},
+				},
+			},
+		},
+		"List with value": {
+			"Should list variables declared in the rule": {
+				files: map[string]source.File{
+					"main_test.rego": {
+						RawText: `package main
+
+test_allow {
+	mock := {"user": "admin"}
+	allow with input as m
 }`,
 					},
 				},
+				createLocation: createLocation(5, 22, "main_test.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "mock", Kind: source.VariableItem},
+				},
 			},
-			"Should list rules in the same package but other file": {
+			"Should list variables declared before the with clause in the same line": {
 				files: map[string]source.File{
-					"main.rego": {
+					"main_test.rego": {
 						RawText: `package main
 
-violation [msg] {
-	he
+test_allow {
+	mock := {"user": "admin"}; allow with input as m
 }`,
 					},
-					"other.rego": {
+				},
+				createLocation: createLocation(4, 49, "main_test.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "mock", Kind: source.VariableItem},
+				},
+			},
+			"Should list input and data": {
+				files: map[string]source.File{
+					"main_test.rego": {
 						RawText: `package main
 
-hello(msg) {
-	msg == "hello"
+test_allow {
+	allow with input as i with data.users as d
 }`,
 					},
 				},
-				createLocation: createLocation(4, 3, "main.rego"),
+				createLocation: createLocation(4, 22, "main_test.rego"),
+				expectItems: []source.CompletionItem{
+					{Label: "input", Kind: source.VariableItem},
+				},
+			},
+			"Should list rules": {
+				files: map[string]source.File{
+					"main_test.rego": {
+						RawText: `package main
+
+admin_input := {"user": "admin"}
+
+test_allow {
+	allow with input as adm
+}`,
+					},
+				},
+				createLocation: createLocation(6, 24, "main_test.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "hello",
-						Kind:  source.FunctionItem,
+						Label: "admin_input",
+						Kind:  source.VariableItem,
 						TextEdit: &source.TextEdit{
-							Row:  4,
-							Col:  2,
-							Text: "hello(msg)",
+							Row:  6,
+							Col:  22,
+							Text: "admin_input",
 						},
-						Detail: `hello(msg) {
-	msg == "hello"
-}`,
+						Detail: `admin_input := {"user": "admin"}`,
 					},
 				},
 			},
-			"Should list rules in the other packages": {
+		},
+		"List rules for the prefix": {
+			"Should list the rules of the current package for the bare prefix": {
 				files: map[string]source.File{
 					"main.rego": {
 						RawText: `package main
 
 import data.lib
 
-violation [msg] {
-	lib.i
+is_admin {
+	input.admin
+}
+
+violation[msg] {
+	is
 }`,
 					},
 					"lib.rego": {
 						RawText: `package lib
 
-is_hello(msg) {
-	msg == "hello"
+is_hello {
+	input.msg == "hello"
 }`,
 					},
 				},
-				createLocation: createLocation(6, 6, "main.rego"),
+				createLocation: createLocation(10, 3, "main.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:    "is_admin",
+						Kind:     source.VariableItem,
+						TextEdit: &source.TextEdit{Row: 10, Col: 2, Text: "is_admin"},
+						Detail:   "is_admin {\n\tinput.admin\n}",
+					},
+				},
+			},
+		},
+		"List object keys": {
+			"Should list keys of the object bound to the variable": {
+				files: map[string]source.File{
+					"src.rego": {
+						RawText: `package src
+
+allow {
+	x := {"name": "admin", "enabled": true}
+	x.n
+}`,
+					},
+				},
+				createLocation: createLocation(5, 4, "src.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:    "name",
+						Kind:     source.VariableItem,
+						Detail:   "string",
+						TextEdit: &source.TextEdit{Row: 5, Col: 4, Text: "name"},
+					},
+				},
+			},
+			"Should list fields of the function result whose type is known": {
+				files: map[string]source.File{
+					"src.rego": {
+						RawText: `package src
+
+parse_config(x) := {"name": x.name, "port": 8080}
+
+allow {
+	c := parse_config(input)
+	c.p
+}`,
+					},
+				},
+				createLocation: createLocation(7, 4, "src.rego"),
+				expectItems: []source.CompletionItem{
+					{
+						Label:    "port",
+						Kind:     source.VariableItem,
+						Detail:   "number",
+						TextEdit: &source.TextEdit{Row: 7, Col: 4, Text: "port"},
+					},
+				},
+			},
+			"Should list nested fields of the imported function result": {
+				files: map[string]source.File{
+					"src.rego": {
+						RawText: `package src
+
+import data.lib
+
+allow {
+	c := lib.parse_config(input)
+	c.tls.e
+}`,
+					},
+					"lib.rego": {
+						RawText: `package lib
+
+parse_config(x) := {"name": x.name, "tls": {"enabled": true}}`,
+					},
+				},
+				createLocation: createLocation(7, 8, "src.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "is_hello",
-						Kind:  source.FunctionItem,
-						TextEdit: &source.TextEdit{
-							Row:  6,
-							Col:  6,
-							Text: "is_hello(msg)",
-						},
-						Detail: `is_hello(msg) {
-	msg == "hello"
-}`,
+						Label:    "enabled",
+						Kind:     source.VariableItem,
+						Detail:   "boolean",
+						TextEdit: &source.TextEdit{Row: 7, Col: 8, Text: "enabled"},
 					},
 				},
 			},
-			"Should list built-in functions": {
+			"Should list fields of the element of the built-in result": {
 				files: map[string]source.File{
-					"main.rego": {
-						RawText: `package main
+					"src.rego": {
+						RawText: `package src
 
-violation[msg] {
-	j
+allow {
+	result := json.match_schema(input, {})
+	result[1][0].f
 }`,
 					},
 				},
-				createLocation: createLocation(4, 2, "main.rego"),
+				createLocation: createLocation(5, 15, "src.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label:  "json.patch",
-						Kind:   source.BuiltinFunctionItem,
-						Detail: "json.patch(any, array[object<op: string, path: any>[any: any]])\n\n" + source.BuiltinDetail,
-						TextEdit: &source.TextEdit{
-							Row:  4,
-							Col:  2,
-							Text: "json.patch(any, array[object<op: string, path: any>[any: any]])",
-						},
+						Label:    "field",
+						Kind:     source.VariableItem,
+						Detail:   "string",
+						TextEdit: &source.TextEdit{Row: 5, Col: 15, Text: "field"},
 					},
 				},
 			},
-			"Should list built-in functions when prefix include `.` character": {
+			"Should list fields of the built-in result which is destructured": {
 				files: map[string]source.File{
-					"main.rego": {
-						RawText: `package main
+					"src.rego": {
+						RawText: `package src
 
-violation[msg] {
-	json.p
+allow {
+	[valid, errs] := json.match_schema(input, {})
+	errs[_].d
 }`,
 					},
 				},
-				createLocation: createLocation(4, 7, "main.rego"),
+				createLocation: createLocation(5, 10, "src.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label:  "patch",
-						Kind:   source.BuiltinFunctionItem,
-						Detail: "json.patch(any, array[object<op: string, path: any>[any: any]])\n\n" + source.BuiltinDetail,
-						TextEdit: &source.TextEdit{
-							Row:  4,
-							Col:  7,
-							Text: "patch(any, array[object<op: string, path: any>[any: any]])",
-						},
+						Label:    "desc",
+						Kind:     source.VariableItem,
+						Detail:   "string",
+						TextEdit: &source.TextEdit{Row: 5, Col: 10, Text: "desc"},
 					},
 				},
 			},
-			"Should list rule which is variable": {
+			"Should list keys of the nested object": {
 				files: map[string]source.File{
 					"src.rego": {
 						RawText: `package src
 
-violation[msg] {
-	is
-}
-
-default is_test = true`,
+allow {
+	x := {"user": {"name": "admin", "roles": ["a"]}}
+	x.user.r
+}`,
 					},
 				},
-				createLocation: createLocation(4, 3, "src.rego"),
+				createLocation: createLocation(5, 9, "src.rego"),
 				expectItems: []source.CompletionItem{
 					{
-						Label: "is_test",
-						Kind:  source.VariableItem,
-						TextEdit: &source.TextEdit{
-							Row:  4,
-							Col:  2,
-							Text: "is_test",
-						},
-						Detail: "default is_test = true",
+						Label:    "roles",
+						Kind:     source.VariableItem,
+						Detail:   "array",
+						TextEdit: &source.TextEdit{Row: 5, Col: 9, Text: "roles"},
 					},
 				},
 			},
@@ -581,3 +2296,433 @@ func in(item source.CompletionItem, list []source.CompletionItem) bool {
 	}
 	return false
 }
+
+func TestProject_ListCompletionItemsDeprecatedBuiltins(t *testing.T) {
+	files := map[string]source.File{
+		"main.rego": {
+			RawText: `package main
+
+allow {
+	net.cidr_
+}`,
+		},
+	}
+	project, err := source.NewProjectWithFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := project.ListCompletionItems(createLocation(4, 10, "main.rego")(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deprecated, replacement := -1, -1
+	for i, item := range items {
+		switch item.Label {
+		case "cidr_overlap":
+			deprecated = i
+			if !item.Deprecated {
+				t.Errorf("cidr_overlap should be deprecated")
+			}
+		case "cidr_contains":
+			replacement = i
+			if item.Deprecated {
+				t.Errorf("cidr_contains should not be deprecated")
+			}
+		}
+	}
+	if deprecated == -1 || replacement == -1 {
+		t.Fatalf("ListCompletionItems should list cidr_overlap and cidr_contains, but got %v", items)
+	}
+	if deprecated != len(items)-1 || replacement > deprecated {
+		t.Errorf("cidr_overlap should rank below cidr_contains, but got index %d and %d", deprecated, replacement)
+	}
+}
+
+func TestProject_ListCompletionItemsWithSchema(t *testing.T) {
+	schema := `{
+	"type": "object",
+	"properties": {
+		"config": {
+			"type": "object",
+			"required": ["name"],
+			"properties": {
+				"name": {"type": "string"},
+				"enabled": {"type": "boolean"},
+				"limits": {
+					"type": "object",
+					"properties": {
+						"cpu": {"type": "string"},
+						"memory": {"type": "string"}
+					}
+				}
+			}
+		}
+	}
+}`
+
+	tests := map[string]completionTestCase{
+		"Should list the top-level properties of the schema after input": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input
+}`,
+				},
+			},
+			updateFile: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.
+}`,
+				},
+			},
+			createLocation: createLocation(4, 7, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "config",
+					Kind:     source.VariableItem,
+					Detail:   "object (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 8, Text: "config"},
+				},
+			},
+		},
+		"Should list the nested properties of the schema which match the prefix": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.config.limits.c
+}`,
+				},
+			},
+			createLocation: createLocation(4, 22, "src.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "cpu",
+					Kind:     source.VariableItem,
+					Detail:   "string (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 22, Text: "cpu"},
+				},
+			},
+		},
+		"Should list the keys of the schema in the empty object": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package src
+
+test_allow {
+	input.config == {}
+}`,
+				},
+			},
+			createLocation: createLocation(4, 18, "src_test.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "enabled",
+					Kind:     source.VariableItem,
+					Detail:   "boolean (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 19, Text: `"enabled"`, End: &source.Position{Row: 4, Col: 19}},
+				},
+				{
+					Label:    "limits",
+					Kind:     source.VariableItem,
+					Detail:   "object (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 19, Text: `"limits"`, End: &source.Position{Row: 4, Col: 19}},
+				},
+				{
+					Label:    "name",
+					Kind:     source.VariableItem,
+					Detail:   "string (required)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 19, Text: `"name"`, End: &source.Position{Row: 4, Col: 19}},
+				},
+			},
+		},
+		"Should list the keys which match the typed prefix and are not used yet": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package src
+
+test_allow {
+	input.config == {"name", "e"}
+}`,
+				},
+			},
+			createLocation: createLocation(4, 28, "src_test.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "enabled",
+					Kind:     source.VariableItem,
+					Detail:   "boolean (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 27, Text: `"enabled"`, End: &source.Position{Row: 4, Col: 30}},
+				},
+			},
+		},
+		"Should list the keys of the nested object": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package src
+
+test_allow {
+	input == {"config": {"limits": {"m"}}}
+}`,
+				},
+			},
+			createLocation: createLocation(4, 35, "src_test.rego"),
+			expectItems: []source.CompletionItem{
+				{
+					Label:    "memory",
+					Kind:     source.VariableItem,
+					Detail:   "string (optional)",
+					TextEdit: &source.TextEdit{Row: 4, Col: 34, Text: `"memory"`, End: &source.Position{Row: 4, Col: 37}},
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := project.SetSchema(ast.InputRootRef, []byte(schema)); err != nil {
+				t.Fatal(err)
+			}
+
+			for path, file := range tt.updateFile {
+				if err := project.UpdateFile(path, file.RawText, file.Version); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.ListCompletionItems(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectItems, got); diff != "" {
+				t.Errorf("ListCompletionItems result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_ListCompletionItemsCache(t *testing.T) {
+	labels := func(items []source.CompletionItem) map[string]bool {
+		result := make(map[string]bool)
+		for _, item := range items {
+			result[item.Label] = true
+		}
+		return result
+	}
+	complete := func(t *testing.T, project *source.Project, files map[string]source.File, row, col int) map[string]bool {
+		t.Helper()
+		for path, file := range files {
+			if err := project.UpdateFile(path, file.RawText, file.Version); err != nil {
+				t.Fatal(err)
+			}
+		}
+		items, err := project.ListCompletionItems(createLocation(row, col, "main.rego")(files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return labels(items)
+	}
+
+	project, err := source.NewProjectWithFiles(map[string]source.File{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := complete(t, project, map[string]source.File{
+		"main.rego": {RawText: "package main\n\nallow {\n\tc\n}\n\ncat := 1\n\ncow := 2\n", Version: 1},
+	}, 4, 2)
+	if !got["cat"] || !got["cow"] {
+		t.Fatalf("should list cat and cow, but got %v", got)
+	}
+
+	got = complete(t, project, map[string]source.File{
+		"main.rego": {RawText: "package main\n\nallow {\n\tco\n}\n\ncat := 1\n\ncow := 2\n", Version: 2},
+	}, 4, 3)
+	if got["cat"] || !got["cow"] {
+		t.Errorf("should be filtered by the new prefix, but got %v", got)
+	}
+	if hits := project.CompletionCacheHits(); hits != 1 {
+		t.Errorf("should reuse the candidates for the growing word, but the cache hits %d times", hits)
+	}
+
+	got = complete(t, project, map[string]source.File{
+		"main.rego": {RawText: "package main\n\nallow {\n\tco\n}\n\ncat := 1\n\ncow := 2\n\ncoat := 3\n", Version: 3},
+	}, 4, 3)
+	if !got["coat"] {
+		t.Errorf("should list the rule which is added to the same file, but got %v", got)
+	}
+
+	got = complete(t, project, map[string]source.File{
+		"main.rego": {RawText: "package main\n\nallow {\n\tco\n}\n\ncat := 1\n\ncow := 2\n\ncoat := 3\n", Version: 3},
+		"lib.rego":  {RawText: "package main\n\ncool := 4\n", Version: 1},
+	}, 4, 3)
+	if !got["coat"] || !got["cool"] {
+		t.Errorf("should list the rule which is added to the other file, but got %v", got)
+	}
+}
+
+func BenchmarkProject_ListCompletionItems(b *testing.B) {
+	files := map[string]source.File{
+		"main.rego": {RawText: "package main\n\nallow {\n\tuser := input.user\n\tco\n}\n\ncat := 1\n\ncow := 2\n"},
+		"lib.rego":  {RawText: "package main\n\ncool := 4\n"},
+	}
+	location := createLocation(5, 3, "main.rego")(files)
+
+	b.Run("cached", func(b *testing.B) {
+		project, err := source.NewProjectWithFiles(files)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := project.ListCompletionItems(location); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("invalidated", func(b *testing.B) {
+		project, err := source.NewProjectWithFiles(files)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			if err := project.UpdateFile("lib.rego", files["lib.rego"].RawText, i); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+			if _, err := project.ListCompletionItems(location); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestProject_InsertPackageEdit(t *testing.T) {
+	tests := map[string]struct {
+		files      map[string]source.File
+		path       string
+		pkgName    string
+		expectEdit source.TextEdit
+	}{
+		"Should insert the package into the empty file": {
+			files: map[string]source.File{
+				"src.rego": {RawText: ""},
+			},
+			path:    "src.rego",
+			pkgName: "src",
+			expectEdit: source.TextEdit{
+				Row:  1,
+				Col:  1,
+				Text: "package src\n\n",
+			},
+		},
+		"Should replace the whitespaces of the empty file": {
+			files: map[string]source.File{
+				"src.rego": {RawText: "\n\n"},
+			},
+			path:    "src.rego",
+			pkgName: "src",
+			expectEdit: source.TextEdit{
+				Row:  1,
+				Col:  1,
+				Text: "package src\n\n",
+				End:  &source.Position{Row: 3, Col: 1},
+			},
+		},
+		"Should insert the package above the rules of the file without the package": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `allow {
+	input.user == "admin"
+}`,
+				},
+			},
+			path:    "src.rego",
+			pkgName: "lib.src",
+			expectEdit: source.TextEdit{
+				Row:  1,
+				Col:  1,
+				Text: "package lib.src\n\n",
+			},
+		},
+		"Should not add the blank line when the file starts with it": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `
+import data.lib`,
+				},
+			},
+			path:    "src.rego",
+			pkgName: "src",
+			expectEdit: source.TextEdit{
+				Row:  1,
+				Col:  1,
+				Text: "package src\n",
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.InsertPackageEdit(tt.path, tt.pkgName)
+			if diff := cmp.Diff(tt.expectEdit, got); diff != "" {
+				t.Errorf("InsertPackageEdit result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCurrentWord(t *testing.T) {
+	tests := map[string]struct {
+		rawText      string
+		expectWord   string
+		expectOffset int
+	}{
+		"Should return the identifier before the offset": {
+			rawText:      "allow {\n\tin_put",
+			expectWord:   "in_put",
+			expectOffset: len("allow {\n\t"),
+		},
+		"Should return the multibyte letters in the identifier": {
+			rawText:      "\tcafé",
+			expectWord:   "café",
+			expectOffset: len("\t"),
+		},
+		"Should stop at the multibyte character which is not a letter": {
+			rawText:      "∪de",
+			expectWord:   "de",
+			expectOffset: len("∪"),
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			word, offset := source.CurrentWord(tt.rawText, len(tt.rawText))
+			if word != tt.expectWord || offset != tt.expectOffset {
+				t.Errorf("CurrentWord should return %q, %d, but got %q, %d", tt.expectWord, tt.expectOffset, word, offset)
+			}
+		})
+	}
+}
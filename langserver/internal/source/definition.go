@@ -4,11 +4,20 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
 )
 
+// LookupDefinition returns every definition of the term at the location, so the client can show them as the picker.
+// When the rule has several clauses like the incremental rule, the location of each clause is returned
+// even if the clauses are in the different files of the same package.
+// The locations are sorted by the file and then the row.
+//
+//	mem_multiple("E") = 1000000000000000000000
+//	mem_multiple("P") = 1000000000000000000
+//	mem_multiple(s) -> both clauses
 func (p *Project) LookupDefinition(location *ast.Location) ([]*ast.Location, error) {
 	targetTerm, err := p.SearchTargetTerm(location)
 	if err != nil {
@@ -18,9 +27,89 @@ func (p *Project) LookupDefinition(location *ast.Location) ([]*ast.Location, err
 		return nil, nil
 	}
 
+	if b := infixBuiltin(targetTerm); b != nil {
+		return []*ast.Location{builtinDocumentLocation(b)}, nil
+	}
+
+	if locations := p.findImportedPackageDefinitions(targetTerm); len(locations) != 0 {
+		return locations, nil
+	}
+
 	return p.findDefinition(targetTerm), nil
 }
 
+// findImportedPackageDefinitions returns the package declarations which the imported name refers to.
+// The aliased import and the import whose package is not in the workspace are not resolved, so the import itself is the definition.
+//
+//	import data.lib
+//
+//	lib.nested.rule
+//	^ data.lib
+//	    ^ data.lib.nested
+func (p *Project) findImportedPackageDefinitions(term *ast.Term) []*ast.Location {
+	head, path := term, ast.Ref{}
+	if ref, ok := term.Value.(ast.Ref); ok && len(ref) > 1 {
+		head, path = ref[0], ref[1:]
+	}
+	name, ok := head.Value.(ast.Var)
+	if !ok || head.Location == nil {
+		return nil
+	}
+
+	// the local variable shadows the import.
+	if rule := p.findRuleForTerm(head.Loc()); rule != nil && p.findDefinitionInRule(head, rule) != nil {
+		return nil
+	}
+
+	module := p.GetModule(head.Loc().File)
+	if module == nil {
+		return nil
+	}
+	imp := findImportByName(name, module.Imports)
+	if imp == nil || imp.Alias != "" {
+		return nil
+	}
+	pkg, ok := imp.Path.Value.(ast.Ref)
+	if !ok {
+		return nil
+	}
+	return uniqueLocations(p.findImportDefinitions(&ast.Term{Value: pkg.Concat(path)}))
+}
+
+// infixBuiltin returns the built-in function when the term is the infix operator.
+//
+//	input.user == "admin"
+//	           ^ equal
+func infixBuiltin(term *ast.Term) *ast.Builtin {
+	switch term.Value.(type) {
+	case ast.Var, ast.Ref:
+	default:
+		return nil
+	}
+	if term.Location == nil {
+		return nil
+	}
+	b, ok := ast.BuiltinMap[term.Value.String()]
+	if !ok || b.Infix == "" || b.Infix != string(term.Location.Text) {
+		return nil
+	}
+	return b
+}
+
+// builtinDocumentLocation returns the location whose File is the document URL of the built-in function.
+func builtinDocumentLocation(b *ast.Builtin) *ast.Location {
+	anchor := "#built-in-functions"
+	if len(b.Categories) > 0 {
+		anchor = fmt.Sprintf("#builtin-%s-%s", b.Categories[0], strings.ReplaceAll(b.Name, ".", ""))
+	}
+	return &ast.Location{
+		Row:  1,
+		Col:  1,
+		Text: []byte(b.Name),
+		File: BuiltinDocumentURL + anchor,
+	}
+}
+
 func (p *Project) findDefinition(term *ast.Term) []*ast.Location {
 	rule := p.findRuleForTerm(term.Loc())
 	if rule != nil {
@@ -32,6 +121,7 @@ func (p *Project) findDefinition(term *ast.Term) []*ast.Location {
 	return p.findDefinitionOutOfRule(term)
 }
 
+// findRuleForTerm returns the rule which contains the location, or its else branch when the location is in it.
 func (p *Project) findRuleForTerm(loc *ast.Location) *ast.Rule {
 	module := p.GetModule(loc.File)
 	if module == nil {
@@ -46,6 +136,8 @@ func (p *Project) findRuleForTerm(loc *ast.Location) *ast.Rule {
 	return nil
 }
 
+// findElseRule returns the branch of the else chain which contains the location.
+// Each branch has its own body, so the variables are searched only in the returned branch.
 func (p *Project) findElseRule(loc *ast.Location, rule *ast.Rule) *ast.Rule {
 	for {
 		if rule.Else == nil || !in(loc, rule.Else.Loc()) {
@@ -56,6 +148,24 @@ func (p *Project) findElseRule(loc *ast.Location, rule *ast.Rule) *ast.Rule {
 }
 
 func (p *Project) findDefinitionInRule(term *ast.Term, rule *ast.Rule) *ast.Term {
+	// The variables in the comprehension shadow the variables of the rule.
+	if c := findComprehensionTerm(term, rule); c != nil {
+		result := p.findDefinitionInTerm(term, c)
+		if result != nil {
+			return result
+		}
+	}
+
+	// name := n if { n := input.name }
+	//         ^ the head is evaluated after the body
+	if isInHeadKeyOrValue(term, rule) {
+		result := p.findDefinitionInTerms(term, rule.Head.Args)
+		if result != nil {
+			return result
+		}
+		return p.findDefinitionInBody(afterRule(term, rule), rule.Body)
+	}
+
 	// violation[msg]
 	//           ^ this is key
 	if rule.Head.Key != nil {
@@ -81,7 +191,34 @@ func (p *Project) findDefinitionInRule(term *ast.Term, rule *ast.Rule) *ast.Term
 		return result
 	}
 
-	for _, b := range rule.Body {
+	return p.findDefinitionInBody(term, rule.Body)
+}
+
+func isInHeadKeyOrValue(term *ast.Term, rule *ast.Rule) bool {
+	for _, t := range []*ast.Term{rule.Head.Key, rule.Head.Value} {
+		if t != nil && t.Location != nil && in(term.Loc(), t.Location) {
+			return true
+		}
+	}
+	return false
+}
+
+// afterRule returns the term which is moved to the end of the rule, so the variables in the body are found before it.
+func afterRule(term *ast.Term, rule *ast.Rule) *ast.Term {
+	return &ast.Term{
+		Value: term.Value,
+		Location: &ast.Location{
+			Row:    term.Location.Row,
+			Col:    term.Location.Col,
+			Offset: rule.Location.Offset + len(rule.Location.Text),
+			Text:   term.Location.Text,
+			File:   term.Location.File,
+		},
+	}
+}
+
+func (p *Project) findDefinitionInBody(term *ast.Term, body ast.Body) *ast.Term {
+	for _, b := range body {
 		switch t := b.Terms.(type) {
 		case *ast.Term:
 			result := p.findDefinitionInTerm(term, t)
@@ -89,13 +226,54 @@ func (p *Project) findDefinitionInRule(term *ast.Term, rule *ast.Rule) *ast.Term
 				return result
 			}
 		case []*ast.Term:
-			// equality -> [hoge, fuga] = split_hoge()
 			// assign -> hoge := fuga()
-			if ast.Equality.Ref().Equal(b.Operator()) || ast.Assign.Ref().Equal(b.Operator()) {
+			if ast.Assign.Ref().Equal(b.Operator()) {
 				result := p.findDefinitionInTerm(term, t[1])
 				if result != nil {
 					return result
 				}
+				continue
+			}
+
+			// equality -> [hoge, fuga] = split_hoge()
+			//             input.name = name
+			// the unification can bind the variables of either operand, so the first occurrence is the binding.
+			if ast.Equality.Ref().Equal(b.Operator()) {
+				for _, operand := range t[1:] {
+					// the arguments of the call like `x = f(y)` are not bound by the unification.
+					if _, ok := operand.Value.(ast.Call); ok {
+						continue
+					}
+					result := p.findDefinitionInTerm(term, operand)
+					if result != nil && !p.isRuleOrImport(result) {
+						return result
+					}
+				}
+				continue
+			}
+
+			// call -> count(input.users, n)
+			// only the output argument beyond the arity of the built-in binds the variable.
+			result := p.findDefinitionInTerms(term, outputArgs(t))
+			if result != nil && !p.isRuleOrImport(result) {
+				return result
+			}
+		case *ast.Every:
+			// every k, v in coll { use(k) }
+			// the key and the value are visible only in the body of every.
+			if !in(term.Loc(), b.Loc()) {
+				continue
+			}
+			for _, t := range []*ast.Term{t.Key, t.Value} {
+				if t == nil {
+					continue
+				}
+				if result := p.findDefinitionInTerm(term, t); result != nil {
+					return result
+				}
+			}
+			if result := p.findDefinitionInBody(term, t.Body); result != nil {
+				return result
 			}
 		default:
 			fmt.Fprintf(os.Stderr, "type: %T", b.Terms)
@@ -104,6 +282,42 @@ func (p *Project) findDefinitionInRule(term *ast.Term, rule *ast.Rule) *ast.Term
 	return nil
 }
 
+// outputArgs returns the arguments of the built-in call which are beyond its declared arity.
+//
+//	count(input.users, n) -> n
+//	is_set(names)         -> none
+func outputArgs(terms []*ast.Term) []*ast.Term {
+	if len(terms) == 0 {
+		return nil
+	}
+	op, ok := terms[0].Value.(ast.Ref)
+	if !ok {
+		return nil
+	}
+	b, ok := ast.BuiltinMap[op.String()]
+	if !ok || b.Decl == nil {
+		return nil
+	}
+	arity := len(b.Decl.FuncArgs().Args)
+	if len(terms)-1 <= arity {
+		return nil
+	}
+	return terms[1+arity:]
+}
+
+// isRuleOrImport returns true when the var is the name of the rule in the package or the import,
+// so it is not bound by the output argument or the unification.
+func (p *Project) isRuleOrImport(term *ast.Term) bool {
+	v, ok := term.Value.(ast.Var)
+	if !ok {
+		return false
+	}
+	if module := p.GetModule(term.Loc().File); module != nil && findImportByName(v, module.Imports) != nil {
+		return true
+	}
+	return len(p.findRulesInModule(term)) > 0
+}
+
 func (p *Project) findDefinitionInTerms(target *ast.Term, terms []*ast.Term) *ast.Term {
 	for _, term := range terms {
 		t := p.findDefinitionInTerm(target, term)
@@ -130,11 +344,23 @@ func (p *Project) findDefinitionInTerm(target *ast.Term, term *ast.Term) *ast.Te
 			return t
 		}
 		return nil
+	case ast.Set:
+		return p.findDefinitionInTerms(target, v.Slice())
+	case ast.Call:
+		// arr[f(x)]
+		//      ^ the call can be an element of the ref
+		return p.findDefinitionInTerms(target, []*ast.Term(v)[1:])
 	case ast.Var:
 		if target.Equal(term) && target.Loc().Offset > term.Loc().Offset {
 			return term
 		}
 		return nil
+	case *ast.ArrayComprehension:
+		return p.findDefinitionInComprehension(target, term, []*ast.Term{v.Term}, v.Body)
+	case *ast.SetComprehension:
+		return p.findDefinitionInComprehension(target, term, []*ast.Term{v.Term}, v.Body)
+	case *ast.ObjectComprehension:
+		return p.findDefinitionInComprehension(target, term, []*ast.Term{v.Key, v.Value}, v.Body)
 	case ast.String, ast.Boolean, ast.Number:
 		return nil
 	default:
@@ -142,7 +368,186 @@ func (p *Project) findDefinitionInTerm(target *ast.Term, term *ast.Term) *ast.Te
 	}
 }
 
+// findDefinitionInComprehension returns the variable bound in the body of the comprehension.
+// The variables in the comprehension are not visible from the outside, so the target must be in the comprehension.
+func (p *Project) findDefinitionInComprehension(target, comprehension *ast.Term, head []*ast.Term, body ast.Body) *ast.Term {
+	if !in(target.Loc(), comprehension.Loc()) {
+		return nil
+	}
+
+	if c := findComprehensionTerm(target, body); c != nil {
+		result := p.findDefinitionInTerm(target, c)
+		if result != nil {
+			return result
+		}
+	}
+
+	// [x | x := input[_]]
+	//  ^ the head is evaluated after the body
+	for _, h := range head {
+		if h != nil && in(target.Loc(), h.Loc()) {
+			target = &ast.Term{
+				Value: target.Value,
+				Location: &ast.Location{
+					Row:    target.Location.Row,
+					Col:    target.Location.Col,
+					Offset: comprehension.Location.Offset + len(comprehension.Location.Text),
+					Text:   target.Location.Text,
+					File:   target.Location.File,
+				},
+			}
+			break
+		}
+	}
+	return p.findDefinitionInBody(target, body)
+}
+
+// findComprehensionTerm returns the outermost comprehension which contains the target in x.
+func findComprehensionTerm(target *ast.Term, x interface{}) *ast.Term {
+	var result *ast.Term
+	ast.NewGenericVisitor(func(x interface{}) bool {
+		if result != nil {
+			return true
+		}
+		t, ok := x.(*ast.Term)
+		if !ok {
+			return false
+		}
+		switch t.Value.(type) {
+		case *ast.ArrayComprehension, *ast.SetComprehension, *ast.ObjectComprehension:
+			if t.Loc() != nil && in(target.Loc(), t.Loc()) {
+				result = t
+				return true
+			}
+		}
+		return false
+	}).Walk(x)
+	return result
+}
+
+// DefinitionMode decides which part of the rule is returned as the definition.
+type DefinitionMode int
+
+const (
+	// DefinitionHeadName returns the name of the rule head.
+	DefinitionHeadName DefinitionMode = iota
+	// DefinitionWholeRule returns the whole rule.
+	DefinitionWholeRule
+	// DefinitionHeadKey returns the key of the partial rule like `msg` of `violation[msg]`.
+	// The name is returned for the rule which has no key.
+	DefinitionHeadKey
+)
+
+// SetDefinitionMode changes the part of the rule which is returned as the definition.
+func (p *Project) SetDefinitionMode(mode DefinitionMode) {
+	p.definitionMode = mode
+}
+
+// UnqualifiedRefMode decides how the unqualified reference which matches no rule in the current package is handled.
+type UnqualifiedRefMode int
+
+const (
+	// UnqualifiedRefPackage resolves the unqualified reference only in the current package.
+	UnqualifiedRefPackage UnqualifiedRefMode = iota
+	// UnqualifiedRefWorkspace searches the rules of the same name in the whole workspace for the definition.
+	UnqualifiedRefWorkspace
+	// UnqualifiedRefWarn reports the unqualified reference by UnresolvedRefs, because the import is likely missing.
+	UnqualifiedRefWarn
+)
+
+// SetUnqualifiedRefMode changes how the unqualified reference which matches no rule in the current package is handled.
+func (p *Project) SetUnqualifiedRefMode(mode UnqualifiedRefMode) {
+	p.unqualifiedRefMode = mode
+}
+
+// findRulesInWorkspace returns the rules of the name in all packages.
+func (p *Project) findRulesInWorkspace(name ast.Var) []*ast.Rule {
+	result := make([]*ast.Rule, 0)
+	for _, pkg := range p.cache.GetPackages() {
+		for _, m := range p.cache.FindPolicies(pkg) {
+			for _, r := range m.Rules {
+				if ruleHeadName(r) == string(name) {
+					result = append(result, r)
+				}
+			}
+		}
+	}
+	return result
+}
+
 func (p *Project) findDefinitionInModule(term *ast.Term) []*ast.Location {
+	rules := p.findRulesInModule(term)
+	if rules == nil {
+		return nil
+	}
+
+	result := make([]*ast.Location, 0, len(rules))
+	for _, rule := range rules {
+		switch {
+		case p.definitionMode == DefinitionWholeRule:
+			loc := *rule.Location
+			result = append(result, &loc)
+			continue
+		case p.definitionMode == DefinitionHeadKey && rule.Head.Key != nil && rule.Head.Key.Location != nil:
+			loc := *rule.Head.Key.Location
+			result = append(result, &loc)
+			continue
+		}
+
+		result = append(result, ruleNameLocation(rule))
+	}
+	return result
+}
+
+// RulesForPath returns the rules which produce the document of the path.
+// When the path addresses a package, the rules of the package and its sub packages are returned.
+//
+//	data.authz.allow -> allow rules in package authz
+//	data.authz       -> all rules in package authz
+func (p *Project) RulesForPath(path ast.Ref) ([]*ast.Rule, error) {
+	if !path.HasPrefix(ast.DefaultRootRef) {
+		return nil, fmt.Errorf("%s is not a path of data", path)
+	}
+
+	result := make([]*ast.Rule, 0)
+	var longest ast.Ref
+	for _, pkg := range p.cache.GetPackages() {
+		switch {
+		case pkg.HasPrefix(path):
+			for _, m := range p.cache.FindPolicies(pkg) {
+				result = append(result, m.Rules...)
+			}
+		case path.HasPrefix(pkg) && len(pkg) > len(longest):
+			longest = pkg
+		}
+	}
+
+	// data.authz.allow.x
+	//            ^ the rule which produces the path
+	if len(result) == 0 && longest != nil {
+		for _, m := range p.cache.FindPolicies(longest) {
+			for _, r := range m.Rules {
+				if ast.String(ruleHeadName(r)).Equal(path[len(longest)].Value) {
+					result = append(result, r)
+				}
+			}
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no rules produce %s", path)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Location.File != result[j].Location.File {
+			return result[i].Location.File < result[j].Location.File
+		}
+		return result[i].Location.Offset < result[j].Location.Offset
+	})
+	return result, nil
+}
+
+// findRulesInModule returns rules which are referred by the term.
+func (p *Project) findRulesInModule(term *ast.Term) []*ast.Rule {
 	searchPackageName := p.findPolicyRef(term)
 	searchPolicies := p.cache.FindPolicies(searchPackageName)
 
@@ -151,28 +556,55 @@ func (p *Project) findDefinitionInModule(term *ast.Term) []*ast.Location {
 	}
 
 	word := term.String()
-	if strings.Contains(word, ".") /* imported method */ {
-		word = word[strings.Index(word, ".")+1:]
+	if ref, ok := term.Value.(ast.Ref); ok && len(ref) > 1 /* imported method */ {
+		word = ref[len(ref)-1].Value.String()
+		if s, ok := ref[len(ref)-1].Value.(ast.String); ok {
+			word = string(s)
+		}
 	}
 
-	result := make([]*ast.Location, 0)
+	result := make([]*ast.Rule, 0)
 	for _, mod := range searchPolicies {
 		for _, rule := range mod.Rules {
-			if rule.Head.Name.String() == word {
-				loc := &ast.Location{
-					Row:    rule.Location.Row,
-					Col:    rule.Location.Col,
-					File:   rule.Location.File,
-					Text:   []byte(rule.Head.Name.String()),
-					Offset: rule.Location.Offset,
-				}
-				result = append(result, loc)
+			if ruleHeadName(rule) == word {
+				result = append(result, rule)
 			}
 		}
 	}
 	return result
 }
 
+// ruleHeadName returns the name of the document which the rule produces.
+// Head.Name is empty for the rule whose head is the ref, so the first segment of the ref is used.
+//
+//	deny contains msg if { ... }       -> deny
+//	deny[msg] { ... }                  -> deny
+//	deny.admin contains msg if { ... } -> deny
+func ruleHeadName(rule *ast.Rule) string {
+	if rule.Head.Name != "" {
+		return rule.Head.Name.String()
+	}
+	ref := rule.Head.Ref()
+	if len(ref) == 0 {
+		return ""
+	}
+	return ref[0].Value.String()
+}
+
+// findNestedPackage returns the longest package which is pkg followed by the path.
+//
+//	import data.lib
+//	lib.nested.rule -> data.lib.nested when the package exists
+func (p *Project) findNestedPackage(pkg ast.Ref, path ast.Ref) ast.Ref {
+	for i := len(path); i > 0; i-- {
+		candidate := pkg.Concat(path[:i])
+		if len(p.cache.FindPolicies(candidate)) > 0 {
+			return candidate
+		}
+	}
+	return pkg
+}
+
 func (p *Project) findPolicyRef(term *ast.Term) ast.Ref {
 	if term == nil {
 		return nil
@@ -184,7 +616,15 @@ func (p *Project) findPolicyRef(term *ast.Term) ast.Ref {
 	}
 
 	if ref, ok := term.Value.(ast.Ref); ok && len(ref) > 1 {
-		imp := findImportOutsidePolicy(ref[0].String(), module.Imports)
+		v, ok := ref[0].Value.(ast.Var)
+		if !ok {
+			return nil
+		}
+		// data is the root of the whole workspace, not the imported package.
+		if ast.DefaultRootDocument.Value.Compare(v) == 0 {
+			return p.findDataPackage(ref)
+		}
+		imp := findImportByName(v, module.Imports)
 		if imp == nil {
 			return nil
 		}
@@ -192,26 +632,25 @@ func (p *Project) findPolicyRef(term *ast.Term) ast.Ref {
 		if !ok {
 			return nil
 		}
-		return result
+		return p.findNestedPackage(result, ref[1:len(ref)-1])
 	}
 
 	return module.Package.Path
 }
 
-func findImportOutsidePolicy(moduleName string, imports []*ast.Import) *ast.Import {
-	for _, imp := range imports {
-		alias := imp.Alias.String()
-		if alias != "" && moduleName == alias {
-			imp := imp
-			return imp
-		}
-		m := imp.String()[strings.LastIndex(imp.String(), "."):]
-		if strings.HasSuffix(m, moduleName) {
-			imp := imp
-			return imp
+// findDataPackage returns the longest package which the ref from the data root refers to.
+// When no package is found, the ref without the last term is returned, so the packages under it can be listed.
+//
+//	data.lib.auth.is_admin -> data.lib.auth
+//	data.lib.              -> data.lib
+func (p *Project) findDataPackage(ref ast.Ref) ast.Ref {
+	prefix := ref[:len(ref)-1]
+	for i := len(prefix); i > 1; i-- {
+		if len(p.cache.FindPolicies(prefix[:i])) > 0 {
+			return prefix[:i]
 		}
 	}
-	return nil
+	return prefix
 }
 
 func (p *Project) GetRawText(path string) (string, error) {
@@ -238,10 +677,21 @@ func (p *Project) findDefinitionOutOfRule(term *ast.Term) []*ast.Location {
 	if len(locations) != 0 {
 		return locations
 	}
+	// The modules are found from the map, so the order of definitions should be fixed.
 	if isImportTerm(term) {
-		return p.findImportDefinitions(term)
+		// data.lib.auth.is_admin
+		//               ^ the rule of the package when the ref is not the package
+		if locations := p.findImportDefinitions(term); len(locations) != 0 {
+			return uniqueLocations(locations)
+		}
+	}
+	locations = p.findDefinitionInModule(term)
+	if v, ok := term.Value.(ast.Var); ok && len(locations) == 0 && p.unqualifiedRefMode == UnqualifiedRefWorkspace {
+		for _, r := range p.findRulesInWorkspace(v) {
+			locations = append(locations, ruleNameLocation(r))
+		}
 	}
-	return p.findDefinitionInModule(term)
+	return uniqueLocations(locations)
 }
 
 func (p *Project) findDefinitionInImports(term *ast.Term) []*ast.Location {
@@ -251,32 +701,30 @@ func (p *Project) findDefinitionInImports(term *ast.Term) []*ast.Location {
 	}
 
 	module := p.GetModule(term.Loc().File)
-	for _, imp := range module.Imports {
-		if imp.Alias != "" && val.Equal(imp.Alias) {
-			t, err := p.GetRawText(imp.Location.File)
-			if err != nil {
-				return nil
-			}
-			t = t[imp.Location.Offset:]
-			t = t[:strings.Index(t, "\n")]
-			loc := &ast.Location{
-				Row:    imp.Location.Row,
-				Col:    strings.LastIndex(t, " ") + 2,
-				Offset: imp.Location.Offset + strings.LastIndex(t, " ") + 1,
-				Text:   []byte(imp.Alias),
-				File:   imp.Location.File,
-			}
-			return []*ast.Location{loc}
-		}
+	imp := findImportByName(val, module.Imports)
+	if imp == nil {
+		return nil
+	}
 
-		if imp.Alias == "" {
-			ref, ok := imp.Path.Value.(ast.Ref)
-			if ok && val.String() == string(ref[len(ref)-1].Value.(ast.String)) {
-				return []*ast.Location{ref[len(ref)-1].Loc()}
-			}
+	if imp.Alias != "" {
+		t, err := p.GetRawText(imp.Location.File)
+		if err != nil {
+			return nil
+		}
+		t = t[imp.Location.Offset:]
+		t = t[:strings.Index(t, "\n")]
+		loc := &ast.Location{
+			Row:    imp.Location.Row,
+			Col:    strings.LastIndex(t, " ") + 2,
+			Offset: imp.Location.Offset + strings.LastIndex(t, " ") + 1,
+			Text:   []byte(imp.Alias),
+			File:   imp.Location.File,
 		}
+		return []*ast.Location{loc}
 	}
-	return nil
+
+	ref := imp.Path.Value.(ast.Ref)
+	return []*ast.Location{ref[len(ref)-1].Loc()}
 }
 
 // import data.xxx
@@ -2,6 +2,7 @@ package source_test
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -39,6 +40,146 @@ violation[msg] {
 				},
 			},
 		},
+		"Should return variable definition bound by the right operand of unification": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	input.user.name = name
+	startswith(name, "admin")
+	name != "admin_guest"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 5, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    20,
+					Offset: len("package main\n\nallow {\n\tinput.user.name = "),
+					Text:   []byte("name"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the first binding of the variable which is unified on multiple lines": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	[user, _] = split(input.path, "/")
+	user = input.user
+	user == "admin"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 5, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    3,
+					Offset: len("package main\n\nallow {\n\t["),
+					Text:   []byte("user"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the rule which is unified instead of the operand": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+admin_role := "admin"
+
+allow {
+	input.role = admin_role
+	admin_role != ""
+}`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("admin_role"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should not return the argument of the call in unification as definition": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	n = count(arr)
+	arr = input.items
+	n > 0
+	arr[0] == "a"
+}`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    5,
+					Col:    2,
+					Offset: len("package main\n\nallow {\n\tn = count(arr)\n\t"),
+					Text:   []byte("arr"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return variable definition after multibyte string and comment": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+# 挨拶を返す
+violation[msg] {
+	greeting := "こんにちは"; m := greeting # 日本語
+	msg = m
+}`,
+				},
+			},
+			createLocation: createLocation(6, 8, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    5,
+					Col:    23,
+					Offset: len("package main\n\n# 挨拶を返す\nviolation[msg] {\n\tgreeting := \"こんにちは\"; "),
+					Text:   []byte("m"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return variable definition of the argument of the call in the ref": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	i := 1
+	arr := [1, 2]
+	arr[abs(i)] == 2
+}`,
+				},
+			},
+			createLocation: createLocation(6, 10, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    2,
+					Offset: len("package main\n\nallow {\n\t"),
+					Text:   []byte("i"),
+					File:   "src.rego",
+				},
+			},
+		},
 		"Should return definition in the rule's key": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -143,6 +284,80 @@ method(msg) {
 				},
 			},
 		},
+		"Should resolve the alias before the package whose name collides with it": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.x.foobar
+import data.foo as bar
+
+allow {
+	bar.is_admin
+	foobar.is_admin
+}`,
+				},
+				"foo.rego": {
+					RawText: `package foo
+
+is_admin := true`,
+				},
+				"bar.rego": {
+					RawText: `package bar
+
+is_admin := false`,
+				},
+				"foobar.rego": {
+					RawText: `package x.foobar
+
+is_admin := false`,
+				},
+			},
+			createLocation: createLocation(7, 6, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package foo\n\n"),
+					Text:   []byte("is_admin"),
+					File:   "foo.rego",
+				},
+			},
+		},
+		"Should return the alias definition when the package of the same name exists": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.bar.baz
+import data.foo as baz
+
+allow {
+	baz.is_admin
+}`,
+				},
+				"foo.rego": {
+					RawText: `package foo
+
+is_admin := true`,
+				},
+				"baz.rego": {
+					RawText: `package bar.baz
+
+is_admin := false`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    20,
+					Offset: len("package main\n\nimport data.bar.baz\nimport data.foo as "),
+					Text:   []byte("baz"),
+					File:   "src.rego",
+				},
+			},
+		},
 		"Should return import sentense definition": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -167,6 +382,71 @@ violation[msg] {
 				},
 			},
 		},
+		"Should return package definition of the imported name": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.method("hello")
+	msg := "hello"
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+method(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: 0,
+					Text:   []byte("package"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return package definition of the nested package under the imported name": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.nested.method("hello")
+	msg := "hello"
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib`,
+				},
+				"nested.rego": {
+					RawText: `package lib.nested
+
+method(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 6, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: 0,
+					Text:   []byte("package"),
+					File:   "nested.rego",
+				},
+			},
+		},
 		"Should not return definition when itself is definition": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -284,47 +564,1021 @@ authorize = "allow" {
 				},
 			},
 		},
-		"Should return definition from import sentense to the library file": {
+		"Should not return definition in the other branch of else": {
 			files: map[string]source.File{
 				"src.rego": {
 					RawText: `package main
 
-import data.lib`,
+authorize = "allow" {
+	msg := "allow"
+	trace(msg)
+} else = "deny" {
+	trace(msg)
+}`,
 				},
-				"lib.rego": {
-					RawText: `package lib`,
+			},
+			createLocation: createLocation(7, 10, "src.rego"),
+			expectResult:   []*ast.Location{},
+		},
+		"Should return the argument of the function from the else clause": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+f(x) = 1 {
+	x > 0
+} else = y {
+	y := x
+}`,
 				},
 			},
-			createLocation: createLocation(3, 13, "src.rego"),
+			createLocation: createLocation(6, 7, "src.rego"),
 			expectResult: []*ast.Location{
 				{
-					Row:    1,
-					Col:    1,
-					Offset: len(""),
-					Text:   []byte("package"),
-					File:   "lib.rego",
+					Row:    3,
+					Col:    3,
+					Offset: len("package main\n\nf("),
+					Text:   []byte("x"),
+					File:   "src.rego",
 				},
 			},
 		},
-	}
+		"Should return definition of the value of the else clause in its body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
 
-	for n, tt := range tests {
-		t.Run(n, func(t *testing.T) {
-			p, err := source.NewProjectWithFiles(tt.files)
-			if err != nil {
-				t.Fatalf("failed to create project: %v", err)
-			}
+f(x) = 1 {
+	x > 0
+} else = y {
+	y := x
+}`,
+				},
+			},
+			createLocation: createLocation(5, 10, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    2,
+					Offset: len("package main\n\nf(x) = 1 {\n\tx > 0\n} else = y {\n\t"),
+					Text:   []byte("y"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition which is the argument of the standalone call": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
 
-			location := tt.createLocation(tt.files)
-			got, err := p.LookupDefinition(location)
-			if !errors.Is(err, tt.expectErr) {
-				t.Fatalf("LookupDefinition should return error expect %v, but got %v", tt.expectErr, err)
-			}
+allow {
+	count(input.users, n)
+	n > 0
+}`,
+				},
+			},
+			createLocation: createLocation(5, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    21,
+					Offset: len("package main\n\nallow {\n\tcount(input.users, "),
+					Text:   []byte("n"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should not return the input argument of the call as definition of the rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
 
-			if diff := cmp.Diff(tt.expectResult, got, cmp.Comparer(func(x, y []*ast.Location) bool {
-				return reflect.DeepEqual(x, y)
-			})); diff != "" {
-				t.Errorf("LookupDefinition result diff (-expect +got):\n%s", diff)
+names := {"a"}
+
+allow {
+	count(names, n)
+	is_set(names)
+}`,
+				},
+			},
+			createLocation: createLocation(7, 13, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("names"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should not return the output argument of the call as definition of the rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+n := 1
+
+allow {
+	count(input.users, n)
+	n > 0
+}`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("n"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition in the comprehension body from the comprehension head": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	ids := [x | x := input.users[_].id]
+	count(ids) > 0
+}`,
+				},
+			},
+			createLocation: createLocation(4, 10, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    14,
+					Offset: len("package main\n\nallow {\n\tids := [x | "),
+					Text:   []byte("x"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition in the comprehension body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	names := {n | u := input.users[_]; n := u.name}
+	count(names) > 0
+}`,
+				},
+			},
+			createLocation: createLocation(4, 42, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    16,
+					Offset: len("package main\n\nallow {\n\tnames := {n | "),
+					Text:   []byte("u"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should not return definition in the comprehension from the outer scope": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	ids := [x | x := input.users[_].id]
+	x == ids[0]
+}`,
+				},
+			},
+			createLocation: createLocation(5, 2, "src.rego"),
+			expectResult:   []*ast.Location{},
+		},
+		"Should return definition of the variable used as the object value": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	name := input.user.name
+	user := {"name": name, "admin": false}
+	user.admin
+}`,
+				},
+			},
+			createLocation: createLocation(5, 19, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    2,
+					Offset: len("package main\n\nallow {\n\t"),
+					Text:   []byte("name"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition of the variable in the set literal": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	admin := "admin"
+	roles := {admin, "guest"}
+	input.role == roles[_]
+}`,
+				},
+			},
+			createLocation: createLocation(5, 12, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    2,
+					Offset: len("package main\n\nallow {\n\t"),
+					Text:   []byte("admin"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition of the variable in the set literal in the comprehension": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	admin := "admin"
+	roles := [r | r := {admin, "guest"}[_]]
+	input.role == roles[_]
+}`,
+				},
+			},
+			createLocation: createLocation(5, 22, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    2,
+					Offset: len("package main\n\nallow {\n\t"),
+					Text:   []byte("admin"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return each definition of the incremental rule once in order": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+violation[msg] {
+	deny[msg]
+}`,
+				},
+				"b.rego": {
+					RawText: `package main
+
+deny["b"]`,
+				},
+				"a.rego": {
+					RawText: `package main
+
+deny["a1"]
+
+deny["a2"]`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("deny"),
+					File:   "a.rego",
+				},
+				{
+					Row:    5,
+					Col:    1,
+					Offset: len("package main\n\ndeny[\"a1\"]\n\n"),
+					Text:   []byte("deny"),
+					File:   "a.rego",
+				},
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("deny"),
+					File:   "b.rego",
+				},
+			},
+		},
+		"Should return every clause of the partial set rule in the other package": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib
+
+violation[msg] {
+	lib.deny[msg]
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+import future.keywords.contains
+import future.keywords.if
+
+deny contains msg if {
+	msg := "contains"
+}
+
+deny[msg] {
+	msg := "bracket"
+}
+
+deny.admin contains msg if {
+	msg := "ref head"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 6, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    1,
+					Offset: len("package lib\n\nimport future.keywords.contains\nimport future.keywords.if\n\n"),
+					Text:   []byte("deny"),
+					File:   "lib.rego",
+				},
+				{
+					Row:    10,
+					Col:    1,
+					Offset: len("package lib\n\nimport future.keywords.contains\nimport future.keywords.if\n\ndeny contains msg if {\n\tmsg := \"contains\"\n}\n\n"),
+					Text:   []byte("deny"),
+					File:   "lib.rego",
+				},
+				{
+					Row:    14,
+					Col:    1,
+					Offset: len("package lib\n\nimport future.keywords.contains\nimport future.keywords.if\n\ndeny contains msg if {\n\tmsg := \"contains\"\n}\n\ndeny[msg] {\n\tmsg := \"bracket\"\n}\n\n"),
+					Text:   []byte("deny"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return definition of the rule referred from data": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+allow {
+	data.lib.auth.is_admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib.auth
+
+is_admin {
+	input.admin
+}`,
+				},
+			},
+			createLocation: createLocation(4, 16, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package lib.auth\n\n"),
+					Text:   []byte("is_admin"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should resolve the package under data rather than the import of the same name": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.other as lib
+
+allow {
+	data.lib.is_admin
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_admin {
+	input.admin
+}`,
+				},
+				"other.rego": {
+					RawText: `package other
+
+is_admin {
+	input.other
+}`,
+				},
+			},
+			createLocation: createLocation(6, 11, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package lib\n\n"),
+					Text:   []byte("is_admin"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return parent package definition from the middle segment of the import": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib.util`,
+				},
+				"lib.rego": {
+					RawText: `package lib`,
+				},
+				"util.rego": {
+					RawText: `package lib.util`,
+				},
+			},
+			createLocation: createLocation(3, 13, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: len(""),
+					Text:   []byte("package"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return package definition from the last segment of the import": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib.util`,
+				},
+				"lib.rego": {
+					RawText: `package lib`,
+				},
+				"util.rego": {
+					RawText: `package lib.util`,
+				},
+			},
+			createLocation: createLocation(3, 18, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: len(""),
+					Text:   []byte("package"),
+					File:   "util.rego",
+				},
+			},
+		},
+		"Should fall back to the imported package when the parent package doesn't exist": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib.util`,
+				},
+				"util.rego": {
+					RawText: `package lib.util`,
+				},
+			},
+			createLocation: createLocation(3, 13, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: len(""),
+					Text:   []byte("package"),
+					File:   "util.rego",
+				},
+			},
+		},
+		"Should return definition from import sentense to the library file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import data.lib`,
+				},
+				"lib.rego": {
+					RawText: `package lib`,
+				},
+			},
+			createLocation: createLocation(3, 13, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    1,
+					Col:    1,
+					Offset: len(""),
+					Text:   []byte("package"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return definition of the target of with clause": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package main
+
+import data.lib
+
+test_allow {
+	allow with lib.is_admin as true
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_admin {
+	input.user == "admin"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 17, "src_test.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package lib\n\n"),
+					Text:   []byte("is_admin"),
+					File:   "lib.rego",
+				},
+			},
+		},
+		"Should return definition of the value of with clause": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package main
+
+test_allow {
+	user := {"name": "admin"}
+	allow with input.user as user
+}`,
+				},
+			},
+			createLocation: createLocation(5, 27, "src_test.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    2,
+					Offset: len("package main\n\ntest_allow {\n\t"),
+					Text:   []byte("user"),
+					File:   "src_test.rego",
+				},
+			},
+		},
+		"Should return the document of the built-in for the infix operator": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import future.keywords.in
+
+allow {
+	input.user == "admin"
+	"admin" in input.roles
+}`,
+				},
+			},
+			createLocation: createLocation(6, 13, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:  1,
+					Col:  1,
+					Text: []byte("equal"),
+					File: source.BuiltinDocumentURL + "#builtin-comparison-equal",
+				},
+			},
+		},
+		"Should return the document of the built-in for the in operator": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import future.keywords.in
+
+allow {
+	input.user == "admin"
+	"admin" in input.roles
+}`,
+				},
+			},
+			createLocation: createLocation(7, 10, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:  1,
+					Col:  1,
+					Text: []byte("internal.member_2"),
+					File: source.BuiltinDocumentURL + "#built-in-functions",
+				},
+			},
+		},
+		"Should return definition of the variable in the rego.v1 rule body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import rego.v1
+
+allow if {
+	user := input.user
+	user.admin
+}`,
+				},
+			},
+			createLocation: createLocation(7, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    2,
+					Offset: len("package main\n\nimport rego.v1\n\nallow if {\n\t"),
+					Text:   []byte("user"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return definition of the head value which is assigned in the rego.v1 rule body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import rego.v1
+
+name := n if {
+	n := input.name
+}`,
+				},
+			},
+			createLocation: createLocation(5, 9, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    2,
+					Offset: len("package main\n\nimport rego.v1\n\nname := n if {\n\t"),
+					Text:   []byte("n"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the key of every from the body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import rego.v1
+
+allow if {
+	every key, value in input.list {
+		key > 0
+		value != ""
+	}
+}`,
+				},
+			},
+			createLocation: createLocation(7, 5, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    8,
+					Offset: len("package main\n\nimport rego.v1\n\nallow if {\n\tevery "),
+					Text:   []byte("key"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the value of every from the body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package main
+
+import rego.v1
+
+allow if {
+	every key, value in input.list {
+		key > 0
+		value != ""
+	}
+}`,
+				},
+			},
+			createLocation: createLocation(8, 7, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    13,
+					Offset: len("package main\n\nimport rego.v1\n\nallow if {\n\tevery key, "),
+					Text:   []byte("value"),
+					File:   "src.rego",
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			p, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := p.LookupDefinition(location)
+			if !errors.Is(err, tt.expectErr) {
+				t.Fatalf("LookupDefinition should return error expect %v, but got %v", tt.expectErr, err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got, cmp.Comparer(func(x, y []*ast.Location) bool {
+				return reflect.DeepEqual(x, y)
+			})); diff != "" {
+				t.Errorf("LookupDefinition result diff (-expect +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLookupDefinitionAcrossFiles(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package main
+
+allow {
+	mem_multiple("E") > 0
+}
+
+mem_multiple("P") = 1000000000000000000`,
+		},
+		"units.rego": {
+			RawText: `package main
+
+mem_multiple("E") = 1000000000000000000000
+
+mem_multiple("T") = 1000000000000`,
+		},
+	}
+
+	p, err := source.NewProjectWithFiles(files)
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+
+	got, err := p.LookupDefinition(createLocation(4, 2, "src.rego")(files))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []*ast.Location{
+		{
+			Row:    7,
+			Col:    1,
+			Offset: len("package main\n\nallow {\n\tmem_multiple(\"E\") > 0\n}\n\n"),
+			Text:   []byte("mem_multiple"),
+			File:   "src.rego",
+		},
+		{
+			Row:    3,
+			Col:    1,
+			Offset: len("package main\n\n"),
+			Text:   []byte("mem_multiple"),
+			File:   "units.rego",
+		},
+		{
+			Row:    5,
+			Col:    1,
+			Offset: len("package main\n\nmem_multiple(\"E\") = 1000000000000000000000\n\n"),
+			Text:   []byte("mem_multiple"),
+			File:   "units.rego",
+		},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("LookupDefinition result diff (-expect +got):\n%s", diff)
+	}
+}
+
+func TestLookupDefinitionWithMode(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package main
+
+violation[msg] {
+	msg := "denied"
+}
+
+allow {
+	violation
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		mode         source.DefinitionMode
+		expectResult []*ast.Location
+	}{
+		"Should return the head name": {
+			mode: source.DefinitionHeadName,
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("violation"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the whole rule": {
+			mode: source.DefinitionWholeRule,
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package main\n\n"),
+					Text:   []byte("violation[msg] {\n\tmsg := \"denied\"\n}"),
+					File:   "src.rego",
+				},
+			},
+		},
+		"Should return the head key": {
+			mode: source.DefinitionHeadKey,
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    11,
+					Offset: len("package main\n\nviolation["),
+					Text:   []byte("msg"),
+					File:   "src.rego",
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			p, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+			p.SetDefinitionMode(tt.mode)
+
+			location := createLocation(8, 3, "src.rego")(files)
+			got, err := p.LookupDefinition(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("LookupDefinition result diff (-expect +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLookupDefinitionWithUnqualifiedRefMode(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package main
+
+allow {
+	is_admin
+}`,
+		},
+		"lib.rego": {
+			RawText: `package lib
+
+is_admin {
+	input.admin
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		mode         source.UnqualifiedRefMode
+		expectResult []*ast.Location
+	}{
+		"Should not return the rule in the other package": {
+			mode:         source.UnqualifiedRefPackage,
+			expectResult: []*ast.Location{},
+		},
+		"Should return the rule of the same name in the workspace": {
+			mode: source.UnqualifiedRefWorkspace,
+			expectResult: []*ast.Location{
+				{
+					Row:    3,
+					Col:    1,
+					Offset: len("package lib\n\n"),
+					Text:   []byte("is_admin"),
+					File:   "lib.rego",
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			p, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+			p.SetUnqualifiedRefMode(tt.mode)
+
+			location := createLocation(4, 3, "src.rego")(files)
+			got, err := p.LookupDefinition(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("LookupDefinition result diff (-expect +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_RulesForPath(t *testing.T) {
+	files := map[string]source.File{
+		"authz.rego": {
+			RawText: `package authz
+
+default allow = false
+
+allow {
+	input.admin
+}
+
+deny[msg] {
+	msg := "denied"
+}`,
+		},
+		"authz_user.rego": {
+			RawText: `package authz.user
+
+name := input.user.name`,
+		},
+	}
+
+	tests := map[string]struct {
+		path        string
+		expectRules []string
+		expectErr   bool
+	}{
+		"Should return the rules of the full path": {
+			path:        "data.authz.allow",
+			expectRules: []string{"authz.rego:3 allow", "authz.rego:5 allow"},
+		},
+		"Should return the rule which produces the document under the path": {
+			path:        "data.authz.user.name.first",
+			expectRules: []string{"authz_user.rego:3 name"},
+		},
+		"Should return all rules of the package": {
+			path:        "data.authz.user",
+			expectRules: []string{"authz_user.rego:3 name"},
+		},
+		"Should return the rules of the package and its sub packages": {
+			path:        "data.authz",
+			expectRules: []string{"authz.rego:3 allow", "authz.rego:5 allow", "authz.rego:9 deny", "authz_user.rego:3 name"},
+		},
+		"Should return error when no rules produce the path": {
+			path:      "data.authz.unknown",
+			expectErr: true,
+		},
+		"Should return error when the path is not data": {
+			path:      "input.authz",
+			expectErr: true,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			p, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatalf("failed to create project: %v", err)
+			}
+
+			got, err := p.RulesForPath(ast.MustParseRef(tt.path))
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("RulesForPath should return error, but got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rules := make([]string, len(got))
+			for i, r := range got {
+				rules[i] = fmt.Sprintf("%s:%d %s", r.Location.File, r.Location.Row, r.Head.Name)
+			}
+			if diff := cmp.Diff(tt.expectRules, rules); diff != "" {
+				t.Errorf("RulesForPath result diff (-expect +got):\n%s", diff)
 			}
 		})
 	}
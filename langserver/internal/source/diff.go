@@ -1,10 +1,10 @@
 // Copyright 2019 The Go Authors. All rights reserved.
 // Use of this source code is governed by a BSD-style
-// license that can be found in the LICENSE file.package langserver
+// license that can be found in the LICENSE file.package source
 // Source:
 // https://github.com/golang/tools/blob/78b158585360beccadc3faac6e35759f491831f3/internal/lsp/diff/myers/diff.go
 
-package langserver
+package source
 
 import (
 	"strings"
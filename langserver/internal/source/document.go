@@ -1,6 +1,7 @@
 package source
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
@@ -10,6 +11,9 @@ const (
 	BuiltinDetail = `built-in function
 
 See https://www.openpolicyagent.org/docs/latest/policy-reference/#built-in-functions`
+
+	// BuiltinDocumentURL is the page which documents the built-in functions.
+	BuiltinDocumentURL = "https://www.openpolicyagent.org/docs/latest/policy-reference/"
 )
 
 type Document struct {
@@ -17,6 +21,222 @@ type Document struct {
 	Language string
 }
 
+type HoverResult struct {
+	Documents []Document
+}
+
+// Hover returns documents for the term at the location.
+// When the location is on the package declaration, it returns the package path.
+func (p *Project) Hover(location *ast.Location) (*HoverResult, error) {
+	module := p.GetModule(location.File)
+	if module != nil && module.Package != nil && inPackage(location, module.Package) {
+		return &HoverResult{
+			Documents: []Document{
+				{
+					Content:  module.Package.Path.String(),
+					Language: "rego",
+				},
+			},
+		}, nil
+	}
+
+	// count(split(input.path, "/"))
+	//             ^ the argument or ^ the call is described rather than the outer call
+	value, loc, err := p.InnermostExpr(location)
+	if err != nil {
+		return nil, err
+	}
+	switch v := value.(type) {
+	case ast.Call:
+		if l := v[0].Loc(); l != nil {
+			operator := *l
+			location = &operator
+		}
+	case ast.Null, ast.Boolean, ast.Number, ast.String, *ast.Array, ast.Object, ast.Set:
+		return &HoverResult{
+			Documents: []Document{
+				{
+					Content:  fmt.Sprintf("%s: %s", loc.Text, valueTypeName(v)),
+					Language: "rego",
+				},
+			},
+		}, nil
+	}
+
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, nil
+	}
+
+	docs := p.findImportedRuleDocument(term)
+	if len(docs) == 0 {
+		docs = p.findTermDocument(term)
+	}
+	if len(docs) == 0 {
+		return nil, nil
+	}
+
+	return &HoverResult{Documents: docs}, nil
+}
+
+// InnermostExpr returns the smallest expression which contains the location, like the call, the ref or the literal.
+// The ref is not split into its parts, but the call in the brackets of the ref is searched.
+//
+//	count(split(input.path, "/"))
+//	      ^ split(input.path, "/")
+//	            ^ input.path
+func (p *Project) InnermostExpr(location *ast.Location) (ast.Value, *ast.Location, error) {
+	policy := p.cache.Get(location.File)
+	if policy == nil {
+		return nil, nil, fmt.Errorf("file not found: %s", location.File)
+	}
+	if policy.Module == nil {
+		return nil, nil, nil
+	}
+
+	var value ast.Value
+	var loc *ast.Location
+	update := func(v ast.Value, l *ast.Location) {
+		if l == nil || !in(location, l) {
+			return
+		}
+		// the later one is nested in the former one of the same size.
+		if loc == nil || len(l.Text) <= len(loc.Text) {
+			value, loc = v, l
+		}
+	}
+
+	var vis *ast.GenericVisitor
+	vis = ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.Expr:
+			// the call of the expression has no term which wraps it.
+			if terms, ok := v.Terms.([]*ast.Term); ok {
+				update(ast.Call(terms), v.Location)
+			}
+		case *ast.Term:
+			if _, ok := v.Value.(ast.Call); ok && v.Location != nil {
+				// the location of the nested call has the extra `)` of the outer call.
+				l := *v.Location
+				l.Text = callText(l.Text)
+				update(v.Value, &l)
+			} else {
+				update(v.Value, v.Location)
+			}
+			if ref, ok := v.Value.(ast.Ref); ok {
+				for _, t := range ref[1:] {
+					switch t.Value.(type) {
+					case ast.Var, ast.String, ast.Number:
+					default:
+						vis.Walk(t)
+					}
+				}
+				return true
+			}
+		}
+		return false
+	})
+	for _, r := range policy.Module.Rules {
+		if in(location, r.Loc()) {
+			vis.Walk(r)
+		}
+	}
+	return value, loc, nil
+}
+
+// callText returns the text of the call until `)` which closes the arguments.
+// The parentheses in the strings are skipped.
+func callText(text []byte) []byte {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return text[:i+1]
+			}
+		}
+	}
+	return text
+}
+
+func inPackage(location *ast.Location, pkg *ast.Package) bool {
+	if pkg.Location == nil || len(pkg.Path) == 0 {
+		return false
+	}
+	last := pkg.Path[len(pkg.Path)-1].Loc()
+	if last == nil {
+		return false
+	}
+	return location.Offset >= pkg.Location.Offset && location.Offset <= last.Offset+len(last.Text)
+}
+
+// findImportedRuleDocument returns the resolved package path and the head of the rules referred via import.
+//
+//	import data.lib
+//	lib.is_hello(msg) -> data.lib.is_hello(msg)
+func (p *Project) findImportedRuleDocument(term *ast.Term) []Document {
+	ref, ok := term.Value.(ast.Ref)
+	if !ok || len(ref) < 2 {
+		return nil
+	}
+
+	module := p.GetModule(term.Loc().File)
+	if module == nil {
+		return nil
+	}
+
+	pkg := p.findPolicyRef(term)
+	if pkg == nil || pkg.Equal(module.Package.Path) {
+		return nil
+	}
+
+	result := make([]Document, 0)
+	annotations := make([]Document, 0)
+	exists := make(map[string]struct{})
+	for _, rule := range p.findRulesInModule(term) {
+		if mod := p.GetModule(rule.Location.File); mod != nil {
+			for _, a := range ruleAnnotations(mod, rule) {
+				doc, ok := createDocForAnnotations(a)
+				if _, exist := exists[doc]; !ok || exist {
+					continue
+				}
+				exists[doc] = struct{}{}
+				annotations = append(annotations, Document{
+					Content:  doc,
+					Language: "markdown",
+				})
+			}
+		}
+
+		content := fmt.Sprintf("%s.%s", pkg.String(), rule.Head.Location.Text)
+		if _, ok := exists[content]; ok {
+			continue
+		}
+		exists[content] = struct{}{}
+		result = append(result, Document{
+			Content:  content,
+			Language: "rego",
+		})
+	}
+	return append(result, annotations...)
+}
+
 func (p *Project) TermDocument(loc *ast.Location) ([]Document, error) {
 	term, err := p.SearchTargetTerm(loc)
 	if err != nil {
@@ -71,6 +291,8 @@ func (p *Project) findTermDocumentInModule(term *ast.Term) []Document {
 	}
 
 	result := make([]Document, 0)
+	annotations := make([]Document, 0)
+	exists := make(map[string]struct{})
 	for _, mod := range searchPolicies {
 		for _, rule := range mod.Rules {
 			if rule.Head.Name.String() == word {
@@ -78,12 +300,61 @@ func (p *Project) findTermDocumentInModule(term *ast.Term) []Document {
 					Content:  createDocForRule(rule),
 					Language: "rego",
 				})
+
+				for _, a := range ruleAnnotations(mod, rule) {
+					doc, ok := createDocForAnnotations(a)
+					if _, exist := exists[doc]; !ok || exist {
+						continue
+					}
+					exists[doc] = struct{}{}
+					annotations = append(annotations, Document{
+						Content:  doc,
+						Language: "markdown",
+					})
+				}
 			}
 		}
 	}
+	return append(result, annotations...)
+}
+
+// ruleAnnotations returns the metadata annotations which are written just above the rule.
+func ruleAnnotations(module *ast.Module, rule *ast.Rule) []*ast.Annotations {
+	result := make([]*ast.Annotations, 0)
+	for _, a := range module.Annotations {
+		if (a.Scope != "rule" && a.Scope != "document") || a.Location == nil || a.Location.Row >= rule.Location.Row {
+			continue
+		}
+
+		attached := true
+		for _, r := range module.Rules {
+			if r.Location.Row > a.Location.Row && r.Location.Row < rule.Location.Row {
+				attached = false
+				break
+			}
+		}
+		if attached {
+			result = append(result, a)
+		}
+	}
 	return result
 }
 
+// createDocForAnnotations returns the title and the description of the annotations as markdown.
+func createDocForAnnotations(a *ast.Annotations) (string, bool) {
+	parts := make([]string, 0, 2)
+	if a.Title != "" {
+		parts = append(parts, fmt.Sprintf("**%s**", a.Title))
+	}
+	if a.Description != "" {
+		parts = append(parts, a.Description)
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, "\n\n"), true
+}
+
 func createDocForRule(rule *ast.Rule) string {
 	detail := string(rule.Loc().Text)
 	if detail == "default" {
@@ -126,3 +126,301 @@ See https://www.openpolicyagent.org/docs/latest/policy-reference/#built-in-funct
 		})
 	}
 }
+
+func TestProject_InnermostExpr(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+allow {
+	count(split(input.path, "/")) > 1
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		createLocation createLocationFunc
+		expectText     string
+	}{
+		"Should return the outer call": {
+			createLocation: createLocation(4, 30, "src.rego"),
+			expectText:     `count(split(input.path, "/"))`,
+		},
+		"Should return the nested call": {
+			createLocation: createLocation(4, 24, "src.rego"),
+			expectText:     `split(input.path, "/")`,
+		},
+		"Should return the whole ref of the argument": {
+			createLocation: createLocation(4, 19, "src.rego"),
+			expectText:     "input.path",
+		},
+		"Should return the literal": {
+			createLocation: createLocation(4, 26, "src.rego"),
+			expectText:     `"/"`,
+		},
+		"Should return the infix operator": {
+			createLocation: createLocation(4, 31, "src.rego"),
+			expectText:     ">",
+		},
+	}
+
+	project, err := source.NewProjectWithFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			_, loc, err := project.InnermostExpr(tt.createLocation(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loc == nil {
+				t.Fatal("InnermostExpr should return the location")
+			}
+			if got := string(loc.Text); got != tt.expectText {
+				t.Errorf("InnermostExpr should return %q, but got %q", tt.expectText, got)
+			}
+		})
+	}
+}
+
+func TestProject_Hover(t *testing.T) {
+	tests := map[string]struct {
+		files          map[string]source.File
+		createLocation createLocationFunc
+		expectResult   *source.HoverResult
+	}{
+		"Should show rule body": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	method(msg)
+}
+
+method(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content: `method(msg) {
+	msg == "hello"
+}`,
+						Language: "rego",
+					},
+				},
+			},
+		},
+		"Should show metadata annotations of the rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	is_admin
+	msg := "admin"
+}
+
+# METADATA
+# title: Admin check
+# description: is_admin is true when the user has the admin role.
+is_admin {
+	input.user.role == "admin"
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content: `is_admin {
+	input.user.role == "admin"
+}`,
+						Language: "rego",
+					},
+					{
+						Content:  "**Admin check**\n\nis_admin is true when the user has the admin role.",
+						Language: "markdown",
+					},
+				},
+			},
+		},
+		"Should show metadata annotations of the imported rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+violation[msg] {
+	lib.is_hello(msg)
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+# METADATA
+# description: is_hello checks the greeting.
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 7, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  "data.lib.is_hello(msg)",
+						Language: "rego",
+					},
+					{
+						Content:  "is_hello checks the greeting.",
+						Language: "markdown",
+					},
+				},
+			},
+		},
+		"Should show builtin function signature": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	sprintf("msg: %s", [msg])
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  "sprintf(string, array[any])",
+						Language: "rego",
+					},
+					{
+						Content:  source.BuiltinDetail,
+						Language: "markdown",
+					},
+				},
+			},
+		},
+		"Should show resolved package path and rule head for imported rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+violation[msg] {
+	lib.is_hello(msg)
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 7, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  "data.lib.is_hello(msg)",
+						Language: "rego",
+					},
+				},
+			},
+		},
+		"Should show the signature of the nested call": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	count(split(input.path, "/")) > 1
+}`,
+				},
+			},
+			createLocation: createLocation(4, 24, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  "split(string, string)",
+						Language: "rego",
+					},
+					{
+						Content:  source.BuiltinDetail,
+						Language: "markdown",
+					},
+				},
+			},
+		},
+		"Should show the type of the argument of the nested call": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	count(split(input.path, "/")) > 1
+}`,
+				},
+			},
+			createLocation: createLocation(4, 26, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  `"/": string`,
+						Language: "rego",
+					},
+				},
+			},
+		},
+		"Should show package path on package declaration": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src.main
+
+violation[msg] {
+	msg := "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(1, 12, "src.rego"),
+			expectResult: &source.HoverResult{
+				Documents: []source.Document{
+					{
+						Content:  "data.src.main",
+						Language: "rego",
+					},
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.Hover(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("Hover result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,12 @@
+package source
+
+// CompletionCacheHits returns the number of the completions which reuse the cached candidates.
+func (p *Project) CompletionCacheHits() int {
+	p.completionCache.mu.Lock()
+	defer p.completionCache.mu.Unlock()
+
+	return p.completionCache.hits
+}
+
+// CurrentWord is currentWord for the tests of the word boundary.
+var CurrentWord = currentWord
@@ -0,0 +1,187 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// WorkspaceEdit is the text edits for each file.
+type WorkspaceEdit map[string][]TextEdit
+
+// ExtractRule moves the body expressions in the selection into a new rule named name,
+// and replaces the selection with the call of the new rule.
+// The variables which are bound before the selection are passed as the arguments.
+//
+//	allow {                        allow {
+//		user := input.user             user := input.user
+//		user.name == "admin"   ->      is_admin(user)
+//		user.enabled               }
+//	}
+//	                               is_admin(user) {
+//	                                   user.name == "admin"
+//	                                   user.enabled
+//	                               }
+func (p *Project) ExtractRule(path string, selection Range, name string) (WorkspaceEdit, error) {
+	if !varNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("%q is not a valid name", name)
+	}
+	if isReservedWord(name) {
+		return nil, fmt.Errorf("%q is a reserved word", name)
+	}
+
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if len(policy.Errs) > 0 || policy.Module == nil {
+		return nil, fmt.Errorf("failed to parse %s", path)
+	}
+
+	// rule names, imports and built-in functions are not bound in the rule.
+	globals := make(map[ast.Var]struct{})
+	for _, m := range p.cache.FindPolicies(policy.Module.Package.Path) {
+		for _, r := range m.Rules {
+			if r.Head.Name.Equal(ast.Var(name)) {
+				return nil, fmt.Errorf("rule %s already exists in %s", name, policy.Module.Package.Path.String())
+			}
+			globals[r.Head.Name] = struct{}{}
+		}
+	}
+	for _, imp := range policy.Module.Imports {
+		globals[ast.Var(importToLabel(imp))] = struct{}{}
+	}
+	isLocal := func(v ast.Var) bool {
+		if _, ok := globals[v]; ok {
+			return false
+		}
+		if _, ok := ast.BuiltinMap[string(v)]; ok {
+			return false
+		}
+		return !isIgnoredVar(v) && !ast.RootDocumentNames.Contains(ast.NewTerm(v))
+	}
+
+	rule, start, end := findSelectedExprs(policy.RawText, policy.Module, selection)
+	if rule == nil {
+		return nil, fmt.Errorf("no expressions are selected")
+	}
+	selected := rule.Body[start:end]
+
+	bound := make(map[ast.Var]struct{})
+	for _, a := range rule.Head.Args {
+		ast.WalkVars(a, func(v ast.Var) bool {
+			bound[v] = struct{}{}
+			return false
+		})
+	}
+	for _, e := range rule.Body[:start] {
+		ast.WalkVars(e, func(v ast.Var) bool {
+			bound[v] = struct{}{}
+			return false
+		})
+	}
+
+	params := make([]string, 0)
+	exists := make(map[ast.Var]struct{})
+	for _, e := range selected {
+		ast.WalkVars(e, func(v ast.Var) bool {
+			if _, ok := bound[v]; !ok || !isLocal(v) {
+				return false
+			}
+			if _, ok := exists[v]; ok {
+				return false
+			}
+			exists[v] = struct{}{}
+			params = append(params, string(v))
+			return false
+		})
+	}
+
+	// the variables which are bound in the selection can't be returned from the new rule.
+	declared := make(map[ast.Var]struct{})
+	for _, e := range selected {
+		ast.WalkVars(e, func(v ast.Var) bool {
+			if _, ok := bound[v]; !ok && isLocal(v) {
+				declared[v] = struct{}{}
+			}
+			return false
+		})
+	}
+	var usedAfter []ast.Var
+	after := func(v ast.Var) bool {
+		if _, ok := declared[v]; ok {
+			usedAfter = append(usedAfter, v)
+		}
+		return false
+	}
+	ast.WalkVars(rule.Head, after)
+	ast.WalkVars(rule.Body[end:], after)
+	if len(usedAfter) > 0 {
+		return nil, fmt.Errorf("%s is bound in the selection and used after it", usedAfter[0])
+	}
+
+	call := name
+	if len(params) > 0 {
+		call = fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+	}
+
+	body := make([]string, len(selected))
+	for i, e := range selected {
+		body[i] = "\t" + string(e.Location.Text)
+	}
+
+	first, last := selected[0].Location, selected[len(selected)-1].Location
+	lastEnd := offsetToPosition(policy.RawText, last.Offset+len(last.Text))
+	ruleEnd := offsetToPosition(policy.RawText, rule.Location.Offset+len(rule.Location.Text))
+	return WorkspaceEdit{
+		path: {
+			{
+				Row:  first.Row,
+				Col:  first.Col,
+				Text: call,
+				End:  &lastEnd,
+			},
+			{
+				Row:  ruleEnd.Row,
+				Col:  ruleEnd.Col,
+				Text: fmt.Sprintf("\n\n%s {\n%s\n}", call, strings.Join(body, "\n")),
+			},
+		},
+	}, nil
+}
+
+// findSelectedExprs returns the rule and the range of the body expressions which are in the selection.
+func findSelectedExprs(rawText string, module *ast.Module, selection Range) (*ast.Rule, int, int) {
+	for _, r := range module.Rules {
+		for rule := r; rule != nil; rule = rule.Else {
+			start, end := -1, -1
+			for i, e := range rule.Body {
+				if e.Location == nil {
+					continue
+				}
+				exprStart := Position{Row: e.Location.Row, Col: e.Location.Col}
+				exprEnd := offsetToPosition(rawText, e.Location.Offset+len(e.Location.Text))
+				if positionLess(exprStart, selection.Start) || positionLess(selection.End, exprEnd) {
+					continue
+				}
+				if start < 0 {
+					start = i
+				}
+				end = i + 1
+			}
+			if start >= 0 {
+				// the top-level rule contains the else rules, so the new rule is inserted after it.
+				return &ast.Rule{Head: rule.Head, Body: rule.Body, Location: r.Location}, start, end
+			}
+		}
+	}
+	return nil, 0, 0
+}
+
+func positionLess(a, b Position) bool {
+	if a.Row != b.Row {
+		return a.Row < b.Row
+	}
+	return a.Col < b.Col
+}
@@ -0,0 +1,129 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_ExtractRule(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		selection   source.Range
+		name        string
+		expectEdits source.WorkspaceEdit
+		expectErr   string
+	}{
+		"Should extract the expressions with the captured variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	user := input.user
+	user.name == "admin"
+	count(user.roles) > 0
+}`,
+				},
+			},
+			path: "src.rego",
+			selection: source.Range{
+				Start: source.Position{Row: 5, Col: 1},
+				End:   source.Position{Row: 6, Col: 23},
+			},
+			name: "is_admin",
+			expectEdits: source.WorkspaceEdit{
+				"src.rego": {
+					{Row: 5, Col: 2, Text: "is_admin(user)", End: &source.Position{Row: 6, Col: 23}},
+					{Row: 7, Col: 2, Text: "\n\nis_admin(user) {\n\tuser.name == \"admin\"\n\tcount(user.roles) > 0\n}"},
+				},
+			},
+		},
+		"Should extract the expressions without arguments": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.user.name == "admin"
+}`,
+				},
+			},
+			path: "src.rego",
+			selection: source.Range{
+				Start: source.Position{Row: 4, Col: 1},
+				End:   source.Position{Row: 5, Col: 1},
+			},
+			name: "is_admin",
+			expectEdits: source.WorkspaceEdit{
+				"src.rego": {
+					{Row: 4, Col: 2, Text: "is_admin", End: &source.Position{Row: 4, Col: 28}},
+					{Row: 5, Col: 2, Text: "\n\nis_admin {\n\tinput.user.name == \"admin\"\n}"},
+				},
+			},
+		},
+		"Should reject when the variable bound in the selection is used after it": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	user := input.user
+	msg := user.name
+}`,
+				},
+			},
+			path: "src.rego",
+			selection: source.Range{
+				Start: source.Position{Row: 4, Col: 1},
+				End:   source.Position{Row: 4, Col: 20},
+			},
+			name:      "get_user",
+			expectErr: "user is bound in the selection and used after it",
+		},
+		"Should reject when no expression is selected": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.user.name == "admin"
+}`,
+				},
+			},
+			path: "src.rego",
+			selection: source.Range{
+				Start: source.Position{Row: 1, Col: 1},
+				End:   source.Position{Row: 2, Col: 1},
+			},
+			name:      "is_admin",
+			expectErr: "no expressions are selected",
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.ExtractRule(tt.path, tt.selection, tt.name)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Fatalf("ExtractRule should return error %q, but got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectEdits, got); diff != "" {
+				t.Errorf("ExtractRule result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
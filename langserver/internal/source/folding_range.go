@@ -0,0 +1,116 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type FoldingRangeKind int
+
+const (
+	FoldingRegion FoldingRangeKind = iota + 1
+	FoldingImports
+)
+
+// FoldingRange is the range which can be folded.
+// For the rule body, Start is the position of `{` and End is the position of `}`.
+type FoldingRange struct {
+	Range Range
+	Kind  FoldingRangeKind
+}
+
+// FoldingRanges returns the folding range of each rule body and the block of the imports.
+// The else rules have their own folding ranges.
+//
+//	allow {     -+
+//		...      |
+//	} else {    -+ -+
+//		...         |
+//	}           ----+
+func (p *Project) FoldingRanges(path string) ([]FoldingRange, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	module := policy.Module
+	if module == nil {
+		return []FoldingRange{}, nil
+	}
+
+	result := make([]FoldingRange, 0)
+	if len(module.Imports) > 1 {
+		first, last := module.Imports[0].Loc(), module.Imports[len(module.Imports)-1].Loc()
+		if first != nil && last != nil && last.Offset <= len(policy.RawText) {
+			// the location of the import is only `import` keyword, so the block ends at the end of the line.
+			end := len(policy.RawText)
+			if i := strings.Index(policy.RawText[last.Offset:], "\n"); i >= 0 {
+				end = last.Offset + i
+			}
+			result = append(result, FoldingRange{
+				Range: Range{
+					Start: offsetToPosition(policy.RawText, first.Offset),
+					End:   offsetToPosition(policy.RawText, end),
+				},
+				Kind: FoldingImports,
+			})
+		}
+	}
+
+	for _, rule := range module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			start, end, ok := ruleBodyBraces(policy.RawText, r)
+			if !ok {
+				continue
+			}
+			result = append(result, FoldingRange{
+				Range: Range{
+					Start: offsetToPosition(policy.RawText, start),
+					End:   offsetToPosition(policy.RawText, end),
+				},
+				Kind: FoldingRegion,
+			})
+		}
+	}
+
+	// the range in one line cannot be folded.
+	ranges := make([]FoldingRange, 0, len(result))
+	for _, r := range result {
+		if r.Range.Start.Row < r.Range.End.Row {
+			ranges = append(ranges, r)
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return positionLess(ranges[i].Range.Start, ranges[j].Range.Start)
+	})
+	return ranges, nil
+}
+
+// ruleBodyBraces returns the offsets of `{` and `}` of the rule body.
+// The rule without the braces like `a := 1` or `allow if input.x` returns false.
+func ruleBodyBraces(rawText string, rule *ast.Rule) (int, int, bool) {
+	loc := rule.Loc()
+	if loc == nil || len(rule.Body) == 0 || isGeneratedBody(rule) {
+		return 0, 0, false
+	}
+
+	// the location of the first rule includes the else rules.
+	end := loc.Offset + len(loc.Text)
+	if rule.Else != nil && rule.Else.Loc() != nil {
+		end = rule.Else.Loc().Offset
+	}
+
+	first, last := rule.Body[0].Loc(), rule.Body[len(rule.Body)-1].Loc()
+	if first == nil || last == nil || end > len(rawText) || first.Offset < loc.Offset || last.Offset+len(last.Text) > end {
+		return 0, 0, false
+	}
+
+	before := strings.TrimRight(rawText[loc.Offset:first.Offset], " \t\r\n")
+	after := strings.TrimRight(rawText[last.Offset+len(last.Text):end], " \t\r\n")
+	if !strings.HasSuffix(before, "{") || !strings.HasSuffix(after, "}") {
+		return 0, 0, false
+	}
+	return loc.Offset + len(before) - 1, last.Offset + len(last.Text) + len(after) - 1, true
+}
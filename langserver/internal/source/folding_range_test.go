@@ -0,0 +1,115 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_FoldingRanges(t *testing.T) {
+	tests := map[string]struct {
+		files        map[string]source.File
+		path         string
+		expectRanges []source.FoldingRange
+	}{
+		"Should fold the imports and the rule bodies": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+import future.keywords.in
+
+allow {
+	"admin" in input.roles
+}
+
+default deny := false
+
+x := 1
+
+violation[msg] {
+	msg := "bad"
+}`,
+				},
+			},
+			path: "src.rego",
+			expectRanges: []source.FoldingRange{
+				{
+					Range: source.Range{Start: source.Position{Row: 3, Col: 1}, End: source.Position{Row: 4, Col: 26}},
+					Kind:  source.FoldingImports,
+				},
+				{
+					Range: source.Range{Start: source.Position{Row: 6, Col: 7}, End: source.Position{Row: 8, Col: 1}},
+					Kind:  source.FoldingRegion,
+				},
+				{
+					Range: source.Range{Start: source.Position{Row: 14, Col: 16}, End: source.Position{Row: 16, Col: 1}},
+					Kind:  source.FoldingRegion,
+				},
+			},
+		},
+		"Should fold each else rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+authorize = "allow" {
+	input.user == "admin"
+} else = "deny" {
+	input.path[0] == "admin"
+} else = "unknown" {
+	true
+}`,
+				},
+			},
+			path: "src.rego",
+			expectRanges: []source.FoldingRange{
+				{
+					Range: source.Range{Start: source.Position{Row: 3, Col: 21}, End: source.Position{Row: 5, Col: 1}},
+					Kind:  source.FoldingRegion,
+				},
+				{
+					Range: source.Range{Start: source.Position{Row: 5, Col: 17}, End: source.Position{Row: 7, Col: 1}},
+					Kind:  source.FoldingRegion,
+				},
+				{
+					Range: source.Range{Start: source.Position{Row: 7, Col: 20}, End: source.Position{Row: 9, Col: 1}},
+					Kind:  source.FoldingRegion,
+				},
+			},
+		},
+		"Should not fold the body in one line": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+allow { input.admin }`,
+				},
+			},
+			path:         "src.rego",
+			expectRanges: []source.FoldingRange{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.FoldingRanges(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectRanges, got); diff != "" {
+				t.Errorf("FoldingRanges result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/format"
+)
+
+// Format returns the edits which format the file like `opa fmt`.
+// When the file has parse errors, Format returns no edits so as not to break the text which is being typed.
+func (p *Project) Format(path string) ([]TextEdit, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if len(policy.Errs) > 0 {
+		return []TextEdit{}, nil
+	}
+
+	formatted, err := format.Source(path, []byte(policy.RawText))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format: %w", err)
+	}
+
+	after := string(formatted)
+	if !strings.HasSuffix(policy.RawText, "\n") {
+		after = strings.TrimSuffix(after, "\n")
+	}
+	return computeEdits(policy.RawText, after), nil
+}
+
+// FormatRange returns the edits which format only the rules overlapping the range.
+func (p *Project) FormatRange(path string, rng Range) ([]TextEdit, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if len(policy.Errs) > 0 || policy.Module == nil {
+		return []TextEdit{}, nil
+	}
+
+	formatted, err := format.Source(path, []byte(policy.RawText))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format: %w", err)
+	}
+	formattedModule, err := ast.ParseModule(path, string(formatted))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse formatted text: %w", err)
+	}
+
+	// formatting keeps the order of the rules, so the rules can be matched by the index.
+	if len(policy.Module.Rules) != len(formattedModule.Rules) {
+		return []TextEdit{}, nil
+	}
+
+	beforeLines := splitLines(policy.RawText)
+	afterLines := splitLines(string(formatted))
+	edits := make([]TextEdit, 0)
+	for i, r := range policy.Module.Rules {
+		start, end := ruleRows(r)
+		if end < rng.Start.Row || rng.End.Row < start {
+			continue
+		}
+		afterStart, afterEnd := ruleRows(formattedModule.Rules[i])
+
+		before := strings.Join(beforeLines[start-1:end], "")
+		after := strings.Join(afterLines[afterStart-1:afterEnd], "")
+		if !strings.HasSuffix(before, "\n") {
+			after = strings.TrimSuffix(after, "\n")
+		}
+		for _, e := range computeEdits(before, after) {
+			e.Row += start - 1
+			if e.End != nil {
+				e.End = &Position{Row: e.End.Row + start - 1, Col: e.End.Col}
+			}
+			edits = append(edits, e)
+		}
+	}
+	return edits, nil
+}
+
+// ruleRows returns the first and the last rows of the rule including the else rules.
+func ruleRows(rule *ast.Rule) (int, int) {
+	loc := rule.Loc()
+	return loc.Row, loc.Row + strings.Count(string(loc.Text), "\n")
+}
+
+// computeEdits computes line based edits which convert before into after.
+func computeEdits(before, after string) []TextEdit {
+	ops := operations(splitLines(before), splitLines(after))
+	edits := make([]TextEdit, 0, len(ops))
+	for _, op := range ops {
+		switch op.Kind {
+		case Delete:
+			// Delete: before[i1:i2] is deleted.
+			edits = append(edits, TextEdit{
+				Row: op.I1 + 1,
+				Col: 1,
+				End: &Position{Row: op.I2 + 1, Col: 1},
+			})
+		case Insert:
+			// Insert: after[j1:j2] is inserted at before[i1:i1].
+			if content := strings.Join(op.Content, ""); content != "" {
+				edits = append(edits, TextEdit{
+					Row:  op.I1 + 1,
+					Col:  1,
+					Text: content,
+				})
+			}
+		}
+	}
+	return edits
+}
@@ -0,0 +1,174 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_Format(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectEdits []source.TextEdit
+	}{
+		"Should format the file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+  input.user == "admin"
+}
+`,
+				},
+			},
+			path: "src.rego",
+			expectEdits: []source.TextEdit{
+				{Row: 4, Col: 1, End: &source.Position{Row: 5, Col: 1}},
+				{Row: 5, Col: 1, Text: "\tinput.user == \"admin\"\n"},
+			},
+		},
+		"Should not add trailing newline when the file doesn't have it": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.user == "admin"
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectEdits: []source.TextEdit{},
+		},
+		"Should return no edits when the file has parse errors": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+  input.user ==
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectEdits: []source.TextEdit{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.Format(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expectEdits, got); diff != "" {
+				t.Errorf("Format result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_FormatRange(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		rng         source.Range
+		expectEdits []source.TextEdit
+	}{
+		"Should format only the rule in the range": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+  input.user == "admin"
+}
+
+deny {
+  input.user == "guest"
+}
+`,
+				},
+			},
+			path: "src.rego",
+			rng: source.Range{
+				Start: source.Position{Row: 8, Col: 1},
+				End:   source.Position{Row: 8, Col: 5},
+			},
+			expectEdits: []source.TextEdit{
+				{Row: 8, Col: 1, End: &source.Position{Row: 9, Col: 1}},
+				{Row: 9, Col: 1, Text: "\tinput.user == \"guest\"\n"},
+			},
+		},
+		"Should format all rules overlapping the range": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+  input.user == "admin"
+}
+
+deny {
+  input.user == "guest"
+}
+`,
+				},
+			},
+			path: "src.rego",
+			rng: source.Range{
+				Start: source.Position{Row: 5, Col: 1},
+				End:   source.Position{Row: 7, Col: 1},
+			},
+			expectEdits: []source.TextEdit{
+				{Row: 4, Col: 1, End: &source.Position{Row: 5, Col: 1}},
+				{Row: 5, Col: 1, Text: "\tinput.user == \"admin\"\n"},
+				{Row: 8, Col: 1, End: &source.Position{Row: 9, Col: 1}},
+				{Row: 9, Col: 1, Text: "\tinput.user == \"guest\"\n"},
+			},
+		},
+		"Should return no edits when no rule is in the range": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+  input.user == "admin"
+}
+`,
+				},
+			},
+			path: "src.rego",
+			rng: source.Range{
+				Start: source.Position{Row: 1, Col: 1},
+				End:   source.Position{Row: 2, Col: 1},
+			},
+			expectEdits: []source.TextEdit{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.FormatRange(tt.path, tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expectEdits, got); diff != "" {
+				t.Errorf("FormatRange result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package source
+
+import (
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type HighlightKind int
+
+const (
+	HighlightRead HighlightKind = iota + 1
+	HighlightWrite
+)
+
+type HighlightRange struct {
+	Location *ast.Location
+	Kind     HighlightKind
+}
+
+// DocumentHighlight returns the occurrences of the term at the location in the same file.
+// The occurrences which bind the value like `x := 1`, the rule key, the function argument and the rule name are HighlightWrite.
+func (p *Project) DocumentHighlight(location *ast.Location) ([]HighlightRange, error) {
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, nil
+	}
+
+	module := p.GetModule(location.File)
+	if module == nil {
+		return nil, nil
+	}
+
+	locations := make([]*ast.Location, 0)
+	writes := make(map[int]struct{})
+	if len(p.findDefinitionOutOfRule(term)) == 0 {
+		// Target term is defined in the rule
+		rule := p.findRuleForTerm(term.Loc())
+		if rule == nil {
+			return nil, nil
+		}
+		locations = append(locations, p.findReferencesInRule(term, rule)...)
+		addWriteOffsets(writes, rule)
+	} else {
+		for _, r := range p.findRulesInModule(term) {
+			if r.Location.File != location.File {
+				continue
+			}
+			name := ruleNameLocation(r)
+			locations = append(locations, name)
+			writes[name.Offset] = struct{}{}
+		}
+
+		t := getTermForPackage(term, module, module)
+		for _, rule := range module.Rules {
+			for r := rule; r != nil; r = r.Else {
+				locations = append(locations, p.findReferencesInRule(t, r)...)
+			}
+		}
+	}
+
+	result := make([]HighlightRange, 0)
+	for _, l := range uniqueLocations(locations) {
+		if l.File != location.File {
+			continue
+		}
+		kind := HighlightRead
+		if _, ok := writes[l.Offset]; ok {
+			kind = HighlightWrite
+		}
+		result = append(result, HighlightRange{Location: l, Kind: kind})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Location.Offset < result[j].Location.Offset
+	})
+	return result, nil
+}
+
+// addWriteOffsets adds the offsets of the variables which are bound in the rule.
+//
+//	f(x) { y := x }
+//	  ^    ^ write
+func addWriteOffsets(writes map[int]struct{}, rule *ast.Rule) {
+	add := func(t *ast.Term) {
+		ast.WalkTerms(t, func(t *ast.Term) bool {
+			switch t.Value.(type) {
+			case ast.Var:
+				if t.Location != nil {
+					writes[t.Location.Offset] = struct{}{}
+				}
+			case ast.Ref:
+				// input.x = y doesn't bind input.
+				return true
+			}
+			return false
+		})
+	}
+
+	if rule.Head.Key != nil {
+		add(rule.Head.Key)
+	}
+	for _, arg := range rule.Head.Args {
+		add(arg)
+	}
+	for _, b := range rule.Body {
+		if !b.IsAssignment() && !b.IsEquality() {
+			continue
+		}
+		add(b.Operand(0))
+	}
+}
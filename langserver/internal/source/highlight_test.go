@@ -0,0 +1,127 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_DocumentHighlight(t *testing.T) {
+	tests := map[string]struct {
+		files            map[string]source.File
+		createLocation   createLocationFunc
+		expectHighlights []source.HighlightRange
+	}{
+		"Should highlight the variable with write and read kinds": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	msg := "hello"
+	count(msg) > 0
+}`,
+				},
+			},
+			createLocation: createLocation(5, 8, "src.rego"),
+			expectHighlights: []source.HighlightRange{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    11,
+						Offset: len("package src\n\nviolation["),
+						Text:   []byte("msg"),
+						File:   "src.rego",
+					},
+					Kind: source.HighlightWrite,
+				},
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nviolation[msg] {\n\t"),
+						Text:   []byte("msg"),
+						File:   "src.rego",
+					},
+					Kind: source.HighlightWrite,
+				},
+				{
+					Location: &ast.Location{
+						Row:    5,
+						Col:    8,
+						Offset: len("package src\n\nviolation[msg] {\n\tmsg := \"hello\"\n\tcount("),
+						Text:   []byte("msg"),
+						File:   "src.rego",
+					},
+					Kind: source.HighlightRead,
+				},
+			},
+		},
+		"Should highlight the rule only in the same file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	is_admin
+}
+
+is_admin {
+	input.user == "admin"
+}`,
+				},
+				"admin.rego": {
+					RawText: `package src
+
+is_admin {
+	input.admin
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			expectHighlights: []source.HighlightRange{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("is_admin"),
+						File:   "src.rego",
+					},
+					Kind: source.HighlightRead,
+				},
+				{
+					Location: &ast.Location{
+						Row:    7,
+						Col:    1,
+						Offset: len("package src\n\nallow {\n\tis_admin\n}\n\n"),
+						Text:   []byte("is_admin"),
+						File:   "src.rego",
+					},
+					Kind: source.HighlightWrite,
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.DocumentHighlight(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectHighlights, got); diff != "" {
+				t.Errorf("DocumentHighlight result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// InlayHint is the label which is shown before the term at Position.
+type InlayHint struct {
+	Position Position
+	Label    string
+}
+
+// InlayHints returns the parameter names of the function calls in the range.
+//
+//	is_hello("world") -> is_hello(msg: "world")
+//
+// The hint is skipped when the argument is the variable of the same name as the parameter.
+func (p *Project) InlayHints(path string, rng Range) ([]InlayHint, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if policy.Module == nil {
+		return []InlayHint{}, nil
+	}
+
+	calls := make([][]*ast.Term, 0)
+	vis := ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.Expr:
+			if v.IsCall() {
+				calls = append(calls, v.Terms.([]*ast.Term))
+			}
+		case ast.Call:
+			calls = append(calls, v)
+		}
+		return false
+	})
+	for _, rule := range policy.Module.Rules {
+		vis.Walk(rule)
+	}
+
+	result := make([]InlayHint, 0)
+	for _, call := range calls {
+		if call[0].Location == nil {
+			continue
+		}
+		if _, ok := ast.BuiltinMap[call[0].String()]; ok {
+			continue
+		}
+
+		params := p.functionParams(call[0])
+		for i, arg := range call[1:] {
+			if i >= len(params) {
+				break
+			}
+			if params[i] == "" || arg.Location == nil {
+				continue
+			}
+			if v, ok := arg.Value.(ast.Var); ok && string(v) == params[i] {
+				continue
+			}
+
+			pos := Position{Row: arg.Location.Row, Col: arg.Location.Col}
+			if positionLess(pos, rng.Start) || positionLess(rng.End, pos) {
+				continue
+			}
+			result = append(result, InlayHint{Position: pos, Label: params[i] + ":"})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return positionLess(result[i].Position, result[j].Position)
+	})
+	return result, nil
+}
+
+// functionParams returns the names of the arguments of the function which is called by the operator.
+// The argument which is not a variable like `f("a")` has the empty name.
+func (p *Project) functionParams(operator *ast.Term) []string {
+	for _, r := range p.findRulesInModule(operator) {
+		if len(r.Head.Args) == 0 {
+			continue
+		}
+
+		params := make([]string, len(r.Head.Args))
+		for i, a := range r.Head.Args {
+			// `_` is the generated variable like `$0`.
+			if v, ok := a.Value.(ast.Var); ok && !v.IsGenerated() && !v.IsWildcard() {
+				params[i] = string(v)
+			}
+		}
+		return params
+	}
+	return nil
+}
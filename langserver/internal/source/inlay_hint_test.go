@@ -0,0 +1,128 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_InlayHints(t *testing.T) {
+	wholeFile := source.Range{Start: source.Position{Row: 1, Col: 1}, End: source.Position{Row: 100, Col: 1}}
+
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		rng         source.Range
+		expectHints []source.InlayHint
+	}{
+		"Should show the parameter names": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	is_hello("world")
+}
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			path: "src.rego",
+			rng:  wholeFile,
+			expectHints: []source.InlayHint{
+				{Position: source.Position{Row: 4, Col: 11}, Label: "msg:"},
+			},
+		},
+		"Should show the parameter names of the imported function and the nested call": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+allow {
+	lib.join(lib.join("a", "b"), "c")
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+join(prefix, suffix) = concat("", [prefix, suffix])`,
+				},
+			},
+			path: "src.rego",
+			rng:  wholeFile,
+			expectHints: []source.InlayHint{
+				{Position: source.Position{Row: 6, Col: 11}, Label: "prefix:"},
+				{Position: source.Position{Row: 6, Col: 20}, Label: "prefix:"},
+				{Position: source.Position{Row: 6, Col: 25}, Label: "suffix:"},
+				{Position: source.Position{Row: 6, Col: 31}, Label: "suffix:"},
+			},
+		},
+		"Should skip the variable of the same name and the built-in function": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	msg := "hello"
+	is_hello(msg)
+	count(msg) > 0
+}
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			path:        "src.rego",
+			rng:         wholeFile,
+			expectHints: []source.InlayHint{},
+		},
+		"Should show the hints only in the range": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	is_hello("world")
+}
+
+deny {
+	is_hello("deny")
+}
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			path: "src.rego",
+			rng:  source.Range{Start: source.Position{Row: 7, Col: 1}, End: source.Position{Row: 9, Col: 1}},
+			expectHints: []source.InlayHint{
+				{Position: source.Position{Row: 8, Col: 11}, Label: "msg:"},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.InlayHints(tt.path, tt.rng)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectHints, got); diff != "" {
+				t.Errorf("InlayHints result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
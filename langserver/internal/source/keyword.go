@@ -0,0 +1,226 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// isAfterRuleBody returns true when the location is just after the closing brace of a rule body.
+//
+//	allow {
+//	  ...
+//	} el
+//	    ^ location
+func (p *Project) isAfterRuleBody(location *ast.Location) bool {
+	policy := p.cache.Get(location.File)
+	if policy == nil || policy.Module == nil {
+		return false
+	}
+
+	_, wordOffset := currentWord(policy.RawText, location.Offset)
+	before := strings.TrimRight(policy.RawText[:wordOffset], " \t")
+	if !strings.HasSuffix(before, "}") {
+		return false
+	}
+	braceOffset := len(before) - 1
+
+	for _, r := range policy.Module.Rules {
+		if r.Location == nil || len(r.Location.Text) == 0 {
+			continue
+		}
+		end := r.Location.Offset + len(r.Location.Text) - 1
+		if end == braceOffset && r.Location.Text[len(r.Location.Text)-1] == '}' {
+			return true
+		}
+	}
+	return false
+}
+
+// elseSnippets are the scaffolds of the else clause which follows the rule body.
+var elseSnippets = []struct {
+	label  string
+	detail string
+	text   string
+}{
+	{label: "else { ... }", detail: "else { ... }", text: "else {\n\t${1:true}\n}"},
+	{label: "else = value { ... }", detail: "else = <value> { ... }", text: "else = ${1:value} {\n\t${2:true}\n}"},
+}
+
+// listElseCompletionItems lists `else` keyword and the scaffolds of the else clause which match the word after the rule body.
+// The else clause can be chained after the other else clause, because the rule location contains them.
+func (p *Project) listElseCompletionItems(location *ast.Location) []CompletionItem {
+	if !p.isAfterRuleBody(location) {
+		return nil
+	}
+	policy := p.cache.Get(location.File)
+	word, _ := currentWord(policy.RawText, location.Offset)
+	if !strings.HasPrefix("else", word) {
+		return nil
+	}
+
+	result := []CompletionItem{p.createKeywordCompletionItem(location, "else")}
+	for _, s := range elseSnippets {
+		item := p.createKeywordCompletionItem(location, s.label)
+		item.Kind = SnippetItem
+		item.Detail = s.detail
+		item.TextEdit.Text = s.text
+		result = append(result, item)
+	}
+	return result
+}
+
+// listDefaultCompletionItems lists `default` keyword when the word at the top level can start the rule definition.
+//
+//	package src
+//
+//	de
+//	  ^ location
+func (p *Project) listDefaultCompletionItems(location *ast.Location) []CompletionItem {
+	word, ok := p.topLevelWord(location)
+	if !ok || !strings.HasPrefix("default", word) {
+		return nil
+	}
+
+	item := p.createKeywordCompletionItem(location, "default")
+	item.Detail = "default <name> = <value>"
+	item.TextEdit.Text = "default ${1:name} = ${2:value}"
+	return []CompletionItem{item}
+}
+
+// ruleSnippets are the scaffolds of the common rules.
+var ruleSnippets = []struct {
+	label  string
+	detail string
+	text   string
+}{
+	{label: "deny", detail: "deny[msg] { ... }", text: "deny[msg] {\n\t${1:true}\n\tmsg := ${2:\"message\"}\n}"},
+	{label: "allow", detail: "allow { ... }", text: "allow {\n\t${1:true}\n}"},
+	{label: "default allow", detail: "default allow = false", text: "default allow = ${1:false}"},
+}
+
+// listRuleSnippetItems lists the scaffolds of the rules which match the word at the top level.
+func (p *Project) listRuleSnippetItems(location *ast.Location) []CompletionItem {
+	word, ok := p.topLevelWord(location)
+	if !ok {
+		return nil
+	}
+
+	result := make([]CompletionItem, 0)
+	for _, s := range ruleSnippets {
+		if !strings.HasPrefix(s.label, word) {
+			continue
+		}
+		item := p.createKeywordCompletionItem(location, s.label)
+		item.Kind = SnippetItem
+		item.Detail = s.detail
+		item.TextEdit.Text = s.text
+		result = append(result, item)
+	}
+	return result
+}
+
+// topLevelWord returns the word which is typed at the head of the line.
+// It returns false when nothing is typed, so the keywords don't clutter the other items.
+func (p *Project) topLevelWord(location *ast.Location) (string, bool) {
+	policy := p.cache.Get(location.File)
+	if policy == nil {
+		return "", false
+	}
+
+	word, wordOffset := currentWord(policy.RawText, location.Offset)
+	if word == "" {
+		return "", false
+	}
+	lineStart := strings.LastIndex(policy.RawText[:wordOffset], "\n") + 1
+	if strings.TrimSpace(policy.RawText[lineStart:wordOffset]) != "" {
+		return "", false
+	}
+	return word, true
+}
+
+func (p *Project) createKeywordCompletionItem(location *ast.Location, keyword string) CompletionItem {
+	policy := p.cache.Get(location.File)
+	_, wordOffset := currentWord(policy.RawText, location.Offset)
+	position := offsetToPosition(policy.RawText, wordOffset)
+
+	return CompletionItem{
+		Label: keyword,
+		Kind:  KeywordItem,
+		TextEdit: &TextEdit{
+			Row:  position.Row,
+			Col:  position.Col,
+			Text: keyword,
+		},
+	}
+}
+
+// isAfterNot returns true when the location is just after `not` keyword.
+//
+//	not m
+//	    ^ location
+func (p *Project) isAfterNot(location *ast.Location) bool {
+	policy := p.cache.Get(location.File)
+	if policy == nil {
+		return false
+	}
+
+	_, wordOffset := currentWord(policy.RawText, location.Offset)
+	before := policy.RawText[:wordOffset]
+	trimmed := strings.TrimRight(before, " \t")
+	if len(trimmed) == len(before) {
+		return false
+	}
+
+	word, _ := currentWord(trimmed, len(trimmed))
+	return word == "not"
+}
+
+// listNotCompletionItems lists the negated membership like `not x in coll`.
+func (p *Project) listNotCompletionItems(location *ast.Location, target *ast.Term) []CompletionItem {
+	policy := p.cache.Get(location.File)
+	_, wordOffset := currentWord(policy.RawText, location.Offset)
+	position := offsetToPosition(policy.RawText, wordOffset)
+
+	label := "x in coll"
+	text := "${1:x} in ${2:coll}"
+	if target != nil {
+		if v, ok := target.Value.(ast.Var); ok {
+			label = fmt.Sprintf("%s in coll", v)
+			text = fmt.Sprintf("%s in ${1:coll}", v)
+		}
+	}
+
+	return []CompletionItem{
+		{
+			Label:  label,
+			Kind:   SnippetItem,
+			Detail: fmt.Sprintf("not %s", label),
+			TextEdit: &TextEdit{
+				Row:  position.Row,
+				Col:  position.Col,
+				Text: text,
+			},
+		},
+	}
+}
+
+// currentWord returns the identifier which ends at the offset and the offset where it starts.
+func currentWord(rawText string, offset int) (string, int) {
+	if offset > len(rawText) {
+		offset = len(rawText)
+	}
+
+	start := offset
+	for start > 0 {
+		c, size := utf8.DecodeLastRuneInString(rawText[:start])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		start -= size
+	}
+	return rawText[start:offset], start
+}
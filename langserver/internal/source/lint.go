@@ -0,0 +1,612 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota + 1
+	SeverityWarning
+	SeverityInformation
+	SeverityHint
+)
+
+const (
+	UnusedVariableCode   = "unused-variable"
+	UnusedImportCode     = "unused-import"
+	ReservedWordCode     = "reserved-word"
+	RuleHeadMismatchCode = "rule-head-mismatch"
+	DeprecatedCode       = "deprecated"
+	UnresolvedRefCode    = "unresolved-reference"
+)
+
+// The sources of the diagnostics which are converted from the errors of OPA.
+const (
+	ParseSource   = "parse"
+	CompileSource = "compile"
+)
+
+type Diagnostic struct {
+	Location *ast.Location
+	Severity Severity
+	Code     string
+	Message  string
+
+	// Source is ParseSource or CompileSource for the errors of OPA, and empty for the lints.
+	Source string
+
+	// Fixes are quick fixes which resolve the diagnostic.
+	Fixes []CodeAction
+
+	// Related are the other locations which cause the diagnostic.
+	Related []RelatedInformation
+}
+
+type RelatedInformation struct {
+	Location *ast.Location
+	Message  string
+}
+
+type CodeAction struct {
+	Title string
+	Edits []TextEdit
+
+	// Diagnostics are the diagnostics which the action resolves. They are set by CodeActions.
+	Diagnostics []Diagnostic
+}
+
+// ErrorDiagnostics converts the parse and compile errors into the diagnostics.
+// The errors which don't stop the evaluation, like the deprecated built-in in rego.v1, are warnings.
+// The errors without the location are skipped.
+func ErrorDiagnostics(errs ast.Errors) []Diagnostic {
+	result := make([]Diagnostic, 0, len(errs))
+	for _, e := range errs {
+		if e.Location == nil {
+			continue
+		}
+		source := CompileSource
+		if e.Code == ast.ParseErr {
+			source = ParseSource
+		}
+		result = append(result, Diagnostic{
+			Location: e.Location,
+			Severity: errorSeverity(e),
+			Code:     e.Code,
+			Message:  e.Message,
+			Source:   source,
+		})
+	}
+	return result
+}
+
+// errorSeverity returns the severity of the error by the code and the message.
+//
+//	deprecated built-in function calls in expression: any
+//	assigned var x unused
+//	unused argument x. (hint: use _ (wildcard variable) instead)
+func errorSeverity(e *ast.Error) Severity {
+	switch e.Code {
+	case ast.TypeErr:
+		if strings.HasPrefix(e.Message, "deprecated built-in function calls") {
+			return SeverityWarning
+		}
+	case ast.CompileErr:
+		if strings.HasSuffix(e.Message, " unused") || strings.HasPrefix(e.Message, "unused argument ") {
+			return SeverityWarning
+		}
+	}
+	return SeverityError
+}
+
+// recursionRelated returns the locations of the other rules in the cycle of the recursion error.
+//
+//	rule data.main.a is recursive: data.main.a -> data.main.b -> data.main.a
+func (p *Project) recursionRelated(d Diagnostic) []RelatedInformation {
+	i := strings.Index(d.Message, ": ")
+	if i < 0 {
+		return nil
+	}
+
+	result := make([]RelatedInformation, 0)
+	exists := make(map[string]struct{})
+	for _, name := range strings.Split(d.Message[i+2:], " -> ") {
+		if _, ok := exists[name]; ok {
+			continue
+		}
+		exists[name] = struct{}{}
+
+		ref, err := ast.ParseRef(name)
+		if err != nil {
+			continue
+		}
+		rules, err := p.RulesForPath(ref)
+		if err != nil {
+			continue
+		}
+		for _, r := range rules {
+			if r.Location.File == d.Location.File && r.Location.Row == d.Location.Row {
+				continue
+			}
+			result = append(result, RelatedInformation{
+				Location: ruleNameLocation(r),
+				Message:  fmt.Sprintf("%s is in the recursion", name),
+			})
+		}
+	}
+	return result
+}
+
+// UnusedImports reports imports which are never referred by the rules in the file.
+func (p *Project) UnusedImports(path string) ([]Diagnostic, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if policy.Module == nil {
+		return nil, nil
+	}
+
+	names := make(map[ast.Var]struct{})
+	for _, imp := range policy.Module.Imports {
+		names[ast.Var(importToLabel(imp))] = struct{}{}
+	}
+
+	used := make(map[ast.Var]struct{})
+	for _, rule := range policy.Module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			// the declarations like `lib := input.lib` and the arguments like `f(lib)` are not the uses of the import.
+			declared := make(map[int]struct{})
+			ast.WalkExprs(r.Body, func(expr *ast.Expr) bool {
+				for _, t := range declaredVarsInExpr(expr) {
+					if t.Location != nil {
+						declared[t.Location.Offset] = struct{}{}
+					}
+				}
+				return false
+			})
+			for _, arg := range r.Head.Args {
+				ast.WalkTerms(arg, func(t *ast.Term) bool {
+					if _, ok := t.Value.(ast.Var); ok && t.Location != nil {
+						declared[t.Location.Offset] = struct{}{}
+					}
+					return false
+				})
+			}
+
+			vis := ast.NewGenericVisitor(func(x interface{}) bool {
+				t, ok := x.(*ast.Term)
+				if !ok {
+					return false
+				}
+				v, ok := t.Value.(ast.Var)
+				if !ok || t.Location == nil {
+					return false
+				}
+				if _, ok := names[v]; !ok {
+					return false
+				}
+				if _, ok := declared[t.Location.Offset]; ok {
+					return false
+				}
+				// the local variable which has the same name shadows the import.
+				if p.findDefinitionInRule(t, r) == nil {
+					used[v] = struct{}{}
+				}
+				return false
+			})
+			// rule name doesn't refer imports.
+			vis.Walk(r.Head.Args)
+			if r.Head.Key != nil {
+				vis.Walk(r.Head.Key)
+			}
+			if r.Head.Value != nil {
+				vis.Walk(r.Head.Value)
+			}
+			vis.Walk(r.Body)
+		}
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, imp := range policy.Module.Imports {
+		if isKeywordImport(imp) {
+			continue
+		}
+
+		if _, ok := used[ast.Var(importToLabel(imp))]; ok {
+			continue
+		}
+
+		result = append(result, Diagnostic{
+			Location: importStatementLocation(policy.RawText, imp),
+			Severity: SeverityWarning,
+			Code:     UnusedImportCode,
+			Message:  fmt.Sprintf("%s is imported but never used", imp.Path.String()),
+		})
+	}
+	return result, nil
+}
+
+// importStatementLocation returns the location of the whole import statement,
+// because the location of the import only has `import` keyword.
+func importStatementLocation(rawText string, imp *ast.Import) *ast.Location {
+	end := imp.Path.Location.Offset + len(imp.Path.Location.Text)
+	if imp.Alias != "" {
+		if i := strings.Index(rawText[end:], string(imp.Alias)); i >= 0 {
+			end += i + len(imp.Alias)
+		}
+	}
+
+	return &ast.Location{
+		Row:    imp.Location.Row,
+		Col:    imp.Location.Col,
+		Offset: imp.Location.Offset,
+		Text:   []byte(rawText[imp.Location.Offset:end]),
+		File:   imp.Location.File,
+	}
+}
+
+// isKeywordImport returns true when the import enables keywords like `import future.keywords.in` or `import rego.v1`.
+func isKeywordImport(imp *ast.Import) bool {
+	ref, ok := imp.Path.Value.(ast.Ref)
+	if !ok || len(ref) == 0 {
+		return false
+	}
+	return ref[0].Equal(ast.FutureRootDocument) || ref[0].Equal(ast.RegoRootDocument)
+}
+
+// ReservedWordBindings reports variables which are bound to reserved words like `data` or `contains`.
+// The compiler accepts `data := 1`, but it shadows the root document in the rest of the rule.
+func (p *Project) ReservedWordBindings(path string) []Diagnostic {
+	policy := p.cache.Get(path)
+	if policy == nil || policy.Module == nil {
+		return nil
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, rule := range policy.Module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			result = append(result, reservedWordBindingsInRule(r)...)
+		}
+	}
+	return result
+}
+
+func reservedWordBindingsInRule(rule *ast.Rule) []Diagnostic {
+	result := make([]Diagnostic, 0)
+	ast.WalkExprs(rule.Body, func(expr *ast.Expr) bool {
+		for _, t := range declaredVarsInExpr(expr) {
+			name := t.Value.String()
+			if !isReservedWord(name) {
+				continue
+			}
+
+			newName := name + "_"
+			edits := make([]TextEdit, 0)
+			rename := func(term *ast.Term) bool {
+				if term.Equal(t) && term.Location != nil {
+					edits = append(edits, TextEdit{
+						Row:  term.Location.Row,
+						Col:  term.Location.Col,
+						Text: newName,
+						End:  &Position{Row: term.Location.Row, Col: term.Location.Col + len(name)},
+					})
+				}
+				return false
+			}
+			// the head key and value refer to the variables bound in the body.
+			if rule.Head.Key != nil {
+				ast.WalkTerms(rule.Head.Key, rename)
+			}
+			if rule.Head.Value != nil {
+				ast.WalkTerms(rule.Head.Value, rename)
+			}
+			ast.WalkTerms(rule.Body, func(term *ast.Term) bool {
+				if term.Location != nil && term.Location.Offset < t.Location.Offset {
+					return false
+				}
+				return rename(term)
+			})
+
+			result = append(result, Diagnostic{
+				Location: t.Location,
+				Severity: SeverityWarning,
+				Code:     ReservedWordCode,
+				Message:  fmt.Sprintf("%s is a reserved word and should not be used as a variable name", name),
+				Fixes: []CodeAction{
+					{
+						Title: fmt.Sprintf("Rename %s to %s", name, newName),
+						Edits: edits,
+					},
+				},
+			})
+		}
+		return false
+	})
+	return result
+}
+
+// RuleHeadMismatches reports the rules in the file whose heads have different shapes from the other definitions of the same rule.
+//
+//	allow { ... }
+//	allow[x] { ... } -> conflicts with the complete rule
+func (p *Project) RuleHeadMismatches(path string) []Diagnostic {
+	policy := p.cache.Get(path)
+	if policy == nil || policy.Module == nil {
+		return nil
+	}
+
+	rules := make(map[ast.Var][]*ast.Rule)
+	for _, m := range p.cache.FindPolicies(policy.Module.Package.Path) {
+		for _, r := range m.Rules {
+			rules[r.Head.Name] = append(rules[r.Head.Name], r)
+		}
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, rule := range policy.Module.Rules {
+		shape := ruleHeadShape(rule)
+
+		conflicts := make([]*ast.Rule, 0)
+		for _, other := range rules[rule.Head.Name] {
+			if ruleHeadShape(other) != shape {
+				conflicts = append(conflicts, other)
+			}
+		}
+		if len(conflicts) == 0 {
+			continue
+		}
+		sort.Slice(conflicts, func(i, j int) bool {
+			if conflicts[i].Location.File != conflicts[j].Location.File {
+				return conflicts[i].Location.File < conflicts[j].Location.File
+			}
+			return conflicts[i].Location.Offset < conflicts[j].Location.Offset
+		})
+
+		related := make([]RelatedInformation, len(conflicts))
+		for i, c := range conflicts {
+			related[i] = RelatedInformation{
+				Location: ruleNameLocation(c),
+				Message:  fmt.Sprintf("%s is defined as %s here", c.Head.Name, ruleHeadShape(c)),
+			}
+		}
+
+		first := conflicts[0]
+		result = append(result, Diagnostic{
+			Location: ruleNameLocation(rule),
+			Severity: SeverityError,
+			Code:     RuleHeadMismatchCode,
+			Message:  fmt.Sprintf("%s is defined as %s here, but as %s at %s:%d", rule.Head.Name, shape, ruleHeadShape(first), first.Location.File, first.Location.Row),
+			Related:  related,
+		})
+	}
+	return result
+}
+
+// ruleHeadShape describes the kind of the rule head which must be same across the definitions.
+func ruleHeadShape(rule *ast.Rule) string {
+	switch {
+	case len(rule.Head.Args) > 0:
+		return fmt.Sprintf("a function with %d arguments", len(rule.Head.Args))
+	case rule.Head.Key != nil && rule.Head.Value == nil:
+		return "a partial set rule"
+	case rule.Head.Key != nil:
+		return "a partial object rule"
+	default:
+		return "a complete rule"
+	}
+}
+
+// UnresolvedRefs reports the unqualified references which match no rule in the current package, because the import is likely missing.
+// It reports nothing unless the mode is UnqualifiedRefWarn.
+// Only the called functions and the standalone expressions are checked, because they never bind the variables.
+//
+//	allow {
+//		is_admin     <- is_admin is not defined in data.main
+//		check(input) <- check is not defined in data.main
+//	}
+func (p *Project) UnresolvedRefs(path string) []Diagnostic {
+	policy := p.cache.Get(path)
+	if p.unqualifiedRefMode != UnqualifiedRefWarn || policy == nil || policy.Module == nil {
+		return nil
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, rule := range policy.Module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			for _, expr := range r.Body {
+				var head *ast.Term
+				switch t := expr.Terms.(type) {
+				case *ast.Term:
+					head = t
+				case []*ast.Term:
+					head = t[0]
+				default:
+					// some x in xs, every x in xs { ... }
+					continue
+				}
+				if ref, ok := head.Value.(ast.Ref); ok {
+					// json.is_valid(x) is the built-in, though json is not.
+					if _, ok := ast.BuiltinMap[ref.String()]; ok {
+						continue
+					}
+					head = ref[0]
+				}
+				if d, ok := p.unresolvedRef(head, r, policy.Module); ok {
+					result = append(result, d)
+				}
+			}
+		}
+	}
+	return result
+}
+
+// unresolvedRef returns the diagnostic when the variable is not the rule, the import, the built-in or the local variable.
+func (p *Project) unresolvedRef(term *ast.Term, rule *ast.Rule, module *ast.Module) (Diagnostic, bool) {
+	v, ok := term.Value.(ast.Var)
+	if !ok || v.IsGenerated() || v.IsWildcard() || term.Location == nil || ast.RootDocumentNames.Contains(term) {
+		return Diagnostic{}, false
+	}
+	if _, ok := ast.BuiltinMap[string(v)]; ok {
+		return Diagnostic{}, false
+	}
+	if findImportByName(v, module.Imports) != nil || len(p.findRulesInModule(term)) > 0 || p.findDefinitionInRule(term, rule) != nil {
+		return Diagnostic{}, false
+	}
+
+	message := fmt.Sprintf("%s is not defined in %s", v, module.Package.Path)
+	packages := make([]string, 0)
+	exists := make(map[string]struct{})
+	for _, r := range p.findRulesInWorkspace(v) {
+		pkg := p.GetModule(r.Location.File).Package.Path.String()
+		if _, ok := exists[pkg]; ok {
+			continue
+		}
+		exists[pkg] = struct{}{}
+		packages = append(packages, pkg)
+	}
+	if len(packages) > 0 {
+		sort.Strings(packages)
+		message += fmt.Sprintf(", but in %s. Is the import missing?", strings.Join(packages, ", "))
+	}
+
+	return Diagnostic{
+		Location: term.Location,
+		Severity: SeverityWarning,
+		Code:     UnresolvedRefCode,
+		Message:  message,
+	}, true
+}
+
+// ruleNameLocation returns the location of the rule name.
+// The location of the head starts after the default keyword.
+func ruleNameLocation(rule *ast.Rule) *ast.Location {
+	loc := rule.Location
+	if rule.Head.Location != nil {
+		loc = rule.Head.Location
+	}
+	return &ast.Location{
+		Row:    loc.Row,
+		Col:    loc.Col,
+		Offset: loc.Offset,
+		Text:   []byte(ruleHeadName(rule)),
+		File:   loc.File,
+	}
+}
+
+// deprecatedBuiltinReplacements are the replacements of the deprecated built-ins.
+// The built-in functions are fixed automatically, the others are only suggested in the message.
+var deprecatedBuiltinReplacements = map[string]string{
+	"re_match":         "regex.match",
+	"net.cidr_overlap": "net.cidr_contains",
+	"set_diff":         "the - operator",
+	"all":              "the every keyword",
+	"any":              "the in operator",
+	"cast_array":       "an array comprehension",
+}
+
+// DeprecatedBuiltins reports the calls of built-in functions which are deprecated.
+//
+//	re_match(`^a`, input.name) -> should be regex.match
+func (p *Project) DeprecatedBuiltins(path string) []Diagnostic {
+	policy := p.cache.Get(path)
+	if policy == nil || policy.Module == nil {
+		return nil
+	}
+
+	operators := make([]*ast.Term, 0)
+	vis := ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.Expr:
+			if v.IsCall() {
+				operators = append(operators, v.Terms.([]*ast.Term)[0])
+			}
+		case ast.Call:
+			operators = append(operators, v[0])
+		}
+		return false
+	})
+	for _, rule := range policy.Module.Rules {
+		vis.Walk(rule)
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, op := range operators {
+		name := op.String()
+		b, ok := ast.BuiltinMap[name]
+		if !ok || !b.IsDeprecated() || op.Location == nil {
+			continue
+		}
+
+		d := Diagnostic{
+			Location: op.Location,
+			Severity: SeverityHint,
+			Code:     DeprecatedCode,
+			Message:  fmt.Sprintf("%s is deprecated", name),
+		}
+		if replacement, ok := deprecatedBuiltinReplacements[name]; ok {
+			d.Message = fmt.Sprintf("%s is deprecated, use %s instead", name, replacement)
+			if _, ok := ast.BuiltinMap[replacement]; ok {
+				d.Fixes = []CodeAction{
+					{
+						Title: fmt.Sprintf("Replace %s with %s", name, replacement),
+						Edits: []TextEdit{
+							{
+								Row:  op.Location.Row,
+								Col:  op.Location.Col,
+								Text: replacement,
+								End:  &Position{Row: op.Location.Row, Col: op.Location.Col + len(name)},
+							},
+						},
+					},
+				}
+			}
+		}
+		result = append(result, d)
+	}
+	return result
+}
+
+// deleteTextEdit returns the edit which deletes loc.
+// When loc is the only content of the line, the whole line is deleted.
+func deleteTextEdit(rawText string, loc *ast.Location) TextEdit {
+	start := loc.Offset
+	end := loc.Offset + len(loc.Text)
+
+	lineStart := strings.LastIndex(rawText[:start], "\n") + 1
+	lineEnd := len(rawText)
+	if i := strings.Index(rawText[end:], "\n"); i >= 0 {
+		lineEnd = end + i
+	}
+
+	if strings.TrimSpace(rawText[lineStart:start]) == "" && strings.TrimSpace(rawText[end:lineEnd]) == "" && lineEnd < len(rawText) {
+		return TextEdit{
+			Row: loc.Row,
+			Col: 1,
+			End: &Position{Row: loc.Row + 1, Col: 1},
+		}
+	}
+
+	endPosition := offsetToPosition(rawText, end)
+	return TextEdit{
+		Row: loc.Row,
+		Col: loc.Col,
+		End: &endPosition,
+	}
+}
+
+// offsetToPosition converts the byte offset into the position.
+// Col counts characters like the parser does, so it differs from the byte offset after multibyte characters.
+func offsetToPosition(rawText string, offset int) Position {
+	text := rawText[:offset]
+	return Position{
+		Row: strings.Count(text, "\n") + 1,
+		Col: utf8.RuneCountInString(text[strings.LastIndex(text, "\n")+1:]) + 1,
+	}
+}
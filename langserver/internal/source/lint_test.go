@@ -0,0 +1,656 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_UnusedImports(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectDiags []source.Diagnostic
+	}{
+		"Should report unused import": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+import data.util
+
+allow {
+	lib.is_hello("hello")
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    1,
+						Offset: len("package src\n\nimport data.lib\n"),
+						Text:   []byte("import data.util"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedImportCode,
+					Message:  "data.util is imported but never used",
+				},
+			},
+		},
+		"Should check aliased import against the alias": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib as l
+import data.util as u
+
+allow {
+	l.is_hello("hello")
+	util.is_world("world")
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    1,
+						Offset: len("package src\n\nimport data.lib as l\n"),
+						Text:   []byte("import data.util as u"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedImportCode,
+					Message:  "data.util is imported but never used",
+				},
+			},
+		},
+		"Should report the import which is shadowed by the local variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+import data.users
+
+allow {
+	users := input.users
+	count(users) > 0
+}
+
+is_admin(lib) {
+	lib.admin
+}
+
+deny {
+	lib.is_denied(input)
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    1,
+						Offset: len("package src\n\nimport data.lib\n"),
+						Text:   []byte("import data.users"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedImportCode,
+					Message:  "data.users is imported but never used",
+				},
+			},
+		},
+		"Should not report keyword imports and imports used in the rule value": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.in
+import data.lib
+
+allow := lib.allow`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.UnusedImports(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("UnusedImports result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_ReservedWordBindings(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectDiags []source.Diagnostic
+	}{
+		"Should report variable bound to data": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	data := input.user
+	data.name == "admin"
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("data"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.ReservedWordCode,
+					Message:  "data is a reserved word and should not be used as a variable name",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename data to data_",
+							Edits: []source.TextEdit{
+								{Row: 4, Col: 2, Text: "data_", End: &source.Position{Row: 4, Col: 6}},
+								{Row: 5, Col: 2, Text: "data_", End: &source.Position{Row: 5, Col: 6}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"Should report future keyword bound by some": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+deny[contains] {
+	some contains
+	input.list[contains]
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    7,
+						Offset: len("package src\n\ndeny[contains] {\n\tsome "),
+						Text:   []byte("contains"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.ReservedWordCode,
+					Message:  "contains is a reserved word and should not be used as a variable name",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename contains to contains_",
+							Edits: []source.TextEdit{
+								{Row: 3, Col: 6, Text: "contains_", End: &source.Position{Row: 3, Col: 14}},
+								{Row: 4, Col: 7, Text: "contains_", End: &source.Position{Row: 4, Col: 15}},
+								{Row: 5, Col: 13, Text: "contains_", End: &source.Position{Row: 5, Col: 21}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"Should not report the usage of root documents": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	user := input.user
+	data.admins[user]
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.ReservedWordBindings(tt.path)
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("ReservedWordBindings result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_RuleHeadMismatches(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectDiags []source.Diagnostic
+	}{
+		"Should report the partial set rule which conflicts with the complete rule in the other file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow[x] {
+	x := input.user
+}`,
+				},
+				"other.rego": {
+					RawText: `package src
+
+allow {
+	input.admin
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package src\n\n"),
+						Text:   []byte("allow"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityError,
+					Code:     source.RuleHeadMismatchCode,
+					Message:  "allow is defined as a partial set rule here, but as a complete rule at other.rego:3",
+					Related: []source.RelatedInformation{
+						{
+							Location: &ast.Location{
+								Row:    3,
+								Col:    1,
+								Offset: len("package src\n\n"),
+								Text:   []byte("allow"),
+								File:   "other.rego",
+							},
+							Message: "allow is defined as a complete rule here",
+						},
+					},
+				},
+			},
+		},
+		"Should report the complete rule which conflicts with the partial rule in the same file": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.admin
+}
+
+allow[x] {
+	x := input.user
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package src\n\n"),
+						Text:   []byte("allow"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityError,
+					Code:     source.RuleHeadMismatchCode,
+					Message:  "allow is defined as a complete rule here, but as a partial set rule at src.rego:7",
+					Related: []source.RelatedInformation{
+						{
+							Location: &ast.Location{
+								Row:    7,
+								Col:    1,
+								Offset: len("package src\n\nallow {\n\tinput.admin\n}\n\n"),
+								Text:   []byte("allow"),
+								File:   "src.rego",
+							},
+							Message: "allow is defined as a partial set rule here",
+						},
+					},
+				},
+				{
+					Location: &ast.Location{
+						Row:    7,
+						Col:    1,
+						Offset: len("package src\n\nallow {\n\tinput.admin\n}\n\n"),
+						Text:   []byte("allow"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityError,
+					Code:     source.RuleHeadMismatchCode,
+					Message:  "allow is defined as a partial set rule here, but as a complete rule at src.rego:3",
+					Related: []source.RelatedInformation{
+						{
+							Location: &ast.Location{
+								Row:    3,
+								Col:    1,
+								Offset: len("package src\n\n"),
+								Text:   []byte("allow"),
+								File:   "src.rego",
+							},
+							Message: "allow is defined as a complete rule here",
+						},
+					},
+				},
+			},
+		},
+		"Should report the default rule at the rule name": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+default allow = false`,
+				},
+				"other.rego": {
+					RawText: `package src
+
+allow[x] {
+	x := input.user
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    9,
+						Offset: len("package src\n\ndefault "),
+						Text:   []byte("allow"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityError,
+					Code:     source.RuleHeadMismatchCode,
+					Message:  "allow is defined as a complete rule here, but as a partial set rule at other.rego:3",
+					Related: []source.RelatedInformation{
+						{
+							Location: &ast.Location{
+								Row:    3,
+								Col:    1,
+								Offset: len("package src\n\n"),
+								Text:   []byte("allow"),
+								File:   "other.rego",
+							},
+							Message: "allow is defined as a partial set rule here",
+						},
+					},
+				},
+			},
+		},
+		"Should not report the incremental rules which have the same shape": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+deny[msg] {
+	msg := "a"
+}
+
+deny[msg] {
+	msg := "b"
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.RuleHeadMismatches(tt.path)
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("RuleHeadMismatches result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_DeprecatedBuiltins(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectDiags []source.Diagnostic
+	}{
+		"Should report deprecated built-in with the replacement": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	re_match("^admin", input.user)
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("re_match"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityHint,
+					Code:     source.DeprecatedCode,
+					Message:  "re_match is deprecated, use regex.match instead",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Replace re_match with regex.match",
+							Edits: []source.TextEdit{
+								{Row: 4, Col: 2, Text: "regex.match", End: &source.Position{Row: 4, Col: 10}},
+							},
+						},
+					},
+				},
+			},
+		},
+		"Should report deprecated built-in in the call argument": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+list := cast_array(input.list)`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    3,
+						Col:    9,
+						Offset: len("package src\n\nlist := "),
+						Text:   []byte("cast_array"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityHint,
+					Code:     source.DeprecatedCode,
+					Message:  "cast_array is deprecated, use an array comprehension instead",
+				},
+			},
+		},
+		"Should suggest the array comprehension for cast_array without the fix": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	list := cast_array(input.set)
+	count(list) > 0
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    10,
+						Offset: len("package src\n\nallow {\n\tlist := "),
+						Text:   []byte("cast_array"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityHint,
+					Code:     source.DeprecatedCode,
+					Message:  "cast_array is deprecated, use an array comprehension instead",
+				},
+			},
+		},
+		"Should not report built-ins which are not deprecated": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	regex.match("^admin", input.user)
+	count(input.list) > 0
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.DeprecatedBuiltins(tt.path)
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("DeprecatedBuiltins result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_UnresolvedRefs(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+import data.util
+
+allow {
+	is_admin
+	check(input)
+	util.f(1)
+	x := count(input.list)
+	x
+	is_local
+	json.is_valid("{}")
+	regex.match("a", "a")
+	time.now_ns()
+}
+
+is_local := true`,
+		},
+		"lib.rego": {
+			RawText: `package lib
+
+is_admin {
+	input.admin
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		mode        source.UnqualifiedRefMode
+		expectDiags []source.Diagnostic
+	}{
+		"Should report the unqualified references which match no rule in the package": {
+			mode: source.UnqualifiedRefWarn,
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    6,
+						Col:    2,
+						Offset: len("package src\n\nimport data.util\n\nallow {\n\t"),
+						Text:   []byte("is_admin"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnresolvedRefCode,
+					Message:  "is_admin is not defined in data.src, but in data.lib. Is the import missing?",
+				},
+				{
+					Location: &ast.Location{
+						Row:    7,
+						Col:    2,
+						Offset: len("package src\n\nimport data.util\n\nallow {\n\tis_admin\n\t"),
+						Text:   []byte("check"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnresolvedRefCode,
+					Message:  "check is not defined in data.src",
+				},
+			},
+		},
+		"Should not report anything in the default mode": {
+			mode: source.UnqualifiedRefPackage,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			project.SetUnqualifiedRefMode(tt.mode)
+
+			got := project.UnresolvedRefs("src.rego")
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("UnresolvedRefs result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
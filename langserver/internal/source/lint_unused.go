@@ -0,0 +1,291 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// UnusedVariables reports local variables which are declared but never read in the rule.
+func (p *Project) UnusedVariables(path string) []Diagnostic {
+	policy := p.cache.Get(path)
+	if policy == nil || policy.Module == nil {
+		return nil
+	}
+
+	// rule names and imports are referred in the ref key, but they are not bound there.
+	globals := make(map[ast.Var]struct{})
+	for _, m := range p.cache.FindPolicies(policy.Module.Package.Path) {
+		for _, r := range m.Rules {
+			globals[r.Head.Name] = struct{}{}
+		}
+	}
+	for _, imp := range policy.Module.Imports {
+		globals[ast.Var(importToLabel(imp))] = struct{}{}
+	}
+
+	result := make([]Diagnostic, 0)
+	for _, rule := range policy.Module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			result = append(result, unusedVariablesInRule(policy.RawText, r)...)
+			result = append(result, unusedRefKeysInRule(r, globals)...)
+		}
+	}
+	return result
+}
+
+// unusedRefKeysInRule reports variables which appear only as the key of the ref.
+// The key which is bound by the arguments, `:=`, `some`, `every` or the other expressions is the use of the variable.
+//
+//	input.list[i]
+//	           ^ should be `_`
+func unusedRefKeysInRule(rule *ast.Rule, globals map[ast.Var]struct{}) []Diagnostic {
+	keys := make([]*ast.Term, 0)
+	ast.WalkRefs(rule.Body, func(ref ast.Ref) bool {
+		for _, t := range ref[1:] {
+			v, ok := t.Value.(ast.Var)
+			if !ok || isIgnoredVar(v) || t.Location == nil {
+				continue
+			}
+			if _, ok := globals[v]; ok {
+				continue
+			}
+			keys = append(keys, t)
+		}
+		return false
+	})
+
+	result := make([]Diagnostic, 0)
+	for _, k := range keys {
+		if isVarReferredInScope(k, rule, false) {
+			continue
+		}
+
+		name := k.Value.String()
+		result = append(result, Diagnostic{
+			Location: k.Location,
+			Severity: SeverityWarning,
+			Code:     UnusedVariableCode,
+			Message:  fmt.Sprintf("%s is declared but never used", name),
+			Fixes: []CodeAction{
+				{
+					Title: fmt.Sprintf("Replace %s with _", name),
+					Edits: []TextEdit{
+						{
+							Row:  k.Location.Row,
+							Col:  k.Location.Col,
+							Text: "_",
+							End:  &Position{Row: k.Location.Row, Col: k.Location.Col + len(k.Location.Text)},
+						},
+					},
+				},
+			},
+		})
+	}
+	return result
+}
+
+type declaredVar struct {
+	term *ast.Term
+	expr *ast.Expr
+}
+
+func unusedVariablesInRule(rawText string, rule *ast.Rule) []Diagnostic {
+	declared := make([]declaredVar, 0)
+	ast.WalkExprs(rule.Body, func(expr *ast.Expr) bool {
+		for _, t := range declaredVarsInExpr(expr) {
+			declared = append(declared, declaredVar{term: t, expr: expr})
+		}
+		return false
+	})
+
+	result := make([]Diagnostic, 0)
+	for _, d := range declared {
+		if isVarUsedInRule(d.term, rule) {
+			continue
+		}
+
+		name := d.term.Value.String()
+		fixes := []CodeAction{
+			{
+				Title: fmt.Sprintf("Rename %s to _%s", name, name),
+				Edits: []TextEdit{
+					{Row: d.term.Location.Row, Col: d.term.Location.Col, Text: "_"},
+				},
+			},
+		}
+		if d.expr.IsAssignment() && d.expr.Operand(0) == d.term {
+			fixes = append(fixes, CodeAction{
+				Title: fmt.Sprintf("Remove %s", name),
+				Edits: []TextEdit{deleteTextEdit(rawText, d.expr.Location)},
+			})
+		}
+
+		result = append(result, Diagnostic{
+			Location: d.term.Location,
+			Severity: SeverityWarning,
+			Code:     UnusedVariableCode,
+			Message:  fmt.Sprintf("%s is declared but never used", name),
+			Fixes:    fixes,
+		})
+	}
+	return result
+}
+
+// declaredVarsInExpr returns variables which are declared by `:=`, `some` or `every`.
+func declaredVarsInExpr(expr *ast.Expr) []*ast.Term {
+	targets := make([]*ast.Term, 0)
+	switch t := expr.Terms.(type) {
+	case []*ast.Term:
+		if expr.IsAssignment() {
+			targets = append(targets, expr.Operand(0))
+		}
+	case *ast.SomeDecl:
+		for _, s := range t.Symbols {
+			call, ok := s.Value.(ast.Call)
+			if !ok {
+				targets = append(targets, s)
+				continue
+			}
+			// some k, v in xs -> internal.member_3(k, v, xs)
+			if len(call) > 2 {
+				targets = append(targets, call[1:len(call)-1]...)
+			}
+		}
+	case *ast.Every:
+		if t.Key != nil {
+			targets = append(targets, t.Key)
+		}
+		targets = append(targets, t.Value)
+	}
+
+	result := make([]*ast.Term, 0)
+	for _, target := range targets {
+		ast.WalkTerms(target, func(term *ast.Term) bool {
+			v, ok := term.Value.(ast.Var)
+			if ok && !isIgnoredVar(v) {
+				result = append(result, term)
+			}
+			return false
+		})
+	}
+	return result
+}
+
+// isIgnoredVar returns true when the variable is intentionally unused like `_` or `_x`.
+func isIgnoredVar(v ast.Var) bool {
+	return strings.HasPrefix(string(v), "_") || v.IsWildcard() || v.IsGenerated()
+}
+
+// isVarUsedInRule returns true when the variable is used in its scope after it is declared.
+// The comprehension or `every` which declares the same name again shadows the variable.
+//
+//	x := 1
+//	xs := [x | x := input.list[_]]
+//	             ^ not the use of the outer x
+func isVarUsedInRule(target *ast.Term, rule *ast.Rule) bool {
+	return isVarReferredInScope(target, rule, true)
+}
+
+// isVarReferredInScope returns true when the variable appears elsewhere in its scope.
+// When afterOnly is true, the appearances in the body before the target are ignored.
+func isVarReferredInScope(target *ast.Term, rule *ast.Rule, afterOnly bool) bool {
+	scope := declarationScope(target, rule)
+
+	used := false
+	checkOrder := false
+	vis := ast.NewGenericVisitor(func(x interface{}) bool {
+		if used {
+			return true
+		}
+		switch v := x.(type) {
+		case *ast.ArrayComprehension:
+			return redeclares(v.Body, target)
+		case *ast.SetComprehension:
+			return redeclares(v.Body, target)
+		case *ast.ObjectComprehension:
+			return redeclares(v.Body, target)
+		case *ast.Every:
+			return (v.Key != nil && v.Key.Equal(target)) || v.Value.Equal(target) || redeclares(v.Body, target)
+		case *ast.Term:
+			if target.Equal(v) && v.Location != nil && v.Location.Offset != target.Location.Offset &&
+				(!checkOrder || v.Location.Offset > target.Location.Offset) {
+				used = true
+			}
+		}
+		return used
+	})
+	// the heads are evaluated after the body, so they use the variable regardless of the order.
+	for _, h := range scope.heads {
+		vis.Walk(h)
+	}
+	checkOrder = afterOnly
+	vis.Walk(scope.body)
+	return used
+}
+
+// varScope is the part of the rule where the declared variable is visible.
+type varScope struct {
+	heads []interface{}
+	body  ast.Body
+}
+
+// declarationScope returns the innermost rule, comprehension or `every` whose body declares the variable.
+// The key and the value of `every` are visible only in its body.
+func declarationScope(target *ast.Term, rule *ast.Rule) varScope {
+	scope := varScope{heads: []interface{}{rule.Head}, body: rule.Body}
+	vis := ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.ArrayComprehension:
+			if bodyContains(v.Body, target) {
+				scope = varScope{heads: []interface{}{v.Term}, body: v.Body}
+			}
+		case *ast.SetComprehension:
+			if bodyContains(v.Body, target) {
+				scope = varScope{heads: []interface{}{v.Term}, body: v.Body}
+			}
+		case *ast.ObjectComprehension:
+			if bodyContains(v.Body, target) {
+				scope = varScope{heads: []interface{}{v.Key, v.Value}, body: v.Body}
+			}
+		case *ast.Every:
+			if bodyContains(v.Body, target) || isSameTerm(v.Key, target) || isSameTerm(v.Value, target) {
+				scope = varScope{body: v.Body}
+			}
+		}
+		return false
+	})
+	vis.Walk(rule.Body)
+	return scope
+}
+
+func bodyContains(body ast.Body, target *ast.Term) bool {
+	for _, e := range body {
+		if e.Location != nil && in(target.Location, e.Location) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSameTerm returns true when the terms are at the same location.
+func isSameTerm(a, b *ast.Term) bool {
+	return a != nil && b != nil && a.Location != nil && b.Location != nil && a.Location.Offset == b.Location.Offset
+}
+
+// redeclares returns true when the body declares the variable again.
+// The key and the value of `every` are declared in its own scope.
+func redeclares(body ast.Body, target *ast.Term) bool {
+	for _, e := range body {
+		if _, ok := e.Terms.(*ast.Every); ok {
+			continue
+		}
+		for _, t := range declaredVarsInExpr(e) {
+			if t.Equal(target) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,336 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_UnusedVariables(t *testing.T) {
+	tests := map[string]struct {
+		files       map[string]source.File
+		path        string
+		expectDiags []source.Diagnostic
+	}{
+		"Should report unused local variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	x := 1
+	y := 2
+	y == 2
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("x"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "x is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename x to _x",
+							Edits: []source.TextEdit{{Row: 4, Col: 2, Text: "_"}},
+						},
+						{
+							Title: "Remove x",
+							Edits: []source.TextEdit{{Row: 4, Col: 1, End: &source.Position{Row: 5, Col: 1}}},
+						},
+					},
+				},
+			},
+		},
+		"Should report the remove edit in characters after multibyte string": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	x := "日本語"; input.a == 1
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("x"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "x is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename x to _x",
+							Edits: []source.TextEdit{{Row: 4, Col: 2, Text: "_"}},
+						},
+						{
+							Title: "Remove x",
+							Edits: []source.TextEdit{{Row: 4, Col: 2, End: &source.Position{Row: 4, Col: 12}}},
+						},
+					},
+				},
+			},
+		},
+		"Should report unused some variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.in
+
+allow {
+	some k, v in input.list
+	v == 1
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    6,
+						Col:    7,
+						Offset: len("package src\n\nimport future.keywords.in\n\nallow {\n\tsome "),
+						Text:   []byte("k"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "k is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename k to _k",
+							Edits: []source.TextEdit{{Row: 6, Col: 7, Text: "_"}},
+						},
+					},
+				},
+			},
+		},
+		"Should report variable which is bound as the ref key but never used": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+deny[msg] {
+	input.list[i] == "admin"
+	containers[c]
+	c.privileged
+	msg := "deny"
+}
+
+containers[c] {
+	c := input.containers[_]
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    13,
+						Offset: len("package src\n\ndeny[msg] {\n\tinput.list["),
+						Text:   []byte("i"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "i is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Replace i with _",
+							Edits: []source.TextEdit{{Row: 4, Col: 13, Text: "_", End: &source.Position{Row: 4, Col: 14}}},
+						},
+					},
+				},
+			},
+		},
+		"Should report the variable which is shadowed in the comprehension": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	x := 1
+	xs := [x | x := input.list[_]]
+	count(xs) > 0
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nallow {\n\t"),
+						Text:   []byte("x"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "x is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename x to _x",
+							Edits: []source.TextEdit{{Row: 4, Col: 2, Text: "_"}},
+						},
+						{
+							Title: "Remove x",
+							Edits: []source.TextEdit{{Row: 4, Col: 1, End: &source.Position{Row: 5, Col: 1}}},
+						},
+					},
+				},
+			},
+		},
+		"Should report the variable which is shadowed in every": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.every
+
+allow {
+	v := 1
+	every v in input.list {
+		v > 0
+	}
+}`,
+				},
+			},
+			path: "src.rego",
+			expectDiags: []source.Diagnostic{
+				{
+					Location: &ast.Location{
+						Row:    6,
+						Col:    2,
+						Offset: len("package src\n\nimport future.keywords.every\n\nallow {\n\t"),
+						Text:   []byte("v"),
+						File:   "src.rego",
+					},
+					Severity: source.SeverityWarning,
+					Code:     source.UnusedVariableCode,
+					Message:  "v is declared but never used",
+					Fixes: []source.CodeAction{
+						{
+							Title: "Rename v to _v",
+							Edits: []source.TextEdit{{Row: 6, Col: 2, Text: "_"}},
+						},
+						{
+							Title: "Remove v",
+							Edits: []source.TextEdit{{Row: 6, Col: 1, End: &source.Position{Row: 7, Col: 1}}},
+						},
+					},
+				},
+			},
+		},
+		"Should not report the variables which are used in the head of the comprehension or the outer scope": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+names[name] {
+	name := input.name
+	ids := {id: n | n := input.users[id].name}
+	count([u | u := ids[_]]) > 0
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+		"Should not report variables which are used or ignored": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import future.keywords.every
+
+violation[msg] {
+	_x := 1
+	m := "hello"
+	every v in input.list {
+		v > 0
+	}
+	msg := m
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+		"Should not report the ref key which is assigned before": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	i := 1
+	input.list[i]
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+		"Should not report the ref key which is declared by some": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	some i
+	input.list[i]
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+		"Should not report the ref keys which join the refs": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	input.a[i]
+	input.b[i]
+}`,
+				},
+			},
+			path:        "src.rego",
+			expectDiags: []source.Diagnostic{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.UnusedVariables(tt.path)
+			if diff := cmp.Diff(tt.expectDiags, got); diff != "" {
+				t.Errorf("UnusedVariables result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package source
+
+import (
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// previewContextRows is the number of rows shown before and after the definition.
+const previewContextRows = 2
+
+type Preview struct {
+	Location *ast.Location
+
+	// StartRow is the row of the first line of Text.
+	StartRow int
+	Text     string
+}
+
+// DefinitionPreview returns the source around each definition of the term at the location.
+// When the definition is a rule, the whole rule is included.
+func (p *Project) DefinitionPreview(location *ast.Location) ([]Preview, error) {
+	locations, err := p.LookupDefinition(location)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Preview, 0, len(locations))
+	for _, loc := range locations {
+		rawText, err := p.GetRawText(loc.File)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(rawText, "\n")
+
+		startRow, endRow := loc.Row, loc.Row+strings.Count(string(loc.Text), "\n")
+		if rule := p.findRuleStartingAt(loc); rule != nil {
+			startRow, endRow = ruleRows(rule)
+		}
+
+		startRow -= previewContextRows
+		if startRow < 1 {
+			startRow = 1
+		}
+		endRow += previewContextRows
+		if endRow > len(lines) {
+			endRow = len(lines)
+		}
+
+		result = append(result, Preview{
+			Location: loc,
+			StartRow: startRow,
+			Text:     strings.Join(lines[startRow-1:endRow], "\n"),
+		})
+	}
+	return result, nil
+}
+
+// findRuleStartingAt returns the rule whose name is at the location.
+func (p *Project) findRuleStartingAt(loc *ast.Location) *ast.Rule {
+	module := p.GetModule(loc.File)
+	if module == nil {
+		return nil
+	}
+
+	for _, r := range module.Rules {
+		if r.Location.Offset == loc.Offset {
+			return r
+		}
+	}
+	return nil
+}
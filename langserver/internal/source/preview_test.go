@@ -0,0 +1,113 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_DefinitionPreview(t *testing.T) {
+	tests := map[string]struct {
+		files          map[string]source.File
+		createLocation createLocationFunc
+		expectPreviews []source.Preview
+	}{
+		"Should return the whole rule with the context rows": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+violation[msg] {
+	lib.is_admin(input.user)
+	msg := "admin"
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+# is_admin checks the role.
+is_admin(user) {
+	user.role == "admin"
+}
+
+is_guest(user) {
+	user.role == "guest"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 7, "src.rego"),
+			expectPreviews: []source.Preview{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    1,
+						Offset: len("package lib\n\n# is_admin checks the role.\n"),
+						Text:   []byte("is_admin"),
+						File:   "lib.rego",
+					},
+					StartRow: 2,
+					Text: `
+# is_admin checks the role.
+is_admin(user) {
+	user.role == "admin"
+}
+
+is_guest(user) {`,
+				},
+			},
+		},
+		"Should return the line of the variable with the context rows": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	m := "hello"
+	msg := m
+}`,
+				},
+			},
+			createLocation: createLocation(5, 9, "src.rego"),
+			expectPreviews: []source.Preview{
+				{
+					Location: &ast.Location{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nviolation[msg] {\n\t"),
+						Text:   []byte("m"),
+						File:   "src.rego",
+					},
+					StartRow: 2,
+					Text: `
+violation[msg] {
+	m := "hello"
+	msg := m
+}`,
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.DefinitionPreview(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectPreviews, got); diff != "" {
+				t.Errorf("DefinitionPreview result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package source
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/kitagry/regols/langserver/internal/cache"
 	"github.com/open-policy-agent/opa/ast"
 )
@@ -8,6 +15,19 @@ import (
 type Project struct {
 	rootPath string
 	cache    *cache.GlobalCache
+	schemas  map[string]*jsonSchema
+
+	// schemaFiles has the files of the schemas which are registered by SetSchemaFile.
+	schemaFiles map[string]schemaFile
+
+	definitionMode     DefinitionMode
+	unqualifiedRefMode UnqualifiedRefMode
+	maxDiagnostics     int
+
+	// hideUnderscoreRules hides the rules like `_helper` from the completion and the symbols.
+	hideUnderscoreRules bool
+
+	completionCache completionCache
 }
 
 type File struct {
@@ -15,45 +35,178 @@ type File struct {
 	Version int
 }
 
-func NewProject(rootPath string) (*Project, error) {
+// ProjectOption configures the project when it is created.
+type ProjectOption func(*Project)
+
+// WithMaxDiagnostics caps the number of the errors per file which GetErrors and GetAllDiagnostics return.
+// The rest of the errors are summarized into one error like "and 3 more". n <= 0 means no limit.
+func WithMaxDiagnostics(n int) ProjectOption {
+	return func(p *Project) {
+		p.maxDiagnostics = n
+	}
+}
+
+// NewProject loads the rego files under the rootPath.
+// When rootPath is empty, the project has no files and the files are added by UpdateFile.
+func NewProject(rootPath string, opts ...ProjectOption) (*Project, error) {
+	if rootPath == "" {
+		return NewProjectWithFiles(map[string]File{}, opts...)
+	}
+
+	info, err := os.Stat(rootPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("root path %s does not exist", rootPath)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open root path %s: %w", rootPath, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root path %s is not a directory", rootPath)
+	}
+
 	cache, err := cache.NewGlobalCache(rootPath)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Project{
+	p := &Project{
 		rootPath: rootPath,
 		cache:    cache,
-	}, nil
-}
-
-func NewProjectWithFiles(files map[string]File) (*Project, error) {
-	ff := make(map[string]string, len(files))
-	for path, file := range files {
-		ff[path] = file.RawText
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
+}
 
-	cache, err := cache.NewGlobalCacheWithFiles(ff)
+func NewProjectWithFiles(files map[string]File, opts ...ProjectOption) (*Project, error) {
+	cache, err := cache.NewGlobalCacheWithFiles(map[string]string{})
 	if err != nil {
 		return nil, err
 	}
 
-	return &Project{
+	for path, file := range files {
+		if _, err := cache.PutWithVersion(path, file.RawText, file.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Project{
 		cache: cache,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p, nil
 }
 
+// UpdateFile updates the file text. The text is ignored when the version is older than the current one.
 func (p *Project) UpdateFile(path string, text string, version int) error {
-	p.cache.Put(path, text)
+	p.completionCache.invalidate(path)
+	_, err := p.cache.PutWithVersion(path, text, version)
+	return err
+}
 
-	return nil
+// FileVersion returns the version of the file which is applied last.
+func (p *Project) FileVersion(path string) (int, bool) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return 0, false
+	}
+	return policy.Version, true
 }
 
 func (p *Project) GetErrors(path string) map[string]ast.Errors {
 	errs := p.cache.GetErrors(path)
+	for path, e := range errs {
+		errs[path] = truncateErrors(e, p.maxDiagnostics)
+	}
 	return errs
 }
 
+// GetErrorDiagnostics returns the errors of GetErrors as the diagnostics which have the severity and the source.
+func (p *Project) GetErrorDiagnostics(path string) map[string][]Diagnostic {
+	result := make(map[string][]Diagnostic)
+	for path, errs := range p.GetErrors(path) {
+		result[path] = p.errorDiagnostics(errs)
+	}
+	return result
+}
+
+// GetAllErrorDiagnostics returns the errors of GetAllDiagnostics as the diagnostics like GetErrorDiagnostics.
+func (p *Project) GetAllErrorDiagnostics(path string) map[string][]Diagnostic {
+	errs := p.GetAllDiagnostics(path)
+	result := make(map[string][]Diagnostic, len(errs))
+	for path, e := range errs {
+		result[path] = p.errorDiagnostics(e)
+	}
+	return result
+}
+
+// errorDiagnostics converts the errors with the related information which needs the loaded modules.
+func (p *Project) errorDiagnostics(errs ast.Errors) []Diagnostic {
+	result := ErrorDiagnostics(errs)
+	for i, d := range result {
+		if d.Code == ast.RecursionErr {
+			result[i].Related = p.recursionRelated(d)
+		}
+	}
+	return result
+}
+
+// GetAllDiagnostics returns the errors of all files bucketed by the file which has the location of the error.
+// The files without errors have empty errors, so the stale diagnostics can be cleared.
+// The compile errors without location like "error limit reached" are attached to the head of the file of the path.
+func (p *Project) GetAllDiagnostics(path string) map[string]ast.Errors {
+	errs := p.cache.GetAllErrors()
+	if noLocation, ok := errs[""]; ok {
+		delete(errs, "")
+		if path != "" {
+			for _, e := range noLocation {
+				attached := *e
+				attached.Location = &ast.Location{Row: 1, Col: 1, File: path, Text: []byte{}}
+				errs[path] = append(errs[path], &attached)
+			}
+		}
+	}
+	for path, e := range errs {
+		errs[path] = truncateErrors(e, p.maxDiagnostics)
+	}
+	return errs
+}
+
+// SetHideUnderscoreRules hides the rules whose names start with `_` from the completion and the symbols,
+// because they are private by convention. They are shown by default.
+func (p *Project) SetHideUnderscoreRules(hide bool) {
+	p.hideUnderscoreRules = hide
+	p.completionCache.clear()
+}
+
+// isHiddenRule returns true when the rule is hidden by SetHideUnderscoreRules.
+func (p *Project) isHiddenRule(rule *ast.Rule) bool {
+	return p.hideUnderscoreRules && strings.HasPrefix(ruleHeadName(rule), "_")
+}
+
+// SetResolveSymlinks makes the symlink path and the real path of the same file point to one file,
+// so the navigation works regardless of which path the client sends.
+func (p *Project) SetResolveSymlinks(enabled bool) {
+	p.cache.SetResolveSymlinks(enabled)
+}
+
+// truncateErrors returns the first max errors and the summary of the rest, which is located at the first dropped error.
+func truncateErrors(errs ast.Errors, max int) ast.Errors {
+	if max <= 0 || len(errs) <= max {
+		return errs
+	}
+
+	rest := errs[max]
+	result := append(make(ast.Errors, 0, max+1), errs[:max]...)
+	return append(result, &ast.Error{
+		Code:     rest.Code,
+		Message:  fmt.Sprintf("and %d more", len(errs)-max),
+		Location: rest.Location,
+	})
+}
+
 func (p *Project) GetFile(path string) (string, bool) {
 	policy := p.cache.Get(path)
 	if policy == nil {
@@ -62,7 +215,101 @@ func (p *Project) GetFile(path string) (string, bool) {
 	return policy.RawText, true
 }
 
+// LocationFromPosition converts the 1-based row and col of the editor into the location.
+// col counts characters, so a tab and a multibyte character are one column each like the parser does.
+func (p *Project) LocationFromPosition(path string, row, col int) (*ast.Location, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if row < 1 || col < 1 {
+		return nil, fmt.Errorf("invalid position %d:%d", row, col)
+	}
+
+	offset, err := positionToOffset(policy.RawText, row, col)
+	if err != nil {
+		return nil, fmt.Errorf("%w in %s", err, path)
+	}
+
+	return &ast.Location{
+		Row:    row,
+		Col:    col,
+		Offset: offset,
+		File:   p.cache.ResolvePath(path),
+	}, nil
+}
+
+// positionToOffset converts the 1-based row and col into the byte offset of the text.
+func positionToOffset(rawText string, row, col int) (int, error) {
+	offset := 0
+	for i := 1; i < row; i++ {
+		next := strings.Index(rawText[offset:], "\n")
+		if next < 0 {
+			return 0, fmt.Errorf("row %d is out of range", row)
+		}
+		offset += next + 1
+	}
+
+	line := rawText[offset:]
+	if end := strings.Index(line, "\n"); end >= 0 {
+		line = line[:end]
+	}
+	for i := 1; i < col; i++ {
+		if len(line) == 0 {
+			return 0, fmt.Errorf("col %d is out of row %d", col, row)
+		}
+		_, size := utf8.DecodeRuneInString(line)
+		line = line[size:]
+		offset += size
+	}
+	return offset, nil
+}
+
+// ContentChange is the change of the file text.
+// When Range is nil, Text is the whole text of the file.
+type ContentChange struct {
+	Range *Range
+	Text  string
+}
+
+// UpdateFileIncremental applies the changes to the current text in order and parses the result once.
+// The changes are ignored when the version is older than the current one.
+func (p *Project) UpdateFileIncremental(path string, changes []ContentChange, version int) error {
+	var text string
+	if policy := p.cache.Get(path); policy != nil {
+		if policy.Version > version {
+			return nil
+		}
+		text = policy.RawText
+	}
+
+	for _, c := range changes {
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+
+		start, err := positionToOffset(text, c.Range.Start.Row, c.Range.Start.Col)
+		if err != nil {
+			return fmt.Errorf("failed to apply the change to %s: %w", path, err)
+		}
+		end, err := positionToOffset(text, c.Range.End.Row, c.Range.End.Col)
+		if err != nil {
+			return fmt.Errorf("failed to apply the change to %s: %w", path, err)
+		}
+		if start > end {
+			return fmt.Errorf("failed to apply the change to %s: the range %d:%d-%d:%d is reversed", path, c.Range.Start.Row, c.Range.Start.Col, c.Range.End.Row, c.Range.End.Col)
+		}
+		text = text[:start] + c.Text + text[end:]
+	}
+
+	p.completionCache.invalidate(path)
+	_, err := p.cache.PutWithVersion(path, text, version)
+	return err
+}
+
 func (p *Project) DeleteFile(path string) {
+	p.completionCache.clear()
 	p.cache.Delete(path)
 }
 
@@ -0,0 +1,769 @@
+package source_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestNewProject(t *testing.T) {
+	tests := map[string]struct {
+		rootPath  string
+		expectErr string
+	}{
+		"Should create the empty project when the root path is empty": {
+			rootPath: "",
+		},
+		"Should create the empty project when the root directory has no files": {
+			rootPath: t.TempDir(),
+		},
+		"Should return error when the root path does not exist": {
+			rootPath:  "testdata/not_exist",
+			expectErr: "root path testdata/not_exist does not exist",
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProject(tt.rootPath)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Fatalf("NewProject should return error %q, but got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the opened document can be handled without the files in the root path.
+			if err := project.UpdateFile("src.rego", "package src", 1); err != nil {
+				t.Fatal(err)
+			}
+			if text, ok := project.GetFile("src.rego"); !ok || text != "package src" {
+				t.Errorf("GetFile should return the updated text, but got %q, %v", text, ok)
+			}
+		})
+	}
+}
+
+func TestProject_UpdateFile(t *testing.T) {
+	type update struct {
+		text    string
+		version int
+	}
+
+	tests := map[string]struct {
+		updates       []update
+		expectText    string
+		expectVersion int
+	}{
+		"Should apply the newer version": {
+			updates: []update{
+				{text: "package v1", version: 1},
+				{text: "package v2", version: 2},
+			},
+			expectText:    "package v2",
+			expectVersion: 2,
+		},
+		"Should ignore the older version": {
+			updates: []update{
+				{text: "package v3", version: 3},
+				{text: "package v2", version: 2},
+			},
+			expectText:    "package v3",
+			expectVersion: 3,
+		},
+		"Should apply the same version": {
+			updates: []update{
+				{text: "package v1", version: 1},
+				{text: "package v1_again", version: 1},
+			},
+			expectText:    "package v1_again",
+			expectVersion: 1,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(map[string]source.File{
+				"src.rego": {RawText: "package src"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, u := range tt.updates {
+				if err := project.UpdateFile("src.rego", u.text, u.version); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			text, _ := project.GetFile("src.rego")
+			if text != tt.expectText {
+				t.Errorf("text should be %q, but got %q", tt.expectText, text)
+			}
+
+			version, ok := project.FileVersion("src.rego")
+			if !ok {
+				t.Fatal("FileVersion should find src.rego")
+			}
+			if version != tt.expectVersion {
+				t.Errorf("version should be %d, but got %d", tt.expectVersion, version)
+			}
+		})
+	}
+}
+
+func TestProject_UpdateFileIncremental(t *testing.T) {
+	rawText := "package src\n\nallow {\n\tinput.name == \"こんにちは\"\n}"
+
+	tests := map[string]struct {
+		changes       []source.ContentChange
+		version       int
+		expectText    string
+		expectVersion int
+		expectErr     bool
+	}{
+		"Should apply the ranged change": {
+			changes: []source.ContentChange{
+				{
+					Range: &source.Range{Start: source.Position{Row: 3, Col: 1}, End: source.Position{Row: 3, Col: 6}},
+					Text:  "deny",
+				},
+			},
+			version:       2,
+			expectText:    "package src\n\ndeny {\n\tinput.name == \"こんにちは\"\n}",
+			expectVersion: 2,
+		},
+		"Should apply the changes in order": {
+			changes: []source.ContentChange{
+				{
+					Range: &source.Range{Start: source.Position{Row: 4, Col: 16}, End: source.Position{Row: 4, Col: 23}},
+					Text:  `"hello"`,
+				},
+				{
+					Range: &source.Range{Start: source.Position{Row: 4, Col: 23}, End: source.Position{Row: 4, Col: 23}},
+					Text:  "\n\tinput.age > 20",
+				},
+			},
+			version:       2,
+			expectText:    "package src\n\nallow {\n\tinput.name == \"hello\"\n\tinput.age > 20\n}",
+			expectVersion: 2,
+		},
+		"Should replace the whole text when the change has no range": {
+			changes: []source.ContentChange{
+				{Text: "package replaced"},
+			},
+			version:       2,
+			expectText:    "package replaced",
+			expectVersion: 2,
+		},
+		"Should ignore the older version": {
+			changes: []source.ContentChange{
+				{Text: "package old"},
+			},
+			version:       0,
+			expectText:    rawText,
+			expectVersion: 1,
+		},
+		"Should return error when the range is out of the text": {
+			changes: []source.ContentChange{
+				{
+					Range: &source.Range{Start: source.Position{Row: 10, Col: 1}, End: source.Position{Row: 10, Col: 1}},
+					Text:  "x",
+				},
+			},
+			version:   2,
+			expectErr: true,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(map[string]source.File{
+				"src.rego": {RawText: rawText, Version: 1},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = project.UpdateFileIncremental("src.rego", tt.changes, tt.version)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatal("UpdateFileIncremental should return error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text, _ := project.GetFile("src.rego")
+			if text != tt.expectText {
+				t.Errorf("text should be %q, but got %q", tt.expectText, text)
+			}
+
+			version, _ := project.FileVersion("src.rego")
+			if version != tt.expectVersion {
+				t.Errorf("version should be %d, but got %d", tt.expectVersion, version)
+			}
+		})
+	}
+}
+
+func TestProject_GetErrors(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"lib.rego":   {RawText: "package lib\n\nf(x) = x"},
+		"src.rego":   {RawText: "package src\n\nimport data.lib\n\nallow {\n\tlib.f(1)\n}"},
+		"other.rego": {RawText: "package other\n\nallow {\n\tundefined_func(1)\n}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type step struct {
+		update       map[string]string
+		delete       string
+		expectCounts map[string]int
+	}
+	steps := []step{
+		{
+			expectCounts: map[string]int{"lib.rego": 0, "src.rego": 0, "other.rego": 1},
+		},
+		{
+			// src.rego refers to lib, so it is compiled again.
+			update:       map[string]string{"lib.rego": "package lib\n\ng(x) = x"},
+			expectCounts: map[string]int{"lib.rego": 0, "src.rego": 1, "other.rego": 1},
+		},
+		{
+			update:       map[string]string{"lib.rego": "package lib\n\nf(x) = x"},
+			expectCounts: map[string]int{"lib.rego": 0, "src.rego": 0, "other.rego": 1},
+		},
+		{
+			// the parse error is merged with the compile error of the module parsed last time.
+			update:       map[string]string{"other.rego": "package other\n\nallow {\n\tlib.f(1)"},
+			expectCounts: map[string]int{"lib.rego": 0, "src.rego": 0, "other.rego": 2},
+		},
+		{
+			delete:       "other.rego",
+			expectCounts: map[string]int{"lib.rego": 0, "src.rego": 0},
+		},
+	}
+
+	for i, s := range steps {
+		for path, text := range s.update {
+			if err := project.UpdateFile(path, text, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		path := "src.rego"
+		for p := range s.update {
+			path = p
+		}
+		if s.delete != "" {
+			project.DeleteFile(s.delete)
+		}
+
+		got := make(map[string]int)
+		for p, errs := range project.GetErrors(path) {
+			got[p] = len(errs)
+		}
+		if diff := cmp.Diff(s.expectCounts, got); diff != "" {
+			t.Errorf("step %d: GetErrors count diff (-expect, +got)\n%s", i, diff)
+		}
+	}
+}
+
+func TestProject_GetErrorsWithParseErrorInOtherFile(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego":    {RawText: "package src\n\nallow {\n\tundefined_func(1)\n}"},
+		"broken.rego": {RawText: "package broken\n\nallow {"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string][]string{
+		"src.rego":    {"src.rego:4 rego_type_error"},
+		"broken.rego": {"broken.rego:3 rego_parse_error"},
+	}
+	// the result is the same whichever file is changed.
+	for _, path := range []string{"src.rego", "broken.rego"} {
+		got := make(map[string][]string)
+		for p, errs := range project.GetErrors(path) {
+			got[p] = make([]string, 0, len(errs))
+			for _, e := range errs {
+				got[p] = append(got[p], fmt.Sprintf("%s:%d %s", e.Location.File, e.Location.Row, e.Code))
+			}
+		}
+		if diff := cmp.Diff(expect, got); diff != "" {
+			t.Errorf("GetErrors(%s) result diff (-expect, +got)\n%s", path, diff)
+		}
+	}
+}
+
+func TestProject_GetErrorsAfterFixingEarlierStage(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"a.rego": {RawText: "package a\n\nallow {\n\ty == 1\n}"},
+		"b.rego": {RawText: "package b\n\nallow {\n\tcount(1)\n}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getErrors := func(path string) map[string][]string {
+		got := make(map[string][]string)
+		for p, errs := range project.GetErrors(path) {
+			got[p] = make([]string, 0, len(errs))
+			for _, e := range errs {
+				got[p] = append(got[p], fmt.Sprintf("%s:%d %s", e.Location.File, e.Location.Row, e.Code))
+			}
+		}
+		return got
+	}
+
+	// the compiler stops at the unsafe var, so the type error of b.rego is not reported yet.
+	expect := map[string][]string{
+		"a.rego": {"a.rego:4 rego_unsafe_var_error"},
+		"b.rego": {},
+	}
+	if diff := cmp.Diff(expect, getErrors("a.rego")); diff != "" {
+		t.Errorf("GetErrors result diff (-expect, +got)\n%s", diff)
+	}
+
+	if err := project.UpdateFile("a.rego", "package a\n\nallow {\n\tx := 1\n\tx == 1\n}", 1); err != nil {
+		t.Fatal(err)
+	}
+	expect = map[string][]string{
+		"a.rego": {},
+		"b.rego": {"b.rego:4 rego_type_error"},
+	}
+	if diff := cmp.Diff(expect, getErrors("a.rego")); diff != "" {
+		t.Errorf("GetErrors result diff (-expect, +got)\n%s", diff)
+	}
+}
+
+func TestProject_GetErrorDiagnostics(t *testing.T) {
+	tests := map[string]struct {
+		files  map[string]source.File
+		path   string
+		expect map[string][]string
+	}{
+		"Should return the compile error as error": {
+			files: map[string]source.File{
+				"src.rego":    {RawText: "package src\n\nallow {\n\tundefined_func(1)\n}"},
+				"broken.rego": {RawText: "package broken\n\nallow {"},
+			},
+			path: "src.rego",
+			expect: map[string][]string{
+				"src.rego":    {"src.rego:4 error compile rego_type_error"},
+				"broken.rego": {"broken.rego:3 error parse rego_parse_error"},
+			},
+		},
+		"Should return the deprecated built-in in rego.v1 as warning": {
+			files: map[string]source.File{
+				"src.rego": {RawText: "package src\n\nimport rego.v1\n\nallow if {\n\tany([true])\n}"},
+			},
+			path: "src.rego",
+			expect: map[string][]string{
+				"src.rego": {"src.rego:6 warning compile rego_type_error"},
+			},
+		},
+	}
+
+	severities := map[source.Severity]string{
+		source.SeverityError:   "error",
+		source.SeverityWarning: "warning",
+	}
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string][]string)
+			for p, diagnostics := range project.GetErrorDiagnostics(tt.path) {
+				got[p] = make([]string, 0, len(diagnostics))
+				for _, d := range diagnostics {
+					got[p] = append(got[p], fmt.Sprintf("%s:%d %s %s %s", d.Location.File, d.Location.Row, severities[d.Severity], d.Source, d.Code))
+				}
+			}
+			if diff := cmp.Diff(tt.expect, got); diff != "" {
+				t.Errorf("GetErrorDiagnostics result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_GetErrorDiagnosticsWithRecursion(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego":   {RawText: "package main\n\na {\n\tb\n}"},
+		"other.rego": {RawText: "package main\n\nb {\n\ta\n}"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// each rule in the cycle is related to the error of the other rule.
+	expect := map[string][]string{
+		"src.rego":   {"src.rego:3 rego_recursion_error", "other.rego:3 data.main.b is in the recursion"},
+		"other.rego": {"other.rego:3 rego_recursion_error", "src.rego:3 data.main.a is in the recursion"},
+	}
+
+	got := make(map[string][]string)
+	for p, diagnostics := range project.GetErrorDiagnostics("src.rego") {
+		got[p] = make([]string, 0)
+		for _, d := range diagnostics {
+			got[p] = append(got[p], fmt.Sprintf("%s:%d %s", d.Location.File, d.Location.Row, d.Code))
+			for _, r := range d.Related {
+				got[p] = append(got[p], fmt.Sprintf("%s:%d %s", r.Location.File, r.Location.Row, r.Message))
+			}
+		}
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("GetErrorDiagnostics result diff (-expect, +got)\n%s", diff)
+	}
+}
+
+func TestProject_GetAllDiagnostics(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"lib.rego":    {RawText: "package lib\n\nf(x) = y {\n\ty := x + \"a\"\n}"},
+		"main.rego":   {RawText: "package main\n\nimport data.lib\n\nallow {\n\tlib.f(1)\n}"},
+		"broken.rego": {RawText: "package broken\n\nallow {"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := project.GetAllDiagnostics("main.rego")
+
+	// the errors are bucketed by the file which has the location of the error.
+	files := make(map[string][]string)
+	for path, errs := range got {
+		files[path] = make([]string, 0, len(errs))
+		for _, e := range errs {
+			files[path] = append(files[path], fmt.Sprintf("%s:%d %s", e.Location.File, e.Location.Row, e.Code))
+		}
+	}
+	expect := map[string][]string{
+		"lib.rego":    {"lib.rego:4 rego_type_error"},
+		"main.rego":   {},
+		"broken.rego": {"broken.rego:3 rego_parse_error"},
+	}
+	if diff := cmp.Diff(expect, files); diff != "" {
+		t.Errorf("GetAllDiagnostics result diff (-expect, +got)\n%s", diff)
+	}
+}
+
+func TestProject_GetAllDiagnosticsWithoutLocation(t *testing.T) {
+	// the compiler stops with "error limit reached" which has no location after 10 errors.
+	body := ""
+	for i := 0; i < 11; i++ {
+		body += fmt.Sprintf("\tundefined_%d(1)\n", i)
+	}
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego":  {RawText: "package src\n\nallow {\n" + body + "}"},
+		"main.rego": {RawText: "package main"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := project.GetAllDiagnostics("main.rego")
+	if _, ok := got[""]; ok {
+		t.Errorf("GetAllDiagnostics should not return the errors without the file, but got %v", got[""])
+	}
+
+	messages := make([]string, 0)
+	for _, e := range got["main.rego"] {
+		messages = append(messages, fmt.Sprintf("%s:%d:%d %s", e.Location.File, e.Location.Row, e.Location.Col, e.Message))
+	}
+	expect := []string{"main.rego:1:1 error limit reached"}
+	if diff := cmp.Diff(expect, messages); diff != "" {
+		t.Errorf("GetAllDiagnostics result diff (-expect, +got)\n%s", diff)
+	}
+}
+
+func TestProject_WithMaxDiagnostics(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego": {RawText: "package src\n\nallow {\n\tundefined_a(1)\n\tundefined_b(1)\n\tundefined_c(1)\n\tundefined_d(1)\n}"},
+		"lib.rego": {RawText: "package lib\n\nallow {\n\tundefined_a(1)\n}"},
+	}, source.WithMaxDiagnostics(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := func(errs ast.Errors) []string {
+		result := make([]string, 0, len(errs))
+		for _, e := range errs {
+			result = append(result, fmt.Sprintf("%d %s", e.Location.Row, e.Message))
+		}
+		return result
+	}
+
+	expect := map[string][]string{
+		"src.rego": {
+			"4 undefined function undefined_a",
+			"5 undefined function undefined_b",
+			"6 and 2 more",
+		},
+		"lib.rego": {
+			"4 undefined function undefined_a",
+		},
+	}
+
+	got := make(map[string][]string)
+	for path, errs := range project.GetErrors("src.rego") {
+		got[path] = messages(errs)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("GetErrors result diff (-expect, +got)\n%s", diff)
+	}
+
+	all := project.GetAllDiagnostics("src.rego")
+	got = make(map[string][]string)
+	for path, errs := range all {
+		got[path] = messages(errs)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("GetAllDiagnostics result diff (-expect, +got)\n%s", diff)
+	}
+}
+
+func TestProject_FileVersion(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego": {RawText: "package src", Version: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	version, ok := project.FileVersion("src.rego")
+	if !ok || version != 2 {
+		t.Errorf("FileVersion should return 2, but got %d, %v", version, ok)
+	}
+
+	if _, ok := project.FileVersion("unknown.rego"); ok {
+		t.Error("FileVersion should not find the unknown file")
+	}
+}
+
+func TestProject_LocationFromPosition(t *testing.T) {
+	rawText := "package src\n\nallow {\n\tmsg := \"こんにちは\"; m := msg\n}"
+
+	tests := map[string]struct {
+		row          int
+		col          int
+		expectOffset int
+		expectTerm   string
+		expectErr    bool
+	}{
+		"Should count a tab as one column": {
+			row:          4,
+			col:          2,
+			expectOffset: len("package src\n\nallow {\n\t"),
+			expectTerm:   "msg",
+		},
+		"Should count a multibyte character as one column": {
+			row:          4,
+			col:          23,
+			expectOffset: len("package src\n\nallow {\n\tmsg := \"こんにちは\"; m := "),
+			expectTerm:   "msg",
+		},
+		"Should accept the end of the line": {
+			row:          5,
+			col:          2,
+			expectOffset: len(rawText),
+		},
+		"Should return error when the row is out of the file": {
+			row:       6,
+			col:       1,
+			expectErr: true,
+		},
+		"Should return error when the col is out of the line": {
+			row:       5,
+			col:       3,
+			expectErr: true,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(map[string]source.File{"src.rego": {RawText: rawText}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.LocationFromPosition("src.rego", tt.row, tt.col)
+			if tt.expectErr {
+				if err == nil {
+					t.Fatalf("LocationFromPosition should return error, but got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Row != tt.row || got.Col != tt.col || got.Offset != tt.expectOffset {
+				t.Errorf("LocationFromPosition should return %d:%d (offset %d), but got %d:%d (offset %d)", tt.row, tt.col, tt.expectOffset, got.Row, got.Col, got.Offset)
+			}
+
+			if tt.expectTerm == "" {
+				return
+			}
+			term, err := project.SearchTargetTerm(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if term == nil || term.String() != tt.expectTerm {
+				t.Errorf("SearchTargetTerm should return %s, but got %v", tt.expectTerm, term)
+			}
+		})
+	}
+}
+
+func TestProject_SetResolveSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	realDir := filepath.Join(dir, "real")
+	if err := os.Mkdir(realDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	srcPath := filepath.Join(realDir, "src.rego")
+	src := `package src
+
+allow {
+	is_admin
+}
+
+is_admin {
+	input.role == "admin"
+}`
+	if err := os.WriteFile(srcPath, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the file is loaded twice through the symlink in the root path.
+	if err := os.Symlink(srcPath, filepath.Join(realDir, "link.rego")); err != nil {
+		t.Skipf("symlink is not supported: %v", err)
+	}
+	// the client opens the file through the symlinked directory.
+	linkDir := filepath.Join(dir, "link")
+	if err := os.Symlink(realDir, linkDir); err != nil {
+		t.Skipf("symlink is not supported: %v", err)
+	}
+
+	project, err := source.NewProject(realDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	project.SetResolveSymlinks(true)
+
+	tests := map[string]struct {
+		path string
+	}{
+		"Should resolve the real path": {
+			path: srcPath,
+		},
+		"Should resolve the symlink file in the root path": {
+			path: filepath.Join(realDir, "link.rego"),
+		},
+		"Should resolve the file in the symlinked directory": {
+			path: filepath.Join(linkDir, "src.rego"),
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			location, err := project.LocationFromPosition(tt.path, 4, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if location.File != srcPath {
+				t.Errorf("LocationFromPosition should return the real path %s, but got %s", srcPath, location.File)
+			}
+
+			got, err := project.LookupDefinition(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the merged link.rego doesn't make the duplicated definition.
+			expect := []*ast.Location{
+				{Row: 7, Col: 1, Offset: 24, Text: []byte("is_admin"), File: srcPath},
+			}
+			if diff := cmp.Diff(expect, got); diff != "" {
+				t.Errorf("LookupDefinition result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Should update the real file through the symlink", func(t *testing.T) {
+		updated := "package src\n\ndeny := true"
+		if err := project.UpdateFile(filepath.Join(linkDir, "src.rego"), updated, 2); err != nil {
+			t.Fatal(err)
+		}
+		if text, ok := project.GetFile(srcPath); !ok || text != updated {
+			t.Errorf("GetFile should return the updated text, but got %q, %v", text, ok)
+		}
+	})
+}
+
+func TestProject_SetHideUnderscoreRules(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+_helper {
+	true
+}
+
+allow {
+	_h
+}`,
+		},
+	}
+	project, err := source.NewProjectWithFiles(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	visible := func() []string {
+		result := make([]string, 0)
+		items, err := project.ListCompletionItems(createLocation(8, 3, "src.rego")(files))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range items {
+			result = append(result, "completion "+item.Label)
+		}
+		symbols, err := project.WorkspaceSymbols("helper")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range symbols {
+			result = append(result, "symbol "+s.Name)
+		}
+		return result
+	}
+
+	// the underscore rules are shown by default.
+	if diff := cmp.Diff([]string{"completion _helper", "symbol _helper"}, visible()); diff != "" {
+		t.Errorf("the underscore rule should be shown by default (-expect, +got)\n%s", diff)
+	}
+
+	project.SetHideUnderscoreRules(true)
+	if diff := cmp.Diff([]string{}, visible()); diff != "" {
+		t.Errorf("the underscore rule should be hidden (-expect, +got)\n%s", diff)
+	}
+
+	project.SetHideUnderscoreRules(false)
+	if diff := cmp.Diff([]string{"completion _helper", "symbol _helper"}, visible()); diff != "" {
+		t.Errorf("the underscore rule should be shown again (-expect, +got)\n%s", diff)
+	}
+}
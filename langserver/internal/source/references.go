@@ -17,30 +17,38 @@ func (p *Project) LookupReferences(loc *ast.Location) ([]*ast.Location, error) {
 		return nil, nil
 	}
 
-	result := p.findReferences(term)
+	return uniqueLocations(p.findReferences(term)), nil
+}
+
+// uniqueLocations drops duplicated locations and sorts them by file, row and col.
+func uniqueLocations(locations []*ast.Location) []*ast.Location {
+	if locations == nil {
+		return nil
+	}
 
-	// drop duplicates
 	exists := make(map[string]*ast.Location)
-	for _, r := range result {
+	for _, r := range locations {
 		key := fmt.Sprintf("%s-%d-%d", r.File, r.Row, r.Col)
 		exists[key] = r
 	}
 
-	result = make([]*ast.Location, 0, len(exists))
+	result := make([]*ast.Location, 0, len(exists))
 	for _, l := range exists {
 		result = append(result, l)
 	}
 
-	// sort
 	sort.Slice(result, func(i, j int) bool {
 		if result[i].File != result[j].File {
 			return result[i].File < result[j].File
 		}
 
-		return result[i].Row < result[j].Row
+		if result[i].Row != result[j].Row {
+			return result[i].Row < result[j].Row
+		}
+		return result[i].Col < result[j].Col
 	})
 
-	return result, nil
+	return result
 }
 
 func (p *Project) findReferences(term *ast.Term) []*ast.Location {
@@ -133,6 +141,8 @@ func getTermForPackage(term *ast.Term, termModule, targetModule *ast.Module) *as
 
 			switch v := term.Value.(type) {
 			case ast.Ref:
+				// copy not to rewrite the original term which is used for other modules.
+				v = v.Copy()
 				v[0] = &ast.Term{
 					Value:    prefix,
 					Location: v[0].Location,
@@ -177,17 +187,8 @@ func findPackageName(term *ast.Term, termModule *ast.Module) (*ast.Term, bool) {
 		return nil, false
 	}
 
-	for _, imp := range termModule.Imports {
-		if imp.Alias != "" && val.Equal(imp.Alias) {
-			return imp.Path, true
-		}
-
-		if imp.Alias == "" {
-			ref, ok := imp.Path.Value.(ast.Ref)
-			if ok && val.String() == string(ref[len(ref)-1].Value.(ast.String)) {
-				return imp.Path, true
-			}
-		}
+	if imp := findImportByName(val, termModule.Imports); imp != nil {
+		return imp.Path, true
 	}
 	return nil, false
 }
@@ -697,6 +697,70 @@ is_hello(msg) {
 				},
 			},
 		},
+		"Should list alias function with multi-segment package": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib.util as u
+
+violation[msg] {
+	u.is_hello(msg)
+}`,
+				},
+				"src2.rego": {
+					RawText: `package src2
+
+import data.lib.util
+
+violation[msg] {
+	util.is_hello(msg)
+}`,
+				},
+				"util.rego": {
+					RawText: `package lib.util
+
+is_hello(msg) {
+	msg == "hello"
+}
+
+f {
+	is_hello("a")
+}`,
+				},
+			},
+			createLocation: createLocation(6, 4, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    6,
+					Col:    4,
+					File:   "src.rego",
+					Offset: len("package src\n\nimport data.lib.util as u\n\nviolation[msg] {\n\tu."),
+					Text:   []byte("is_hello"),
+				},
+				{
+					Row:    6,
+					Col:    7,
+					File:   "src2.rego",
+					Offset: len("package src2\n\nimport data.lib.util\n\nviolation[msg] {\n\tutil."),
+					Text:   []byte("is_hello"),
+				},
+				{
+					Row:    3,
+					Col:    1,
+					File:   "util.rego",
+					Offset: len("package lib.util\n\n"),
+					Text:   []byte("is_hello"),
+				},
+				{
+					Row:    8,
+					Col:    2,
+					File:   "util.rego",
+					Offset: len("package lib.util\n\nis_hello(msg) {\n\tmsg == \"hello\"\n}\n\nf {\n\t"),
+					Text:   []byte("is_hello"),
+				},
+			},
+		},
 		"Should list imports": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// futureKeywords are keywords which are imported by `import future.keywords` or `import rego.v1`.
+var futureKeywords = []string{"in", "every", "if", "contains"}
+
+var varNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Rename returns the text edits for each file to rename the rule or the variable at the location.
+func (p *Project) Rename(location *ast.Location, newName string) (map[string][]TextEdit, error) {
+	if !varNamePattern.MatchString(newName) {
+		return nil, fmt.Errorf("%q is not a valid name", newName)
+	}
+	if isReservedWord(newName) {
+		return nil, fmt.Errorf("%q is a reserved word", newName)
+	}
+
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, fmt.Errorf("no symbol to rename at %d:%d", location.Row, location.Col)
+	}
+
+	switch kind := p.symbolKind(term); kind {
+	case VariableSymbol:
+	case RuleSymbol, FunctionSymbol:
+		if err := p.validateRuleName(term, newName); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s cannot be renamed", term.String())
+	}
+
+	result := make(map[string][]TextEdit)
+	for _, l := range uniqueLocations(p.findReferences(term)) {
+		result[l.File] = append(result[l.File], TextEdit{
+			Row:  l.Row,
+			Col:  l.Col,
+			Text: newName,
+			End: &Position{
+				Row: l.Row,
+				Col: l.Col + len(l.Text),
+			},
+		})
+	}
+	return result, nil
+}
+
+// validateRuleName returns error when the package which defines the rule already has the rule named newName.
+func (p *Project) validateRuleName(term *ast.Term, newName string) error {
+	rules := p.findRulesInModule(term)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	module := p.GetModule(rules[0].Location.File)
+	if module == nil {
+		return nil
+	}
+
+	for _, m := range p.cache.FindPolicies(module.Package.Path) {
+		for _, r := range m.Rules {
+			if ruleHeadName(r) == newName {
+				return fmt.Errorf("rule %s already exists in %s", newName, module.Package.Path.String())
+			}
+		}
+	}
+	return nil
+}
+
+// isReservedWord returns true when the name cannot be used as a variable or a rule name.
+func isReservedWord(name string) bool {
+	if ast.IsKeyword(name) || ast.ReservedVars.Contains(ast.Var(name)) {
+		return true
+	}
+	for _, k := range futureKeywords {
+		if k == name {
+			return true
+		}
+	}
+	return false
+}
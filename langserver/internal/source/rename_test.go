@@ -0,0 +1,177 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_Rename(t *testing.T) {
+	tests := map[string]struct {
+		files          map[string]source.File
+		createLocation createLocationFunc
+		newName        string
+		expectEdits    map[string][]source.TextEdit
+		expectErr      string
+	}{
+		"Should rename local variable only in the rule": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	m := "hello"
+	msg := m
+}
+
+deny[msg] {
+	m := "hello"
+	msg := m
+}`,
+				},
+			},
+			createLocation: createLocation(5, 9, "src.rego"),
+			newName:        "message",
+			expectEdits: map[string][]source.TextEdit{
+				"src.rego": {
+					{Row: 4, Col: 2, Text: "message", End: &source.Position{Row: 4, Col: 3}},
+					{Row: 5, Col: 9, Text: "message", End: &source.Position{Row: 5, Col: 10}},
+				},
+			},
+		},
+		"Should rename rule and the callers in other packages": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib.util as u
+
+violation[msg] {
+	u.is_hello(msg)
+}`,
+				},
+				"util.rego": {
+					RawText: `package lib.util
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(3, 1, "util.rego"),
+			newName:        "is_world",
+			expectEdits: map[string][]source.TextEdit{
+				"src.rego": {
+					{Row: 6, Col: 4, Text: "is_world", End: &source.Position{Row: 6, Col: 12}},
+				},
+				"util.rego": {
+					{Row: 3, Col: 1, Text: "is_world", End: &source.Position{Row: 3, Col: 9}},
+				},
+			},
+		},
+		"Should reject the name which collides with the rule in the same package": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}`,
+				},
+				"src2.rego": {
+					RawText: `package src
+
+deny {
+	true
+}`,
+				},
+			},
+			createLocation: createLocation(3, 1, "src.rego"),
+			newName:        "deny",
+			expectErr:      "rule deny already exists in data.src",
+		},
+		"Should rename the default rule without the default keyword": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+default allow = false
+
+allow {
+	input.admin
+}
+
+deny {
+	not allow
+}`,
+				},
+			},
+			createLocation: createLocation(5, 1, "src.rego"),
+			newName:        "permit",
+			expectEdits: map[string][]source.TextEdit{
+				"src.rego": {
+					{Row: 3, Col: 9, Text: "permit", End: &source.Position{Row: 3, Col: 14}},
+					{Row: 5, Col: 1, Text: "permit", End: &source.Position{Row: 5, Col: 6}},
+					{Row: 10, Col: 6, Text: "permit", End: &source.Position{Row: 10, Col: 11}},
+				},
+			},
+		},
+		"Should reject the name which collides with the rule of the ref head": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}
+
+users.admin := "alice"`,
+				},
+			},
+			createLocation: createLocation(3, 1, "src.rego"),
+			newName:        "users",
+			expectErr:      "rule users already exists in data.src",
+		},
+		"Should reject reserved word": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	m := "hello"
+	msg := m
+}`,
+				},
+			},
+			createLocation: createLocation(4, 2, "src.rego"),
+			newName:        "every",
+			expectErr:      `"every" is a reserved word`,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.Rename(location, tt.newName)
+			if tt.expectErr != "" {
+				if err == nil || err.Error() != tt.expectErr {
+					t.Fatalf("Rename should return error %q, but got %v", tt.expectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectEdits, got); diff != "" {
+				t.Errorf("Rename result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package source
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// jsonSchema is the subset of JSON Schema which is used for completion.
+type jsonSchema struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Properties  map[string]*jsonSchema `json:"properties"`
+	Required    []string               `json:"required"`
+	Items       *jsonSchema            `json:"items"`
+}
+
+func (s *jsonSchema) isRequired(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+// propertyDetail returns the type of the property and whether it is required like "string (required)".
+func (s *jsonSchema) propertyDetail(name string) string {
+	typ := s.Properties[name].Type
+	if typ == "" {
+		typ = "any"
+	}
+	required := "optional"
+	if s.isRequired(name) {
+		required = "required"
+	}
+	return fmt.Sprintf("%s (%s)", typ, required)
+}
+
+// sortedPropertyNames returns the names of the properties in the alphabetical order.
+func (s *jsonSchema) sortedPropertyNames() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetSchema registers the JSON schema of the document like `input` or `data.config`.
+func (p *Project) SetSchema(root ast.Ref, raw []byte) error {
+	var schema jsonSchema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return fmt.Errorf("failed to parse schema of %s: %w", root, err)
+	}
+
+	if p.schemas == nil {
+		p.schemas = make(map[string]*jsonSchema)
+	}
+	p.schemas[root.String()] = &schema
+	p.completionCache.clear()
+	return nil
+}
+
+// schemaFile is the file of the schema which is the destination of TypeDefinition.
+type schemaFile struct {
+	path string
+	raw  []byte
+}
+
+// SetSchemaFile registers the JSON schema in the file like SetSchema.
+// The file is also the type definition of the refs under the root.
+func (p *Project) SetSchemaFile(root ast.Ref, path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read schema of %s: %w", root, err)
+	}
+	if err := p.SetSchema(root, raw); err != nil {
+		return err
+	}
+
+	if p.schemaFiles == nil {
+		p.schemaFiles = make(map[string]schemaFile)
+	}
+	p.schemaFiles[root.String()] = schemaFile{path: path, raw: raw}
+	return nil
+}
+
+// findSchema returns the schema of the ref by walking the properties from the registered document.
+//
+//	input.config.name -> properties.config.properties.name of the schema for `input`
+func (p *Project) findSchema(ref ast.Ref) *jsonSchema {
+	for i := len(ref); i > 0; i-- {
+		schema, ok := p.schemas[ref[:i].String()]
+		if !ok {
+			continue
+		}
+
+		for _, t := range ref[i:] {
+			name, ok := t.Value.(ast.String)
+			if !ok || schema.Properties == nil {
+				return nil
+			}
+			schema = schema.Properties[string(name)]
+			if schema == nil {
+				return nil
+			}
+		}
+		return schema
+	}
+	return nil
+}
+
+// listSchemaKeyCompletionItems lists the properties of the schema in the object literal which is compared with the schema-typed ref.
+//
+//	input.config == {"na"}
+//	                    ^ "name"
+func (p *Project) listSchemaKeyCompletionItems(location *ast.Location) []CompletionItem {
+	if len(p.schemas) == 0 {
+		return nil
+	}
+	policy := p.cache.Get(location.File)
+	if policy == nil || policy.Module == nil {
+		return nil
+	}
+	rule := p.findRuleForTerm(location)
+	if rule == nil {
+		return nil
+	}
+
+	for _, b := range rule.Body {
+		if !in(location, b.Loc()) || (!b.IsEquality() && !b.IsAssignment() && !ast.Equal.Ref().Equal(b.Operator())) {
+			continue
+		}
+
+		operands := b.Operands()
+		for i, lit := range operands {
+			ref, ok := operands[1-i].Value.(ast.Ref)
+			if !ok || lit.Loc() == nil || !in(location, lit.Loc()) {
+				continue
+			}
+			schema := p.findSchema(ref)
+			if schema == nil {
+				continue
+			}
+			return schemaKeyCompletionItems(policy.RawText, location, lit, schema)
+		}
+	}
+	return nil
+}
+
+func schemaKeyCompletionItems(rawText string, location *ast.Location, lit *ast.Term, schema *jsonSchema) []CompletionItem {
+	existKeys := make(map[string]struct{})
+	switch v := lit.Value.(type) {
+	case ast.Object:
+		var nested *ast.Term
+		v.Foreach(func(key, value *ast.Term) {
+			if value.Loc() != nil && in(location, value.Loc()) {
+				nested = key
+			}
+			if k, ok := key.Value.(ast.String); ok && !in(location, key.Loc()) {
+				existKeys[string(k)] = struct{}{}
+			}
+		})
+		// {"config": {"na"}}
+		//                ^ lists the properties of config
+		if nested != nil {
+			k, ok := nested.Value.(ast.String)
+			if !ok || schema.Properties[string(k)] == nil {
+				return nil
+			}
+			return schemaKeyCompletionItems(rawText, location, v.Get(nested), schema.Properties[string(k)])
+		}
+	case ast.Set:
+		// the object literal which has only keys like `{"a"}` is parsed as a set.
+		v.Foreach(func(t *ast.Term) {
+			if k, ok := t.Value.(ast.String); ok && !in(location, t.Loc()) {
+				existKeys[string(k)] = struct{}{}
+			}
+		})
+	default:
+		return nil
+	}
+
+	start := location.Offset
+	for start > 0 && isRefChar(rawText[start-1]) && rawText[start-1] != '.' {
+		start--
+	}
+	prefix := rawText[start:location.Offset]
+	if start > 0 && rawText[start-1] == '"' {
+		start--
+	}
+	end := location.Offset
+	if end < len(rawText) && rawText[end] == '"' {
+		end++
+	}
+	startPosition := offsetToPosition(rawText, start)
+	endPosition := offsetToPosition(rawText, end)
+
+	result := make([]CompletionItem, 0)
+	for _, name := range schema.sortedPropertyNames() {
+		if _, ok := existKeys[name]; ok || len(name) < len(prefix) || name[:len(prefix)] != prefix {
+			continue
+		}
+
+		result = append(result, CompletionItem{
+			Label:  name,
+			Kind:   VariableItem,
+			Detail: schema.propertyDetail(name),
+			TextEdit: &TextEdit{
+				Row:  startPosition.Row,
+				Col:  startPosition.Col,
+				Text: fmt.Sprintf("%q", name),
+				End:  &endPosition,
+			},
+		})
+	}
+	return result
+}
+
+// listSchemaPropertyItems lists the properties of the schema for the ref which is being typed.
+//
+//	input.config.
+//	             ^ the properties of config in the schema for `input`
+func (p *Project) listSchemaPropertyItems(location *ast.Location, target *ast.Term) []CompletionItem {
+	if len(p.schemas) == 0 || target == nil {
+		return nil
+	}
+	ref, ok := target.Value.(ast.Ref)
+	if !ok || len(ref) < 2 {
+		return nil
+	}
+	schema := p.findSchema(ref[:len(ref)-1])
+	if schema == nil {
+		return nil
+	}
+
+	result := make([]CompletionItem, 0, len(schema.Properties))
+	for _, name := range schema.sortedPropertyNames() {
+		result = append(result, CompletionItem{
+			Label:    name,
+			Kind:     VariableItem,
+			Detail:   schema.propertyDetail(name),
+			TextEdit: createTextEdit(location, name),
+		})
+	}
+	return result
+}
@@ -0,0 +1,303 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type SemanticTokenType int
+
+const (
+	NamespaceToken SemanticTokenType = iota
+	PropertyToken
+	FunctionToken
+	VariableToken
+	StringToken
+	NumberToken
+)
+
+// SemanticTokenTypes are the names of SemanticTokenType in the order of the values, which are used as the legend of LSP.
+var SemanticTokenTypes = []string{"namespace", "property", "function", "variable", "string", "number"}
+
+type SemanticTokenModifier int
+
+const (
+	DeclarationModifier SemanticTokenModifier = 1 << iota
+	DefaultLibraryModifier
+)
+
+// SemanticTokenModifiers are the names of SemanticTokenModifier in the order of the bits.
+var SemanticTokenModifiers = []string{"declaration", "defaultLibrary"}
+
+// SemanticTokens is the tokens encoded in the format of LSP.
+// Each token is 5 integers: the delta line, the delta start character, the length, the token type and the token modifiers.
+type SemanticTokens struct {
+	Data []int
+}
+
+type semanticToken struct {
+	location  *ast.Location
+	tokenType SemanticTokenType
+	modifiers SemanticTokenModifier
+}
+
+// SemanticTokens returns the tokens of rule names, variables, built-in functions, imports, package paths, strings and numbers in the file.
+// When the file has parse errors, only the tokens before the first error are returned, because the module is the last one which was parsed successfully.
+func (p *Project) SemanticTokens(path string) (*SemanticTokens, error) {
+	policy := p.cache.Get(path)
+	if policy == nil {
+		return nil, fmt.Errorf("file not found: %s", path)
+	}
+	if policy.Module == nil {
+		return &SemanticTokens{Data: []int{}}, nil
+	}
+
+	end := len(policy.RawText)
+	for _, e := range policy.Errs {
+		if e.Code == ast.ParseErr && e.Location != nil && e.Location.Offset < end {
+			end = e.Location.Offset
+		}
+	}
+
+	c := &semanticTokenCollector{
+		rules:   make(map[ast.Var]bool),
+		imports: make(map[ast.Var]struct{}),
+		tokens:  make(map[int]semanticToken),
+	}
+	for _, m := range p.cache.FindPolicies(policy.Module.Package.Path) {
+		for _, r := range m.Rules {
+			c.rules[r.Head.Name] = c.rules[r.Head.Name] || len(r.Head.Args) > 0
+		}
+	}
+	for _, imp := range policy.Module.Imports {
+		c.imports[ast.Var(importToLabel(imp))] = struct{}{}
+	}
+	c.collectModule(policy.Module)
+
+	tokens := make([]semanticToken, 0, len(c.tokens))
+	for _, t := range c.tokens {
+		l := t.location
+		// drop the tokens which don't match the current text, like the module before the parse error.
+		if l.Offset+len(l.Text) > end || policy.RawText[l.Offset:l.Offset+len(l.Text)] != string(l.Text) {
+			continue
+		}
+		// LSP doesn't allow multiline tokens by default.
+		if strings.Contains(string(l.Text), "\n") {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].location.Offset < tokens[j].location.Offset
+	})
+
+	return &SemanticTokens{Data: encodeSemanticTokens(policy.RawText, tokens)}, nil
+}
+
+// encodeSemanticTokens encodes the tokens, whose start characters and lengths are counted in UTF-16 code units as LSP requires.
+func encodeSemanticTokens(rawText string, tokens []semanticToken) []int {
+	result := make([]int, 0, len(tokens)*5)
+	prevLine, prevChar, prevEnd := 0, 0, 0
+	for _, t := range tokens {
+		l := t.location
+		// overlapping tokens are not allowed.
+		if l.Offset < prevEnd {
+			continue
+		}
+
+		line := l.Row - 1
+		char := utf16Count(rawText[strings.LastIndex(rawText[:l.Offset], "\n")+1 : l.Offset])
+		deltaChar := char
+		if line == prevLine {
+			deltaChar = char - prevChar
+		}
+		result = append(result, line-prevLine, deltaChar, utf16Count(string(l.Text)), int(t.tokenType), int(t.modifiers))
+		prevLine, prevChar, prevEnd = line, char, l.Offset+len(l.Text)
+	}
+	return result
+}
+
+// utf16Count returns the length of the text in UTF-16 code units.
+func utf16Count(text string) int {
+	n := 0
+	for _, r := range text {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+type semanticTokenCollector struct {
+	// rules are the rule names in the package, whose value is true when the rule is the function.
+	rules   map[ast.Var]bool
+	imports map[ast.Var]struct{}
+	tokens  map[int]semanticToken
+}
+
+func (c *semanticTokenCollector) add(location *ast.Location, tokenType SemanticTokenType, modifiers SemanticTokenModifier) {
+	if location == nil {
+		return
+	}
+	if _, ok := c.tokens[location.Offset]; ok {
+		return
+	}
+	c.tokens[location.Offset] = semanticToken{location: location, tokenType: tokenType, modifiers: modifiers}
+}
+
+func (c *semanticTokenCollector) collectModule(module *ast.Module) {
+	for _, t := range module.Package.Path[1:] {
+		c.add(t.Location, NamespaceToken, DeclarationModifier)
+	}
+
+	for _, imp := range module.Imports {
+		if ref, ok := imp.Path.Value.(ast.Ref); ok {
+			for _, t := range ref {
+				c.add(t.Location, NamespaceToken, 0)
+			}
+		}
+	}
+
+	for _, rule := range module.Rules {
+		for r := rule; r != nil; r = r.Else {
+			c.collectRule(r)
+		}
+	}
+}
+
+func (c *semanticTokenCollector) collectRule(rule *ast.Rule) {
+	if len(rule.Head.Reference) > 0 {
+		name := rule.Head.Reference[0]
+		if v, ok := name.Value.(ast.Var); ok && name.Location != nil && string(name.Location.Text) == string(v) {
+			c.add(name.Location, c.ruleTokenType(v), DeclarationModifier)
+		}
+	}
+
+	for _, arg := range rule.Head.Args {
+		c.walk(arg)
+	}
+	if rule.Head.Key != nil {
+		c.walk(rule.Head.Key)
+	}
+	if rule.Head.Value != nil {
+		c.walk(rule.Head.Value)
+	}
+	c.walk(rule.Body)
+}
+
+func (c *semanticTokenCollector) ruleTokenType(v ast.Var) SemanticTokenType {
+	if c.rules[v] {
+		return FunctionToken
+	}
+	return PropertyToken
+}
+
+func (c *semanticTokenCollector) walk(x interface{}) {
+	var vis *ast.GenericVisitor
+	vis = ast.NewGenericVisitor(func(x interface{}) bool {
+		switch v := x.(type) {
+		case *ast.Expr:
+			if !v.IsCall() {
+				return false
+			}
+			terms := v.Terms.([]*ast.Term)
+			c.addOperator(terms[0])
+			for _, t := range terms[1:] {
+				vis.Walk(t)
+			}
+			for _, w := range v.With {
+				vis.Walk(w)
+			}
+			return true
+		case *ast.Term:
+			switch t := v.Value.(type) {
+			case ast.Call:
+				c.addOperator(t[0])
+				for _, arg := range t[1:] {
+					vis.Walk(arg)
+				}
+				return true
+			case ast.Ref:
+				c.addRef(t)
+				return true
+			case ast.Var:
+				c.addVar(v)
+			case ast.String:
+				c.add(v.Location, StringToken, 0)
+			case ast.Number:
+				c.add(v.Location, NumberToken, 0)
+			}
+		}
+		return false
+	})
+	vis.Walk(x)
+}
+
+// addOperator adds the function of the call. The infix operators like `==` are skipped.
+func (c *semanticTokenCollector) addOperator(op *ast.Term) {
+	ref, ok := op.Value.(ast.Ref)
+	if !ok {
+		c.walk(op)
+		return
+	}
+
+	if b, ok := ast.BuiltinMap[ref.String()]; ok {
+		if op.Location != nil && string(op.Location.Text) == b.Name {
+			c.add(op.Location, FunctionToken, DefaultLibraryModifier)
+		}
+		return
+	}
+	c.addRef(ref)
+}
+
+func (c *semanticTokenCollector) addRef(ref ast.Ref) {
+	if _, ok := ref[0].Value.(ast.Var); ok {
+		c.addVar(ref[0])
+	} else {
+		c.walk(ref[0])
+	}
+
+	for _, t := range ref[1:] {
+		if _, ok := t.Value.(ast.String); !ok {
+			c.walk(t)
+			continue
+		}
+		if t.Location == nil {
+			continue
+		}
+		// input.user["name"]
+		//       ^ property ^ string
+		if strings.HasPrefix(string(t.Location.Text), `"`) || strings.HasPrefix(string(t.Location.Text), "`") {
+			c.add(t.Location, StringToken, 0)
+		} else {
+			c.add(t.Location, PropertyToken, 0)
+		}
+	}
+}
+
+func (c *semanticTokenCollector) addVar(term *ast.Term) {
+	v := term.Value.(ast.Var)
+	// skip the generated variables like `$0` for `_`.
+	if term.Location == nil || string(term.Location.Text) != string(v) {
+		return
+	}
+
+	if _, ok := c.imports[v]; ok {
+		c.add(term.Location, NamespaceToken, 0)
+		return
+	}
+	if _, ok := c.rules[v]; ok {
+		c.add(term.Location, c.ruleTokenType(v), 0)
+		return
+	}
+	if ast.RootDocumentNames.Contains(ast.NewTerm(v)) {
+		c.add(term.Location, VariableToken, DefaultLibraryModifier)
+		return
+	}
+	c.add(term.Location, VariableToken, 0)
+}
@@ -0,0 +1,125 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_SemanticTokens(t *testing.T) {
+	const (
+		namespace = int(source.NamespaceToken)
+		property  = int(source.PropertyToken)
+		function  = int(source.FunctionToken)
+		variable  = int(source.VariableToken)
+		str       = int(source.StringToken)
+		number    = int(source.NumberToken)
+
+		declaration    = int(source.DeclarationModifier)
+		defaultLibrary = int(source.DefaultLibraryModifier)
+	)
+
+	rawText := `package lib.util
+
+import data.users
+
+is_admin(user) {
+	count(users[user]) > 1
+	user.name == "admin"
+}
+
+allow {
+	is_admin(input.user)
+}`
+
+	tests := map[string]struct {
+		updateText string
+		expectData []int
+	}{
+		"Should encode the tokens by delta": {
+			expectData: []int{
+				0, 8, 3, namespace, declaration, // lib
+				0, 4, 4, namespace, declaration, // util
+				2, 7, 4, namespace, 0, // data
+				0, 5, 5, namespace, 0, // users
+				2, 0, 8, function, declaration, // is_admin
+				0, 9, 4, variable, 0, // user
+				1, 1, 5, function, defaultLibrary, // count
+				0, 6, 5, namespace, 0, // users
+				0, 6, 4, variable, 0, // user
+				0, 9, 1, number, 0, // 1
+				1, 1, 4, variable, 0, // user
+				0, 5, 4, property, 0, // name
+				0, 8, 7, str, 0, // "admin"
+				3, 0, 5, property, declaration, // allow
+				1, 1, 8, function, 0, // is_admin
+				0, 9, 5, variable, defaultLibrary, // input
+				0, 6, 4, property, 0, // user
+			},
+		},
+		"Should return the tokens before the parse error": {
+			updateText: `package lib.util
+
+import data.users
+
+is_admin(user) {
+	count(users[user]) > 1
+	user.name == 
+}`,
+			expectData: []int{
+				0, 8, 3, namespace, declaration, // lib
+				0, 4, 4, namespace, declaration, // util
+				2, 7, 4, namespace, 0, // data
+				0, 5, 5, namespace, 0, // users
+				2, 0, 8, function, declaration, // is_admin
+				0, 9, 4, variable, 0, // user
+				1, 1, 5, function, defaultLibrary, // count
+				0, 6, 5, namespace, 0, // users
+				0, 6, 4, variable, 0, // user
+				0, 9, 1, number, 0, // 1
+				1, 1, 4, variable, 0, // user
+				0, 5, 4, property, 0, // name
+			},
+		},
+		"Should count the characters in UTF-16 code units": {
+			updateText: `package lib.util
+
+allow {
+	msg := "👍"; m := msg
+}`,
+			expectData: []int{
+				0, 8, 3, namespace, declaration, // lib
+				0, 4, 4, namespace, declaration, // util
+				2, 0, 5, property, declaration, // allow
+				1, 1, 3, variable, 0, // msg
+				0, 7, 4, str, 0, // "👍"
+				0, 6, 1, variable, 0, // m
+				0, 5, 3, variable, 0, // msg
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(map[string]source.File{
+				"src.rego": {RawText: rawText},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.updateText != "" {
+				project.UpdateFile("src.rego", tt.updateText, 1)
+			}
+
+			got, err := project.SemanticTokens("src.rego")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectData, got.Data); diff != "" {
+				t.Errorf("SemanticTokens result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
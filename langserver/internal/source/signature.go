@@ -0,0 +1,176 @@
+package source
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type SignatureHelp struct {
+	Signatures      []SignatureInformation
+	ActiveSignature int
+	ActiveParameter int
+}
+
+type SignatureInformation struct {
+	Label         string
+	Documentation string
+	Parameters    []string
+}
+
+// SignatureHelp returns the signatures of the function whose arguments are being typed at the location.
+//
+//	is_hello("a", b
+//	               ^ location, ActiveParameter is 1
+func (p *Project) SignatureHelp(location *ast.Location) (*SignatureHelp, error) {
+	policy := p.cache.Get(location.File)
+	if policy == nil {
+		return nil, nil
+	}
+
+	offset := location.Offset
+	if offset > len(policy.RawText) {
+		offset = len(policy.RawText)
+	}
+
+	call, ok := findEnclosingCall(policy.RawText[:offset])
+	if !ok {
+		return nil, nil
+	}
+
+	var signatures []SignatureInformation
+	if b, ok := ast.BuiltinMap[call.name]; ok {
+		signatures = []SignatureInformation{builtinSignature(b)}
+	} else {
+		signatures = p.ruleSignatures(location, call.name)
+	}
+	if len(signatures) == 0 {
+		return nil, nil
+	}
+
+	return &SignatureHelp{
+		Signatures:      signatures,
+		ActiveParameter: call.commas,
+	}, nil
+}
+
+type enclosingCall struct {
+	name   string
+	commas int
+}
+
+// findEnclosingCall returns the function name of the innermost unclosed call and the number of commas in it.
+func findEnclosingCall(text string) (enclosingCall, bool) {
+	type bracket struct {
+		char   byte
+		offset int
+		commas int
+	}
+
+	stack := make([]bracket, 0)
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"':
+			// skip string
+			for i++; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' {
+					i++
+				}
+			}
+		case '`':
+			// skip raw string
+			for i++; i < len(text) && text[i] != '`'; i++ {
+			}
+		case '#':
+			// skip comment
+			for i++; i < len(text) && text[i] != '\n'; i++ {
+			}
+		case '(', '[', '{':
+			stack = append(stack, bracket{char: c, offset: i})
+		case ')', ']', '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case ',':
+			if len(stack) > 0 {
+				stack[len(stack)-1].commas++
+			}
+		}
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].char != '(' {
+			continue
+		}
+
+		start := stack[i].offset
+		for start > 0 && isRefChar(text[start-1]) {
+			start--
+		}
+		name := text[start:stack[i].offset]
+		if name == "" {
+			return enclosingCall{}, false
+		}
+		return enclosingCall{name: name, commas: stack[i].commas}, true
+	}
+	return enclosingCall{}, false
+}
+
+func isRefChar(c byte) bool {
+	return c == '_' || c == '.' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func builtinSignature(b *ast.Builtin) SignatureInformation {
+	args := b.Decl.FuncArgs().Args
+	params := make([]string, len(args))
+	for i, a := range args {
+		params[i] = a.String()
+	}
+
+	return SignatureInformation{
+		Label:         fmt.Sprintf("%s%s", b.Name, b.Decl.FuncArgs().String()),
+		Documentation: BuiltinDetail,
+		Parameters:    params,
+	}
+}
+
+func (p *Project) ruleSignatures(location *ast.Location, name string) []SignatureInformation {
+	// name is Var like `method` or Ref like `lib.method`
+	term, err := ast.ParseTerm(name)
+	if err != nil {
+		return nil
+	}
+	term.Location = &ast.Location{
+		Row:    location.Row,
+		Col:    location.Col,
+		Offset: location.Offset,
+		Text:   []byte(name),
+		File:   location.File,
+	}
+
+	result := make([]SignatureInformation, 0)
+	exists := make(map[string]struct{})
+	for _, r := range p.findRulesInModule(term) {
+		if len(r.Head.Args) == 0 {
+			continue
+		}
+
+		params := make([]string, len(r.Head.Args))
+		for i, a := range r.Head.Args {
+			params[i] = a.String()
+		}
+		label := fmt.Sprintf("%s(%s)", name, strings.Join(params, ", "))
+		if _, ok := exists[label]; ok {
+			continue
+		}
+		exists[label] = struct{}{}
+
+		result = append(result, SignatureInformation{
+			Label:         label,
+			Documentation: createDocForRule(r),
+			Parameters:    params,
+		})
+	}
+	return result
+}
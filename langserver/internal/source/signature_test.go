@@ -0,0 +1,132 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_SignatureHelp(t *testing.T) {
+	tests := map[string]struct {
+		files          map[string]source.File
+		createLocation createLocationFunc
+		expectResult   *source.SignatureHelp
+	}{
+		"Should return signature of the function in the same package": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	is_hello(msg, "hello")
+}
+
+is_hello(msg, name) {
+	msg == name
+}`,
+				},
+			},
+			createLocation: createLocation(4, 16, "src.rego"),
+			expectResult: &source.SignatureHelp{
+				Signatures: []source.SignatureInformation{
+					{
+						Label: "is_hello(msg, name)",
+						Documentation: `is_hello(msg, name) {
+	msg == name
+}`,
+						Parameters: []string{"msg", "name"},
+					},
+				},
+				ActiveParameter: 1,
+			},
+		},
+		"Should return signature of the imported function": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+violation[msg] {
+	lib.is_hello(msg)
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 15, "src.rego"),
+			expectResult: &source.SignatureHelp{
+				Signatures: []source.SignatureInformation{
+					{
+						Label: "lib.is_hello(msg)",
+						Documentation: `is_hello(msg) {
+	msg == "hello"
+}`,
+						Parameters: []string{"msg"},
+					},
+				},
+				ActiveParameter: 0,
+			},
+		},
+		"Should return signature of the builtin function and ignore nested commas": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	msg := sprintf("%s, %s", [input.a, 
+}`,
+				},
+			},
+			createLocation: createLocation(4, 37, "src.rego"),
+			expectResult: &source.SignatureHelp{
+				Signatures: []source.SignatureInformation{
+					{
+						Label:         "sprintf(string, array[any])",
+						Documentation: source.BuiltinDetail,
+						Parameters:    []string{"string", "array[any]"},
+					},
+				},
+				ActiveParameter: 1,
+			},
+		},
+		"Should return nil outside of the call": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	msg := "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(4, 9, "src.rego"),
+			expectResult:   nil,
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.SignatureHelp(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("SignatureHelp result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
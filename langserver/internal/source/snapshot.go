@@ -0,0 +1,68 @@
+package source
+
+import (
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// ProjectSnapshot is the summary of the files which the project has.
+// It can be encoded to JSON to reproduce the state of the user.
+type ProjectSnapshot struct {
+	Files []FileSnapshot `json:"files"`
+}
+
+type FileSnapshot struct {
+	Path    string `json:"path"`
+	Version int    `json:"version"`
+	// Package is empty when the file cannot be parsed.
+	Package string `json:"package"`
+	// Rules are the rule names in the order of the file. The name of the multiple rules appears once.
+	Rules  []string `json:"rules"`
+	Errors int      `json:"errors"`
+}
+
+// Snapshot returns the files sorted by the path with their packages, rule names and the number of the parse or compile errors.
+func (p *Project) Snapshot() ProjectSnapshot {
+	errs := p.cache.GetAllErrors()
+
+	files := make([]FileSnapshot, 0, len(errs))
+	for path, e := range errs {
+		policy := p.cache.Get(path)
+		if policy == nil {
+			// the compile errors without location
+			continue
+		}
+
+		file := FileSnapshot{
+			Path:    path,
+			Version: policy.Version,
+			Rules:   make([]string, 0),
+			Errors:  len(e),
+		}
+		if policy.Module != nil {
+			file.Package = policy.Module.Package.Path.String()
+			file.Rules = ruleNames(policy.Module)
+		}
+		files = append(files, file)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	return ProjectSnapshot{Files: files}
+}
+
+func ruleNames(module *ast.Module) []string {
+	result := make([]string, 0, len(module.Rules))
+	exists := make(map[string]struct{})
+	for _, r := range module.Rules {
+		name := r.Head.Name.String()
+		if _, ok := exists[name]; ok {
+			continue
+		}
+		exists[name] = struct{}{}
+		result = append(result, name)
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestProject_Snapshot(t *testing.T) {
+	project, err := source.NewProjectWithFiles(map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+import data.lib
+
+default allow := false
+
+allow {
+	lib.is_admin(input.user)
+}
+
+allow {
+	input.public
+}
+
+violation[msg] {
+	msg := undefined_func(1)
+}`,
+			Version: 3,
+		},
+		"lib.rego": {
+			RawText: `package lib
+
+is_admin(user) {
+	user == "admin"
+}`,
+		},
+		"broken.rego": {
+			RawText: `package broken
+
+allow {`,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := source.ProjectSnapshot{
+		Files: []source.FileSnapshot{
+			{Path: "broken.rego", Rules: []string{}, Errors: 1},
+			{Path: "lib.rego", Package: "data.lib", Rules: []string{"is_admin"}},
+			{Path: "src.rego", Version: 3, Package: "data.src", Rules: []string{"allow", "violation"}, Errors: 1},
+		},
+	}
+	if diff := cmp.Diff(expect, project.Snapshot()); diff != "" {
+		t.Errorf("Snapshot result diff (-expect, +got)\n%s", diff)
+	}
+
+	project.DeleteFile("broken.rego")
+	if err := project.UpdateFile("lib.rego", "package lib\n\nis_admin(user) {\n\tuser == \"admin\"\n}\n\nis_guest := false", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	expect = source.ProjectSnapshot{
+		Files: []source.FileSnapshot{
+			{Path: "lib.rego", Version: 1, Package: "data.lib", Rules: []string{"is_admin", "is_guest"}},
+			{Path: "src.rego", Version: 3, Package: "data.src", Rules: []string{"allow", "violation"}, Errors: 1},
+		},
+	}
+	if diff := cmp.Diff(expect, project.Snapshot()); diff != "" {
+		t.Errorf("Snapshot result after update diff (-expect, +got)\n%s", diff)
+	}
+}
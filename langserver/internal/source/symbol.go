@@ -0,0 +1,216 @@
+package source
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+type SymbolKind int
+
+const (
+	UnknownSymbol SymbolKind = iota
+	VariableSymbol
+	RuleSymbol
+	FunctionSymbol
+	PackageSymbol
+	BuiltinSymbol
+	ConstantSymbol
+)
+
+// SymbolInfo bundles information about the symbol for clients which request them at once.
+type SymbolInfo struct {
+	Kind           SymbolKind
+	Definitions    []*ast.Location
+	ReferenceCount int
+	Documents      []Document
+}
+
+// SymbolInfo returns the definitions, the number of references and the documents of the term at the location.
+func (p *Project) SymbolInfo(location *ast.Location) (*SymbolInfo, error) {
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, nil
+	}
+
+	return &SymbolInfo{
+		Kind:           p.symbolKind(term),
+		Definitions:    p.findDefinition(term),
+		ReferenceCount: len(uniqueLocations(p.findReferences(term))),
+		Documents:      p.findTermDocument(term),
+	}, nil
+}
+
+func (p *Project) symbolKind(term *ast.Term) SymbolKind {
+	if isImportTerm(term) {
+		return PackageSymbol
+	}
+
+	rule := p.findRuleForTerm(term.Loc())
+	if rule != nil && p.findDefinitionInRule(term, rule) != nil {
+		return VariableSymbol
+	}
+
+	if len(p.findDefinitionInImports(term)) != 0 {
+		return PackageSymbol
+	}
+
+	rules := p.findRulesInModule(term)
+	if len(rules) != 0 {
+		for _, r := range rules {
+			if len(r.Head.Args) != 0 {
+				return FunctionSymbol
+			}
+		}
+		return RuleSymbol
+	}
+
+	if _, ok := ast.BuiltinMap[term.String()]; ok {
+		return BuiltinSymbol
+	}
+
+	if _, ok := term.Value.(ast.Var); ok {
+		return VariableSymbol
+	}
+	return UnknownSymbol
+}
+
+type SymbolInformation struct {
+	Name     string
+	Kind     SymbolKind
+	Location *ast.Location
+
+	// ContainerName is the package path which the symbol is defined in.
+	ContainerName string
+}
+
+// WorkspaceSymbols returns the rules whose names fuzzy-match the query in all loaded modules.
+// The rules which start with the query come first, and then the rules which contain the query.
+func (p *Project) WorkspaceSymbols(query string) ([]SymbolInformation, error) {
+	type rankedSymbol struct {
+		symbol SymbolInformation
+		rank   int
+	}
+
+	ranked := make([]rankedSymbol, 0)
+	for _, symbol := range p.ruleSymbols() {
+		rank, ok := matchSymbol(symbol.Name, query)
+		if !ok {
+			continue
+		}
+		ranked = append(ranked, rankedSymbol{symbol: symbol, rank: rank})
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].rank < ranked[j].rank
+	})
+
+	result := make([]SymbolInformation, len(ranked))
+	for i, r := range ranked {
+		result[i] = r.symbol
+	}
+	return result, nil
+}
+
+// SymbolsOfKind returns the rules of the kind in all loaded modules, sorted by name.
+func (p *Project) SymbolsOfKind(kind SymbolKind) []SymbolInformation {
+	result := make([]SymbolInformation, 0)
+	for _, symbol := range p.ruleSymbols() {
+		if symbol.Kind == kind {
+			result = append(result, symbol)
+		}
+	}
+	return result
+}
+
+// ruleSymbols returns the symbols of all rules in the loaded modules, sorted by name, file and row.
+func (p *Project) ruleSymbols() []SymbolInformation {
+	result := make([]SymbolInformation, 0)
+	for _, pkg := range p.cache.GetPackages() {
+		for _, m := range p.cache.FindPolicies(pkg) {
+			for _, r := range m.Rules {
+				if p.isHiddenRule(r) {
+					continue
+				}
+				name := r.Head.Name.String()
+				result = append(result, SymbolInformation{
+					Name: name,
+					Kind: ruleSymbolKind(r),
+					Location: &ast.Location{
+						Row:    r.Location.Row,
+						Col:    r.Location.Col,
+						Offset: r.Location.Offset,
+						Text:   []byte(name),
+						File:   r.Location.File,
+					},
+					ContainerName: m.Package.Path.String(),
+				})
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Location.File != b.Location.File {
+			return a.Location.File < b.Location.File
+		}
+		return a.Location.Row < b.Location.Row
+	})
+	return result
+}
+
+// ruleSymbolKind returns FunctionSymbol for the function, ConstantSymbol for the rule which has a constant value without body and RuleSymbol for the others.
+//
+//	f(x) := x
+//	max_size := 10
+//	allow { ... }
+func ruleSymbolKind(rule *ast.Rule) SymbolKind {
+	switch {
+	case len(rule.Head.Args) != 0:
+		return FunctionSymbol
+	case rule.Head.Key == nil && rule.Head.Value != nil && ast.IsConstant(rule.Head.Value.Value) && isGeneratedBody(rule):
+		return ConstantSymbol
+	default:
+		return RuleSymbol
+	}
+}
+
+// isGeneratedBody returns true when the body is generated by the parser for the rule without body.
+// The generated body is located at the rule or the head value, while the written body follows the head.
+func isGeneratedBody(rule *ast.Rule) bool {
+	if len(rule.Body) != 1 || rule.Body[0].Location == nil || rule.Head.Value == nil || rule.Head.Value.Location == nil {
+		return false
+	}
+	return rule.Body[0].Location.Offset <= rule.Head.Value.Location.Offset
+}
+
+// matchSymbol returns the rank of the name for the query. The lower rank is the better match.
+//
+//	0: the name starts with the query
+//	1: the name contains the query
+//	2: the name contains the characters of the query in order
+func matchSymbol(name, query string) (int, bool) {
+	name, query = strings.ToLower(name), strings.ToLower(query)
+	if strings.HasPrefix(name, query) {
+		return 0, true
+	}
+	if strings.Contains(name, query) {
+		return 1, true
+	}
+
+	q := []rune(query)
+	i := 0
+	for _, c := range name {
+		if i < len(q) && q[i] == c {
+			i++
+		}
+	}
+	return 2, i == len(q)
+}
@@ -0,0 +1,315 @@
+package source_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_SymbolInfo(t *testing.T) {
+	tests := map[string]struct {
+		files          map[string]source.File
+		createLocation createLocationFunc
+		expectInfo     *source.SymbolInfo
+	}{
+		"Should bundle information for the rule reference": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+import data.lib
+
+violation[msg] {
+	lib.is_hello(msg)
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+is_hello(msg) {
+	msg == "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(6, 7, "src.rego"),
+			expectInfo: &source.SymbolInfo{
+				Kind: source.FunctionSymbol,
+				Definitions: []*ast.Location{
+					{
+						Row:    3,
+						Col:    1,
+						Offset: len("package lib\n\n"),
+						Text:   []byte("is_hello"),
+						File:   "lib.rego",
+					},
+				},
+				ReferenceCount: 2,
+				Documents: []source.Document{
+					{
+						Content: `is_hello(msg) {
+	msg == "hello"
+}`,
+						Language: "rego",
+					},
+				},
+			},
+		},
+		"Should bundle information for the local variable": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+violation[msg] {
+	m := "hello"
+	msg := m
+}`,
+				},
+			},
+			createLocation: createLocation(5, 9, "src.rego"),
+			expectInfo: &source.SymbolInfo{
+				Kind: source.VariableSymbol,
+				Definitions: []*ast.Location{
+					{
+						Row:    4,
+						Col:    2,
+						Offset: len("package src\n\nviolation[msg] {\n\t"),
+						Text:   []byte("m"),
+						File:   "src.rego",
+					},
+				},
+				ReferenceCount: 2,
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			location := tt.createLocation(tt.files)
+			got, err := project.SymbolInfo(location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectInfo, got); diff != "" {
+				t.Errorf("SymbolInfo result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_WorkspaceSymbols(t *testing.T) {
+	tests := map[string]struct {
+		files         map[string]source.File
+		query         string
+		expectSymbols []source.SymbolInformation
+	}{
+		"Should list prefix matches before substring and fuzzy matches": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+deny_all {
+	true
+}
+
+is_deny(msg) {
+	msg == "deny"
+}`,
+				},
+				"lib.rego": {
+					RawText: `package lib
+
+default_entry := true
+
+allow {
+	true
+}`,
+				},
+			},
+			query: "den",
+			expectSymbols: []source.SymbolInformation{
+				{
+					Name: "deny_all",
+					Kind: source.RuleSymbol,
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package src\n\n"),
+						Text:   []byte("deny_all"),
+						File:   "src.rego",
+					},
+					ContainerName: "data.src",
+				},
+				{
+					Name: "is_deny",
+					Kind: source.FunctionSymbol,
+					Location: &ast.Location{
+						Row:    7,
+						Col:    1,
+						Offset: len("package src\n\ndeny_all {\n\ttrue\n}\n\n"),
+						Text:   []byte("is_deny"),
+						File:   "src.rego",
+					},
+					ContainerName: "data.src",
+				},
+				{
+					Name: "default_entry",
+					Kind: source.ConstantSymbol,
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package lib\n\n"),
+						Text:   []byte("default_entry"),
+						File:   "lib.rego",
+					},
+					ContainerName: "data.lib",
+				},
+			},
+		},
+		"Should list nothing when no rule matches": {
+			files: map[string]source.File{
+				"src.rego": {
+					RawText: `package src
+
+allow {
+	true
+}`,
+				},
+			},
+			query:         "deny",
+			expectSymbols: []source.SymbolInformation{},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(tt.files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.WorkspaceSymbols(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.expectSymbols, got); diff != "" {
+				t.Errorf("WorkspaceSymbols result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_SymbolsOfKind(t *testing.T) {
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+max_size := 10
+
+is_admin(user) {
+	user.role == "admin"
+}
+
+allow {
+	is_admin(input.user)
+}`,
+		},
+		"lib.rego": {
+			RawText: `package lib
+
+default enabled := false
+
+name(user) := user.name
+
+sizes := [1, 2] {
+	true
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		kind          source.SymbolKind
+		expectSymbols []source.SymbolInformation
+	}{
+		"Should list functions": {
+			kind: source.FunctionSymbol,
+			expectSymbols: []source.SymbolInformation{
+				{
+					Name: "is_admin",
+					Kind: source.FunctionSymbol,
+					Location: &ast.Location{
+						Row:    5,
+						Col:    1,
+						Offset: len("package src\n\nmax_size := 10\n\n"),
+						Text:   []byte("is_admin"),
+						File:   "src.rego",
+					},
+					ContainerName: "data.src",
+				},
+				{
+					Name: "name",
+					Kind: source.FunctionSymbol,
+					Location: &ast.Location{
+						Row:    5,
+						Col:    1,
+						Offset: len("package lib\n\ndefault enabled := false\n\n"),
+						Text:   []byte("name"),
+						File:   "lib.rego",
+					},
+					ContainerName: "data.lib",
+				},
+			},
+		},
+		"Should list constants": {
+			kind: source.ConstantSymbol,
+			expectSymbols: []source.SymbolInformation{
+				{
+					Name: "enabled",
+					Kind: source.ConstantSymbol,
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package lib\n\n"),
+						Text:   []byte("enabled"),
+						File:   "lib.rego",
+					},
+					ContainerName: "data.lib",
+				},
+				{
+					Name: "max_size",
+					Kind: source.ConstantSymbol,
+					Location: &ast.Location{
+						Row:    3,
+						Col:    1,
+						Offset: len("package src\n\n"),
+						Text:   []byte("max_size"),
+						File:   "src.rego",
+					},
+					ContainerName: "data.src",
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := project.SymbolsOfKind(tt.kind)
+			if diff := cmp.Diff(tt.expectSymbols, got); diff != "" {
+				t.Errorf("SymbolsOfKind result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
@@ -84,10 +84,37 @@ func (p *Project) SearchTargetTerm(location *ast.Location) (term *ast.Term, err
 }
 
 func (p *Project) searchTargetTermInImport(location *ast.Location, imp *ast.Import) (*ast.Term, error) {
-	if in(location, imp.Path.Loc()) {
+	if !in(location, imp.Path.Loc()) {
+		return nil, nil
+	}
+
+	// import data.lib.util
+	//             ^ returns data.lib when the package exists, else data.lib.util
+	ref, ok := imp.Path.Value.(ast.Ref)
+	if !ok {
 		return imp.Path, nil
 	}
-	return nil, nil
+	for i := 1; i < len(ref)-1; i++ {
+		if ref[i].Location == nil || !in(location, ref[i].Loc()) {
+			continue
+		}
+
+		prefix := ref[:i+1]
+		if len(p.cache.FindPolicies(prefix)) == 0 {
+			break
+		}
+		return &ast.Term{
+			Value: prefix,
+			Location: &ast.Location{
+				Row:    imp.Path.Location.Row,
+				Col:    imp.Path.Location.Col,
+				Offset: imp.Path.Location.Offset,
+				Text:   imp.Path.Location.Text[:ref[i].Location.Offset+len(ref[i].Location.Text)-imp.Path.Location.Offset],
+				File:   imp.Path.Location.File,
+			},
+		}, nil
+	}
+	return imp.Path, nil
 }
 
 func (p *Project) searchTargetTermInRule(location *ast.Location, rule *ast.Rule) (*ast.Term, error) {
@@ -111,21 +138,47 @@ func (p *Project) searchTargetTermInRule(location *ast.Location, rule *ast.Rule)
 				return p.searchTargetTermInTerm(location, rule.Head.Value)
 			}
 		}
-		for _, b := range rule.Body {
-			if !in(location, b.Loc()) {
-				continue
-			}
+		term, err := p.searchTargetTermInBody(location, rule.Body)
+		if err != nil || term != nil {
+			return term, err
+		}
+		rule = rule.Else
+	}
+	return nil, nil
+}
 
-			switch t := b.Terms.(type) {
-			case *ast.Term:
-				if in(location, t.Loc()) {
+func (p *Project) searchTargetTermInBody(location *ast.Location, body ast.Body) (*ast.Term, error) {
+	for _, b := range body {
+		if !in(location, b.Loc()) {
+			continue
+		}
+
+		// violation with data.lib.allow as y
+		//                    ^ target   ^ value
+		for _, w := range b.With {
+			for _, t := range []*ast.Term{w.Target, w.Value} {
+				if t != nil && t.Loc() != nil && in(location, t.Loc()) {
 					return p.searchTargetTermInTerm(location, t)
 				}
-			case []*ast.Term:
-				return p.searchTargetTermInTerms(location, t)
 			}
 		}
-		rule = rule.Else
+
+		switch t := b.Terms.(type) {
+		case *ast.Term:
+			if in(location, t.Loc()) {
+				return p.searchTargetTermInTerm(location, t)
+			}
+		case []*ast.Term:
+			return p.searchTargetTermInTerms(location, t)
+		case *ast.Every:
+			// every k, v in coll { use(k) }
+			for _, term := range []*ast.Term{t.Key, t.Value, t.Domain} {
+				if term != nil && term.Loc() != nil && in(location, term.Loc()) {
+					return p.searchTargetTermInTerm(location, term)
+				}
+			}
+			return p.searchTargetTermInBody(location, t.Body)
+		}
 	}
 	return nil, nil
 }
@@ -144,7 +197,17 @@ func (p *Project) searchTargetTermInTerm(loc *ast.Location, term *ast.Term) (*as
 	case ast.Call:
 		return p.searchTargetTermInTerms(loc, []*ast.Term(v))
 	case ast.Ref:
+		// "admin" in input.roles
+		//         ^ internal.member_2
+		if infixBuiltin(term) != nil {
+			return term, nil
+		}
 		if len(v) > 0 && in(loc, v[0].Loc()) {
+			// {a, b}[_]
+			//  ^ the head can be a composite value
+			if _, ok := v[0].Value.(ast.Var); !ok {
+				return p.searchTargetTermInTerm(loc, v[0])
+			}
 			return v[0], nil
 		}
 		// If lastItem is ast.Var, should return Value
@@ -155,6 +218,11 @@ func (p *Project) searchTargetTermInTerm(loc *ast.Location, term *ast.Term) (*as
 
 		for i, t := range v {
 			if in(loc, t.Loc()) {
+				// arr[f(x)]
+				//       ^ search the arguments of the call
+				if _, ok := t.Value.(ast.Call); ok {
+					return p.searchTargetTermInTerm(loc, t)
+				}
 				value := v[:i+1]
 				return &ast.Term{Value: value, Location: &ast.Location{
 					Text:   []byte(value.String()),
@@ -180,6 +248,24 @@ func (p *Project) searchTargetTermInTerm(loc *ast.Location, term *ast.Term) (*as
 			}
 		}
 		return nil, nil
+	case ast.Object:
+		for _, k := range v.Keys() {
+			for _, t := range []*ast.Term{k, v.Get(k)} {
+				if t.Loc() == nil || !in(loc, t.Loc()) {
+					continue
+				}
+				return p.searchTargetTermInTerm(loc, t)
+			}
+		}
+		return nil, nil
+	case ast.Set:
+		return p.searchTargetTermInTerms(loc, v.Slice())
+	case *ast.ArrayComprehension:
+		return p.searchTargetTermInComprehension(loc, []*ast.Term{v.Term}, v.Body)
+	case *ast.SetComprehension:
+		return p.searchTargetTermInComprehension(loc, []*ast.Term{v.Term}, v.Body)
+	case *ast.ObjectComprehension:
+		return p.searchTargetTermInComprehension(loc, []*ast.Term{v.Key, v.Value}, v.Body)
 	case ast.Var:
 		return term, nil
 	case ast.String, ast.Boolean, ast.Number:
@@ -189,6 +275,20 @@ func (p *Project) searchTargetTermInTerm(loc *ast.Location, term *ast.Term) (*as
 	}
 }
 
+// searchTargetTermInComprehension searches the term from the head and the body of the comprehension.
+//
+//	[x | x := input[_]]
+//	 ^ head   ^ body
+func (p *Project) searchTargetTermInComprehension(loc *ast.Location, head []*ast.Term, body ast.Body) (*ast.Term, error) {
+	term, err := p.searchTargetTermInTerms(loc, head)
+	if err != nil || term != nil {
+		return term, err
+	}
+	return p.searchTargetTermInBody(loc, body)
+}
+
+// in returns true when target is in the span of src.
+// Offset and Text are both in bytes, so the span is correct even if src has multibyte characters.
 func in(target, src *ast.Location) bool {
 	return target.Offset >= src.Offset && target.Offset <= (src.Offset+len(src.Text))
 }
@@ -41,6 +41,28 @@ violation[msg] {
 				Value: ast.Var("msg"),
 			},
 		},
+		"Should find term in the call of the ref index": {
+			files: map[string]source.File{
+				"main.rego": {
+					RawText: `package main
+
+allow {
+	arr[abs(i)] == 2
+}`,
+				},
+			},
+			createLocation: createLocation(4, 10, "main.rego"),
+			expectTerm: &ast.Term{
+				Location: &ast.Location{
+					Row:    4,
+					Col:    10,
+					Offset: len("package main\n\nallow {\n\tarr[abs("),
+					Text:   []byte("i"),
+					File:   "main.rego",
+				},
+				Value: ast.Var("i"),
+			},
+		},
 		"Should find term when the update has not correct ast": {
 			files: map[string]source.File{
 				"main.rego": {
@@ -209,7 +231,7 @@ authorize = input {
 				Value: ast.Var("input"),
 			},
 		},
-		"Should not find term `with` clause": {
+		"Should not find term `with` keyword": {
 			files: map[string]source.File{
 				"src_test.rego": {
 					RawText: `package main
@@ -226,6 +248,32 @@ violation[msg] {
 			createLocation: createLocation(4, 12, "src_test.rego"),
 			expectTerm:     nil,
 		},
+		"Should find term in the target of `with` clause": {
+			files: map[string]source.File{
+				"src_test.rego": {
+					RawText: `package main
+
+test_hoge {
+	violation with input as "{}"
+}
+
+violation[msg] {
+	msg := "hello"
+}`,
+				},
+			},
+			createLocation: createLocation(4, 17, "src_test.rego"),
+			expectTerm: &ast.Term{
+				Location: &ast.Location{
+					Row:    4,
+					Col:    17,
+					Offset: len("package main\n\ntest_hoge {\n\tviolation with "),
+					Text:   []byte("input"),
+					File:   "src_test.rego",
+				},
+				Value: ast.Var("input"),
+			},
+		},
 		"Should find term in the import sentense": {
 			files: map[string]source.File{
 				"src.rego": {
@@ -0,0 +1,314 @@
+package source
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// TypeDefinition returns the location in the schema file of the ref which is typed by the schema.
+// The schema is resolved from the `schemas` of the METADATA annotation of the rule first, and then from the schemas set by SetSchemaFile.
+// The variable which is assigned from the ref is typed by the same schema.
+// When no schema types the term, it returns the definition like LookupDefinition.
+//
+//	# METADATA
+//	# schemas:
+//	#   - input: schema.input
+//	allow {
+//		c := input.config
+//		c.name
+//		  ^ "name" in properties.config.properties of input.json
+//	}
+func (p *Project) TypeDefinition(location *ast.Location) ([]*ast.Location, error) {
+	term, err := p.SearchTargetTerm(location)
+	if err != nil {
+		return nil, err
+	}
+	if term == nil {
+		return nil, nil
+	}
+
+	ref := p.schemaTypedRef(location, term)
+	if loc := p.findAnnotatedSchemaLocation(location, ref); loc != nil {
+		return []*ast.Location{loc}, nil
+	}
+	if loc := p.findSchemaLocation(ref); loc != nil {
+		return []*ast.Location{loc}, nil
+	}
+	return p.LookupDefinition(location)
+}
+
+// schemaTypedRef returns the ref up to the location whose head is replaced with the ref which is assigned to it.
+//
+//	c := input.config
+//	c.name -> input.config.name
+func (p *Project) schemaTypedRef(location *ast.Location, term *ast.Term) ast.Ref {
+	var ref ast.Ref
+	switch v := term.Value.(type) {
+	case ast.Ref:
+		ref = v
+		for i, t := range v {
+			if t.Loc() != nil && in(location, t.Loc()) {
+				ref = v[:i+1]
+				break
+			}
+		}
+	case ast.Var:
+		ref = ast.Ref{term}
+	default:
+		return nil
+	}
+
+	rule := p.findRuleForTerm(location)
+	if rule == nil {
+		return ref
+	}
+	// x := c.config; c := input -> the assignments are followed only a few times not to loop forever.
+	for i := 0; i < 10; i++ {
+		if _, ok := ref[0].Value.(ast.Var); !ok || ref[0].Loc() == nil {
+			return ref
+		}
+		assigned := assignedRef(p.findDefinitionInRule(ref[0], rule), rule.Body)
+		if assigned == nil {
+			return ref
+		}
+		ref = append(assigned.Copy(), ref[1:]...)
+	}
+	return ref
+}
+
+// assignedRef returns the ref which is assigned to the variable of the definition.
+func assignedRef(definition *ast.Term, body ast.Body) ast.Ref {
+	if definition == nil {
+		return nil
+	}
+	for _, b := range body {
+		if !b.IsAssignment() && !b.IsEquality() {
+			continue
+		}
+		operands := b.Operands()
+		for i, o := range operands {
+			if o.Loc() == nil || o.Loc().Offset != definition.Loc().Offset || o.Loc().File != definition.Loc().File {
+				continue
+			}
+			if ref, ok := operands[1-i].Value.(ast.Ref); ok {
+				return ref
+			}
+		}
+	}
+	return nil
+}
+
+// findAnnotatedSchemaLocation returns the location of the property of the ref in the schema of the METADATA annotation.
+// The annotations of the rule take precedence over the annotations of the package.
+//
+//	# METADATA
+//	# schemas:
+//	#   - input.config: schema.config -> config.json
+//	#   - input: {"type": "object"}   -> the annotation itself
+func (p *Project) findAnnotatedSchemaLocation(location *ast.Location, ref ast.Ref) *ast.Location {
+	if len(ref) == 0 {
+		return nil
+	}
+	module := p.GetModule(location.File)
+	rule := p.findRuleForTerm(location)
+	if module == nil || rule == nil {
+		return nil
+	}
+
+	annotations := ruleAnnotations(module, rule)
+	for _, a := range module.Annotations {
+		if a.Scope == "package" {
+			annotations = append(annotations, a)
+		}
+	}
+
+	for _, a := range annotations {
+		// the longest path types the ref, like `input.config` over `input`.
+		var matched *ast.SchemaAnnotation
+		for _, s := range a.Schemas {
+			if ref.HasPrefix(s.Path) && (matched == nil || len(s.Path) > len(matched.Path)) {
+				matched = s
+			}
+		}
+		if matched == nil {
+			continue
+		}
+
+		if matched.Schema == nil {
+			// the inline definition has no file, so the annotation is the type definition.
+			loc := *a.Location
+			return &loc
+		}
+		file, ok := p.loadAnnotatedSchemaFile(location.File, matched.Schema)
+		if !ok {
+			return nil
+		}
+		return schemaPropertyLocation(file, ref[len(matched.Path):])
+	}
+	return nil
+}
+
+// loadAnnotatedSchemaFile reads the schema file of the ref in the annotation like `schema.lib.config` -> lib/config.json.
+// The file is searched in the directory of the policy, the root path and the directories of the schemas set by SetSchemaFile.
+func (p *Project) loadAnnotatedSchemaFile(policyPath string, schema ast.Ref) (schemaFile, bool) {
+	if !schema.HasPrefix(ast.SchemaRootRef) || len(schema) == len(ast.SchemaRootRef) {
+		return schemaFile{}, false
+	}
+	elems := make([]string, 0, len(schema)-1)
+	for _, t := range schema[1:] {
+		name, ok := t.Value.(ast.String)
+		if !ok {
+			return schemaFile{}, false
+		}
+		elems = append(elems, string(name))
+	}
+	name := filepath.Join(elems...) + ".json"
+
+	dirs := []string{filepath.Dir(policyPath)}
+	if p.rootPath != "" {
+		dirs = append(dirs, p.rootPath)
+	}
+	for _, f := range p.schemaFiles {
+		dirs = append(dirs, filepath.Dir(f.path))
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		return schemaFile{path: path, raw: raw}, true
+	}
+	return schemaFile{}, false
+}
+
+// findSchemaLocation returns the location of the property of the ref in the schema file.
+// When the property is not found, the location of the closest parent is returned.
+func (p *Project) findSchemaLocation(ref ast.Ref) *ast.Location {
+	for i := len(ref); i > 0; i-- {
+		file, ok := p.schemaFiles[ref[:i].String()]
+		if !ok {
+			continue
+		}
+		return schemaPropertyLocation(file, ref[i:])
+	}
+	return nil
+}
+
+// schemaPropertyLocation returns the location of the property which is reached by the path from the root of the schema file.
+// When the property is not found, the location of the closest parent is returned.
+func schemaPropertyLocation(file schemaFile, path ast.Ref) *ast.Location {
+	// input.users[_].name -> properties.users.items.properties.name
+	keys := make([]string, 0)
+	ends := make([]int, 0)
+	for _, t := range path {
+		if name, ok := t.Value.(ast.String); ok {
+			keys = append(keys, "properties", string(name))
+		} else {
+			keys = append(keys, "items")
+		}
+		ends = append(ends, len(keys))
+	}
+
+	for j := len(ends) - 1; j >= 0; j-- {
+		if start, end, ok := findJSONKey(file.raw, keys[:ends[j]]); ok {
+			return schemaLocation(file, start, end)
+		}
+	}
+	if len(file.raw) == 0 {
+		return nil
+	}
+	return schemaLocation(file, 0, 1)
+}
+
+func schemaLocation(file schemaFile, start, end int) *ast.Location {
+	position := offsetToPosition(string(file.raw), start)
+	return &ast.Location{
+		Row:    position.Row,
+		Col:    position.Col,
+		Offset: start,
+		Text:   file.raw[start:end],
+		File:   file.path,
+	}
+}
+
+// findJSONKey returns the offsets of the quoted key which is reached by following the keys from the root object.
+func findJSONKey(raw []byte, keys []string) (int, int, bool) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	return findJSONKeyInValue(raw, dec, keys)
+}
+
+func findJSONKeyInValue(raw []byte, dec *json.Decoder, keys []string) (int, int, bool) {
+	tok, err := dec.Token()
+	if err != nil {
+		return 0, 0, false
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '{' {
+		skipJSONValue(dec, tok)
+		return 0, 0, false
+	}
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		key, _ := tok.(string)
+		end := int(dec.InputOffset())
+		if key != keys[0] {
+			if !skipJSONValue(dec, nil) {
+				return 0, 0, false
+			}
+			continue
+		}
+
+		if len(keys) == 1 {
+			return jsonKeyStart(raw, end), end, true
+		}
+		return findJSONKeyInValue(raw, dec, keys[1:])
+	}
+	return 0, 0, false
+}
+
+// skipJSONValue skips the value which starts with tok. When tok is nil, the next token is read.
+func skipJSONValue(dec *json.Decoder, tok json.Token) bool {
+	if tok == nil {
+		var err error
+		tok, err = dec.Token()
+		if err != nil {
+			return false
+		}
+	}
+	d, ok := tok.(json.Delim)
+	if !ok || d == '}' || d == ']' {
+		return true
+	}
+
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return false
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return true
+}
+
+// jsonKeyStart returns the offset of the opening quote of the key which ends at end.
+func jsonKeyStart(raw []byte, end int) int {
+	for i := end - 2; i > 0; i-- {
+		if raw[i] == '"' && raw[i-1] != '\\' {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,232 @@
+package source_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestProject_TypeDefinition(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "config": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"}
+      }
+    },
+    "users": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "string"}
+        }
+      }
+    }
+  }
+}`
+	files := map[string]source.File{
+		"src.rego": {
+			RawText: `package src
+
+allow {
+	input.config.name == "admin"
+	c := input.config
+	c.name == "admin"
+	input.users[_].id == "a"
+	input.config.unknown
+	x := 1
+	x == 1
+}`,
+		},
+	}
+
+	schemaLocation := func(key string, row, col int) *ast.Location {
+		return &ast.Location{
+			Row:    row,
+			Col:    col,
+			Offset: strings.Index(schema, key),
+			Text:   []byte(key),
+			File:   "input.json",
+		}
+	}
+
+	tests := map[string]struct {
+		createLocation createLocationFunc
+		expectResult   []*ast.Location
+	}{
+		"Should return the property of the schema": {
+			createLocation: createLocation(4, 18, "src.rego"),
+			expectResult:   []*ast.Location{schemaLocation(`"name"`, 7, 9)},
+		},
+		"Should return the property of the middle of the ref": {
+			createLocation: createLocation(4, 13, "src.rego"),
+			expectResult:   []*ast.Location{schemaLocation(`"config"`, 4, 5)},
+		},
+		"Should return the property of the variable which is assigned from the ref": {
+			createLocation: createLocation(6, 7, "src.rego"),
+			expectResult:   []*ast.Location{schemaLocation(`"name"`, 7, 9)},
+		},
+		"Should return the property of the items of the array": {
+			createLocation: createLocation(7, 18, "src.rego"),
+			expectResult:   []*ast.Location{schemaLocation(`"id"`, 15, 11)},
+		},
+		"Should return the closest parent when the property is not in the schema": {
+			createLocation: createLocation(8, 21, "src.rego"),
+			expectResult:   []*ast.Location{schemaLocation(`"config"`, 4, 5)},
+		},
+		"Should return the definition when the term is not typed by the schema": {
+			createLocation: createLocation(10, 2, "src.rego"),
+			expectResult: []*ast.Location{
+				{
+					Row:    9,
+					Col:    2,
+					Offset: len("package src\n\nallow {\n\tinput.config.name == \"admin\"\n\tc := input.config\n\tc.name == \"admin\"\n\tinput.users[_].id == \"a\"\n\tinput.config.unknown\n\t"),
+					Text:   []byte("x"),
+					File:   "src.rego",
+				},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	schemaPath := filepath.Join(dir, "input.json")
+	if err := os.WriteFile(schemaPath, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := project.SetSchemaFile(ast.InputRootRef, schemaPath); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, e := range tt.expectResult {
+				if e.File == "input.json" {
+					e.File = schemaPath
+				}
+			}
+
+			got, err := project.TypeDefinition(tt.createLocation(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("TypeDefinition result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProject_TypeDefinitionWithAnnotation(t *testing.T) {
+	schema := `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string"}
+  }
+}`
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "input.json"), []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	policyPath := filepath.Join(dir, "src.rego")
+	files := map[string]source.File{
+		policyPath: {
+			RawText: `package src
+
+# METADATA
+# schemas:
+#   - input.config: schema.config
+allow {
+	input.config.name == "admin"
+	c := input.config
+	c.name == "admin"
+}
+
+# METADATA
+# schemas:
+#   - input: {"type": "object"}
+deny {
+	input.user
+}`,
+		},
+	}
+
+	tests := map[string]struct {
+		createLocation createLocationFunc
+		expectResult   []*ast.Location
+	}{
+		"Should return the property of the schema in the annotation": {
+			createLocation: createLocation(7, 15, policyPath),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    5,
+					Offset: strings.Index(schema, `"name"`),
+					Text:   []byte(`"name"`),
+					File:   filepath.Join(dir, "config.json"),
+				},
+			},
+		},
+		"Should return the property of the variable which is assigned from the ref in the annotation": {
+			createLocation: createLocation(9, 4, policyPath),
+			expectResult: []*ast.Location{
+				{
+					Row:    4,
+					Col:    5,
+					Offset: strings.Index(schema, `"name"`),
+					Text:   []byte(`"name"`),
+					File:   filepath.Join(dir, "config.json"),
+				},
+			},
+		},
+		"Should return the annotation which has the inline schema": {
+			createLocation: createLocation(16, 8, policyPath),
+			expectResult: []*ast.Location{
+				{
+					Row:    12,
+					Col:    1,
+					Offset: len("package src\n\n# METADATA\n# schemas:\n#   - input.config: schema.config\nallow {\n\tinput.config.name == \"admin\"\n\tc := input.config\n\tc.name == \"admin\"\n}\n\n"),
+					Text:   []byte("# METADATA"),
+					File:   policyPath,
+				},
+			},
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			project, err := source.NewProjectWithFiles(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the annotation takes precedence over the schema which is set by the option.
+			if err := project.SetSchemaFile(ast.InputRootRef, filepath.Join(dir, "input.json")); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := project.TypeDefinition(tt.createLocation(files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expectResult, got); diff != "" {
+				t.Errorf("TypeDefinition result diff (-expect, +got)\n%s", diff)
+			}
+		})
+	}
+}
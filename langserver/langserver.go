@@ -17,6 +17,7 @@ type handler struct {
 
 	diagnosticRequest chan lsp.DocumentURI
 	initializeParams  lsp.InitializeParams
+	options           initializationOptions
 
 	project *source.Project
 }
@@ -46,14 +47,38 @@ func (h *handler) handle(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2
 		return h.handleTextDocumentDidSave(ctx, conn, req)
 	case "textDocument/formatting":
 		return h.handleTextDocumentFormatting(ctx, conn, req)
+	case "textDocument/rangeFormatting":
+		return h.handleTextDocumentRangeFormatting(ctx, conn, req)
 	case "textDocument/definition":
 		return h.handleTextDocumentDefinition(ctx, conn, req)
+	case "textDocument/typeDefinition":
+		return h.handleTextDocumentTypeDefinition(ctx, conn, req)
 	case "textDocument/completion":
 		return h.handleTextDocumentCompletion(ctx, conn, req)
 	case "textDocument/hover":
 		return h.handleTextDocumentHover(ctx, conn, req)
 	case "textDocument/references":
 		return h.handleTextDocumentReferences(ctx, conn, req)
+	case "textDocument/documentHighlight":
+		return h.handleTextDocumentDocumentHighlight(ctx, conn, req)
+	case "textDocument/codeAction":
+		return h.handleTextDocumentCodeAction(ctx, conn, req)
+	case "workspace/symbol":
+		return h.handleWorkspaceSymbol(ctx, conn, req)
+	case "textDocument/signatureHelp":
+		return h.handleTextDocumentSignatureHelp(ctx, conn, req)
+	case "textDocument/rename":
+		return h.handleTextDocumentRename(ctx, conn, req)
+	case "textDocument/documentColor":
+		return h.handleTextDocumentDocumentColor(ctx, conn, req)
+	case "textDocument/colorPresentation":
+		return h.handleTextDocumentColorPresentation(ctx, conn, req)
+	case "textDocument/semanticTokens/full":
+		return h.handleTextDocumentSemanticTokensFull(ctx, conn, req)
+	case "textDocument/foldingRange":
+		return h.handleTextDocumentFoldingRange(ctx, conn, req)
+	case "textDocument/inlayHint":
+		return h.handleTextDocumentInlayHint(ctx, conn, req)
 	}
 	return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeMethodNotFound, Message: fmt.Sprintf("method not supported: %s", req.Method)}
 }
@@ -0,0 +1,119 @@
+package langserver
+
+import (
+	"strings"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+// The source package counts the columns in characters like the OPA parser does,
+// but LSP counts the characters in UTF-16 code units. They differ after the characters like emoji,
+// so the positions are converted when they are received from or sent to the client.
+
+// lineAt returns the text of the 0-based line without the line break.
+func lineAt(rawText string, line int) string {
+	for i := 0; i < line; i++ {
+		next := strings.Index(rawText, "\n")
+		if next < 0 {
+			return ""
+		}
+		rawText = rawText[next+1:]
+	}
+	if end := strings.Index(rawText, "\n"); end >= 0 {
+		rawText = rawText[:end]
+	}
+	return rawText
+}
+
+// utf16Len returns the length of the character in UTF-16 code units.
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// utf16Count returns the length of the text in UTF-16 code units.
+func utf16Count(text string) int {
+	n := 0
+	for _, r := range text {
+		n += utf16Len(r)
+	}
+	return n
+}
+
+// colToCharacter converts the 1-based column in characters into the 0-based character in UTF-16 code units.
+// The column beyond the line is kept as it is.
+func colToCharacter(line string, col int) int {
+	character := 0
+	for _, r := range line {
+		if col <= 1 {
+			return character
+		}
+		character += utf16Len(r)
+		col--
+	}
+	return character + col - 1
+}
+
+// characterToCol converts the 0-based character in UTF-16 code units into the 1-based column in characters.
+// The character beyond the line is kept as it is.
+func characterToCol(line string, character int) int {
+	col := 1
+	for _, r := range line {
+		if character <= 0 {
+			return col
+		}
+		character -= utf16Len(r)
+		col++
+	}
+	return col + character
+}
+
+// toSourcePosition converts the position of the client into the 1-based position in characters.
+func toSourcePosition(rawText string, position lsp.Position) source.Position {
+	return source.Position{
+		Row: position.Line + 1,
+		Col: characterToCol(lineAt(rawText, position.Line), position.Character),
+	}
+}
+
+// toLspPosition converts the 1-based position in characters into the position of the client.
+func toLspPosition(rawText string, position source.Position) lsp.Position {
+	return lsp.Position{
+		Line:      position.Row - 1,
+		Character: colToCharacter(lineAt(rawText, position.Row-1), position.Col),
+	}
+}
+
+// toSourceRange converts the range of the client into the 1-based range in characters.
+func toSourceRange(rawText string, rng lsp.Range) source.Range {
+	return source.Range{
+		Start: toSourcePosition(rawText, rng.Start),
+		End:   toSourcePosition(rawText, rng.End),
+	}
+}
+
+// positionToOffset converts the position of the client into the byte offset of the text.
+// The position beyond the line is clamped to the end of the line.
+func positionToOffset(rawText string, position lsp.Position) int {
+	offset := 0
+	for i := 0; i < position.Line; i++ {
+		next := strings.Index(rawText[offset:], "\n")
+		if next < 0 {
+			return len(rawText)
+		}
+		offset += next + 1
+	}
+
+	character := position.Character
+	for _, r := range rawText[offset:] {
+		if character <= 0 || r == '\n' {
+			break
+		}
+		character -= utf16Len(r)
+		offset += len(string(r))
+	}
+	return offset
+}
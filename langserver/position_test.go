@@ -0,0 +1,53 @@
+package langserver
+
+import (
+	"testing"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+)
+
+func TestPositionConversion(t *testing.T) {
+	rawText := "package src\n\nallow {\n\tmsg := \"👍こんにちは\"; m := msg\n}"
+
+	tests := map[string]struct {
+		lspPosition    lsp.Position
+		sourcePosition source.Position
+		offset         int
+	}{
+		"Should count a tab as one character": {
+			lspPosition:    lsp.Position{Line: 3, Character: 1},
+			sourcePosition: source.Position{Row: 4, Col: 2},
+			offset:         len("package src\n\nallow {\n\t"),
+		},
+		"Should count an emoji as two UTF-16 code units": {
+			lspPosition:    lsp.Position{Line: 3, Character: 11},
+			sourcePosition: source.Position{Row: 4, Col: 11},
+			offset:         len("package src\n\nallow {\n\tmsg := \"👍"),
+		},
+		"Should count the BMP characters as one UTF-16 code unit": {
+			lspPosition:    lsp.Position{Line: 3, Character: 24},
+			sourcePosition: source.Position{Row: 4, Col: 24},
+			offset:         len("package src\n\nallow {\n\tmsg := \"👍こんにちは\"; m := "),
+		},
+		"Should accept the end of the text": {
+			lspPosition:    lsp.Position{Line: 4, Character: 1},
+			sourcePosition: source.Position{Row: 5, Col: 2},
+			offset:         len(rawText),
+		},
+	}
+
+	for n, tt := range tests {
+		t.Run(n, func(t *testing.T) {
+			if got := toSourcePosition(rawText, tt.lspPosition); got != tt.sourcePosition {
+				t.Errorf("toSourcePosition should return %v, but got %v", tt.sourcePosition, got)
+			}
+			if got := toLspPosition(rawText, tt.sourcePosition); got != tt.lspPosition {
+				t.Errorf("toLspPosition should return %v, but got %v", tt.lspPosition, got)
+			}
+			if got := positionToOffset(rawText, tt.lspPosition); got != tt.offset {
+				t.Errorf("positionToOffset should return %d, but got %d", tt.offset, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentRename(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.RenameParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.rename(ctx, params.TextDocument.URI, params.Position, params.NewName)
+}
+
+func (h *handler) rename(ctx context.Context, uri lsp.DocumentURI, position lsp.Position, newName string) (*lsp.WorkspaceEdit, error) {
+	loc := h.toOPALocation(position, uri)
+	edits, err := h.project.Rename(loc, newName)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string][]lsp.TextEdit, len(edits))
+	for path, e := range edits {
+		rawText, _ := h.project.GetFile(path)
+		changes[string(uriToDocumentURI(path))] = toLspTextEdits(rawText, e)
+	}
+	return &lsp.WorkspaceEdit{Changes: changes}, nil
+}
@@ -0,0 +1,32 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentSemanticTokensFull(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.SemanticTokensParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.semanticTokens(ctx, params.TextDocument.URI)
+}
+
+func (h *handler) semanticTokens(ctx context.Context, uri lsp.DocumentURI) (*lsp.SemanticTokens, error) {
+	tokens, err := h.project.SemanticTokens(documentURIToURI(uri))
+	if err != nil {
+		h.logger.Printf("failed to get semantic tokens: %v", err)
+		return nil, nil
+	}
+
+	return &lsp.SemanticTokens{Data: tokens.Data}, nil
+}
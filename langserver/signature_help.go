@@ -0,0 +1,52 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleTextDocumentSignatureHelp(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.TextDocumentPositionParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.signatureHelp(ctx, params.TextDocument.URI, params.Position)
+}
+
+func (h *handler) signatureHelp(ctx context.Context, uri lsp.DocumentURI, position lsp.Position) (*lsp.SignatureHelp, error) {
+	loc := h.toOPALocation(position, uri)
+	help, err := h.project.SignatureHelp(loc)
+	if err != nil {
+		return nil, err
+	}
+	if help == nil {
+		return nil, nil
+	}
+
+	signatures := make([]lsp.SignatureInformation, len(help.Signatures))
+	for i, s := range help.Signatures {
+		params := make([]lsp.ParameterInformation, len(s.Parameters))
+		for j, p := range s.Parameters {
+			params[j] = lsp.ParameterInformation{Label: p}
+		}
+		signatures[i] = lsp.SignatureInformation{
+			Label:         s.Label,
+			Documentation: s.Documentation,
+			Parameters:    params,
+		}
+	}
+
+	return &lsp.SignatureHelp{
+		Signatures:      signatures,
+		ActiveSignature: help.ActiveSignature,
+		ActiveParameter: help.ActiveParameter,
+	}, nil
+}
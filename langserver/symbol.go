@@ -0,0 +1,65 @@
+package langserver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
+	"github.com/sourcegraph/jsonrpc2"
+)
+
+func (h *handler) handleWorkspaceSymbol(ctx context.Context, conn *jsonrpc2.Conn, req *jsonrpc2.Request) (result interface{}, err error) {
+	if req.Params == nil {
+		return nil, &jsonrpc2.Error{Code: jsonrpc2.CodeInvalidParams}
+	}
+
+	var params lsp.WorkspaceSymbolParams
+	if err := json.Unmarshal(*req.Params, &params); err != nil {
+		return nil, err
+	}
+
+	return h.workspaceSymbols(ctx, params.Query, params.Limit)
+}
+
+func (h *handler) workspaceSymbols(ctx context.Context, query string, limit int) ([]lsp.SymbolInformation, error) {
+	symbols, err := h.project.WorkspaceSymbols(query)
+	if err != nil {
+		h.logger.Printf("failed to get workspace symbols: %v", err)
+		return nil, nil
+	}
+
+	if limit > 0 && len(symbols) > limit {
+		symbols = symbols[:limit]
+	}
+
+	result := make([]lsp.SymbolInformation, 0, len(symbols))
+	for _, s := range symbols {
+		rawFile, err := h.project.GetRawText(s.Location.File)
+		if err != nil {
+			continue
+		}
+		location := toLspLocation(s.Location, rawFile)
+		location.URI = uriToDocumentURI(s.Location.File)
+		result = append(result, lsp.SymbolInformation{
+			Name:          s.Name,
+			Kind:          symbolKindToLspKind(s.Kind),
+			Location:      location,
+			ContainerName: s.ContainerName,
+		})
+	}
+	return result, nil
+}
+
+func symbolKindToLspKind(kind source.SymbolKind) lsp.SymbolKind {
+	switch kind {
+	case source.FunctionSymbol, source.BuiltinSymbol:
+		return lsp.SKFunction
+	case source.PackageSymbol:
+		return lsp.SKPackage
+	case source.ConstantSymbol:
+		return lsp.SKConstant
+	default:
+		return lsp.SKVariable
+	}
+}
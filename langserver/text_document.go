@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 
 	"github.com/kitagry/regols/langserver/internal/lsp"
+	"github.com/kitagry/regols/langserver/internal/source"
 	"github.com/sourcegraph/jsonrpc2"
 )
 
@@ -33,7 +34,26 @@ func (h *handler) handleTextDocumentDidChange(ctx context.Context, conn *jsonrpc
 		return nil, err
 	}
 
-	h.updateDocument(params.TextDocument.URI, params.ContentChanges[0].Text, params.TextDocument.Version)
+	path := documentURIToURI(params.TextDocument.URI)
+	// the range of each change is converted with the text which the previous changes are applied to.
+	text, _ := h.project.GetFile(path)
+	changes := make([]source.ContentChange, len(params.ContentChanges))
+	for i, c := range params.ContentChanges {
+		changes[i] = source.ContentChange{Text: c.Text}
+		if c.Range == nil {
+			text = c.Text
+			continue
+		}
+		rng := toSourceRange(text, *c.Range)
+		changes[i].Range = &rng
+		if start, end := positionToOffset(text, c.Range.Start), positionToOffset(text, c.Range.End); start <= end {
+			text = text[:start] + c.Text + text[end:]
+		}
+	}
+	if err := h.project.UpdateFileIncremental(path, changes, params.TextDocument.Version); err != nil {
+		return nil, err
+	}
+	h.diagnosticRequest <- params.TextDocument.URI
 
 	return nil, nil
 }